@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"concert-booking/internal/app/config"
+	kafkainfra "concert-booking/internal/infrastructure/kafka"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const usage = `usage: dlq <command> -topic T [flags]
+
+commands:
+  inspect [-all] [-limit N]  print pending dead letters of topic T (e.g. ticket.reserved.dlq)
+  replay [-limit N]          republish pending dead letters to their original topic
+  purge                      drop every pending dead letter
+
+Pending letters are the ones after the cursor of the -group consumer group
+(default <KAFKA_GROUP_ID>-dlq); replay and purge move it forward.
+`
+
+type letter struct {
+	Partition int               `json:"partition"`
+	Offset    int64             `json:"offset"`
+	Key       string            `json:"key"`
+	Headers   map[string]string `json:"headers"`
+	Value     json.RawMessage   `json:"value"`
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	cmd := os.Args[1]
+	cfg := config.Load()
+	flags := flag.NewFlagSet(cmd, flag.ExitOnError)
+	topic := flags.String("topic", "", "dead-letter topic")
+	group := flags.String("group", cfg.KafkaGroupID+"-dlq", "consumer group holding the dead-letter cursor")
+	all := flags.Bool("all", false, "inspect from the first retained offset instead of the cursor")
+	limit := flags.Int("limit", 0, "stop after N letters (0 = no limit)")
+	_ = flags.Parse(os.Args[2:])
+	if *topic == "" {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	security, err := kafkainfra.NewSecurity(kafkainfra.SecurityOptions{
+		SASLMechanism: cfg.KafkaSASLMechanism,
+		Username:      cfg.KafkaSASLUsername,
+		Password:      cfg.KafkaSASLPassword,
+		TLS:           cfg.KafkaTLS,
+		TLSCAFile:     cfg.KafkaTLSCAFile,
+		TLSSkipVerify: cfg.KafkaTLSSkipVerify,
+	})
+	if err != nil {
+		log.Fatalf("kafka security: %v", err)
+	}
+	letters := kafkainfra.NewDeadLetters(cfg.KafkaBrokers, security, *group)
+	ranges, err := letters.Ranges(ctx, *topic, *all && cmd == "inspect")
+	if err != nil {
+		log.Fatalf("read offsets of %s failed: %v", *topic, err)
+	}
+
+	switch cmd {
+	case "inspect":
+		enc := json.NewEncoder(os.Stdout)
+		n := 0
+		each(ctx, letters, *topic, ranges, *limit, func(msg kafka.Message) error {
+			n++
+			return enc.Encode(toLetter(msg))
+		}, nil)
+		log.Printf("%d dead letters", n)
+	case "replay":
+		writer := &kafka.Writer{Addr: kafka.TCP(cfg.KafkaBrokers...), Transport: security.Transport(), RequiredAcks: kafka.RequireAll}
+		defer writer.Close()
+		n := 0
+		each(ctx, letters, *topic, ranges, *limit, func(msg kafka.Message) error {
+			out, err := kafkainfra.Resubmission(msg)
+			if err != nil {
+				return fmt.Errorf("offset %d: %w", msg.Offset, err)
+			}
+			if err := writer.WriteMessages(ctx, out); err != nil {
+				return err
+			}
+			n++
+			return nil
+		}, func(partition int, next int64) error {
+			return letters.Commit(ctx, *topic, partition, next)
+		})
+		log.Printf("replayed %d dead letters", n)
+	case "purge":
+		n := int64(0)
+		for _, r := range ranges {
+			if r.Next >= r.End {
+				continue
+			}
+			if err := letters.Commit(ctx, *topic, r.Partition, r.End); err != nil {
+				log.Fatalf("purge partition %d failed: %v", r.Partition, err)
+			}
+			n += r.End - r.Next
+		}
+		log.Printf("purged %d dead letters", n)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+// each walks the pending letters partition by partition. When commit is set
+// the cursor is advanced past every letter fn accepted, so an interrupted
+// replay resumes where it stopped.
+func each(ctx context.Context, letters *kafkainfra.DeadLetters, topic string, ranges []kafkainfra.PartitionRange, limit int, fn func(kafka.Message) error, commit func(partition int, next int64) error) {
+	seen := 0
+	for _, r := range ranges {
+		if limit > 0 && seen >= limit {
+			return
+		}
+		if limit > 0 && r.End-r.Next > int64(limit-seen) {
+			r.End = r.Next + int64(limit-seen)
+		}
+		next := r.Next
+		err := letters.Read(ctx, topic, r, func(msg kafka.Message) error {
+			if err := fn(msg); err != nil {
+				return err
+			}
+			seen++
+			next = msg.Offset + 1
+			return nil
+		})
+		if commit != nil && next > r.Next {
+			if cerr := commit(r.Partition, next); cerr != nil {
+				log.Fatalf("commit partition %d failed: %v", r.Partition, cerr)
+			}
+		}
+		if err != nil {
+			log.Fatalf("partition %d: %v", r.Partition, err)
+		}
+	}
+}
+
+func toLetter(msg kafka.Message) letter {
+	l := letter{Partition: msg.Partition, Offset: msg.Offset, Key: string(msg.Key), Headers: map[string]string{}}
+	for _, h := range msg.Headers {
+		l.Headers[h.Key] = string(h.Value)
+	}
+	if json.Valid(msg.Value) {
+		l.Value = msg.Value
+	} else {
+		l.Value, _ = json.Marshal(string(msg.Value))
+	}
+	return l
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"concert-booking/internal/app/config"
+	"concert-booking/internal/infrastructure/postgres"
+	"concert-booking/migrations"
+)
+
+const usage = `usage: migrate <command> [flags]
+
+commands:
+  up                   apply all pending migrations
+  down [-steps N]      roll back the last N applied migrations (default 1)
+  status               list migrations and whether they are applied
+  to -version N        migrate up or down to exactly version N
+  baseline -version N  mark migrations up to N as applied without running them
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	cmd := os.Args[1]
+	flags := flag.NewFlagSet(cmd, flag.ExitOnError)
+	dsn := flags.String("dsn", "", "postgres DSN (defaults to POSTGRES_DSN)")
+	steps := flags.Int("steps", 1, "number of migrations to roll back")
+	version := flags.Int64("version", -1, "target migration version")
+	_ = flags.Parse(os.Args[2:])
+
+	cfg := config.Load()
+	if *dsn == "" {
+		*dsn = cfg.PostgresDSN
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	db, err := connectPostgresWithRetry(*dsn, 10, 2*time.Second)
+	if err != nil {
+		log.Fatalf("postgres connect failed: %v", err)
+	}
+	defer db.Close()
+
+	migrator, err := postgres.NewMigrator(db, migrations.FS)
+	if err != nil {
+		log.Fatalf("load migrations failed: %v", err)
+	}
+
+	switch cmd {
+	case "up":
+		n, err := migrator.Up(ctx)
+		if err != nil {
+			log.Fatalf("migrate up failed: %v", err)
+		}
+		log.Printf("applied %d migration(s)", n)
+	case "down":
+		n, err := migrator.Down(ctx, *steps)
+		if err != nil {
+			log.Fatalf("migrate down failed: %v", err)
+		}
+		log.Printf("rolled back %d migration(s)", n)
+	case "to":
+		if *version < 0 {
+			log.Fatal("to requires -version")
+		}
+		n, err := migrator.To(ctx, *version)
+		if err != nil {
+			log.Fatalf("migrate to %d failed: %v", *version, err)
+		}
+		log.Printf("ran %d migration(s), schema at version %d", n, *version)
+	case "baseline":
+		if *version < 0 {
+			log.Fatal("baseline requires -version")
+		}
+		n, err := migrator.Baseline(ctx, *version)
+		if err != nil {
+			log.Fatalf("baseline failed: %v", err)
+		}
+		log.Printf("recorded %d migration(s) as applied", n)
+	case "status":
+		items, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatalf("status failed: %v", err)
+		}
+		for _, item := range items {
+			state := "pending"
+			if item.Applied {
+				state = "applied " + item.AppliedAt.UTC().Format(time.RFC3339)
+			}
+			if item.Dirty {
+				state += " (checksum mismatch)"
+			}
+			fmt.Printf("%03d_%s\t%s\n", item.Version, item.Name, state)
+		}
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+func connectPostgresWithRetry(dsn string, attempts int, delay time.Duration) (*sql.DB, error) {
+	var lastErr error
+	for i := 0; i < attempts; i++ {
+		db, err := postgres.NewDB(dsn)
+		if err == nil {
+			return db, nil
+		}
+		lastErr = err
+		time.Sleep(delay)
+	}
+	return nil, lastErr
+}
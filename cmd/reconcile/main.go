@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"concert-booking/internal/app/config"
+	"concert-booking/internal/infrastructure/postgres"
+	redisinfra "concert-booking/internal/infrastructure/redis"
+	"concert-booking/internal/usecase"
+)
+
+func main() {
+	repair := flag.Bool("repair", false, "overwrite drifted Redis stock counters with the expected value")
+	failOnDrift := flag.Bool("fail-on-drift", false, "exit with status 1 when unrepaired drift is found")
+	flag.Parse()
+
+	cfg := config.Load()
+	switch cfg.StockBackend {
+	case "redis":
+	case "postgres":
+		// Counters and holds are written in the same transaction, so they
+		// cannot drift apart.
+		log.Fatal("STOCK_BACKEND=postgres keeps stock counters in Postgres: there is no Redis stock to reconcile")
+	default:
+		log.Fatalf("unknown STOCK_BACKEND %q", cfg.StockBackend)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	db, err := connectPostgresWithRetry(cfg.PostgresDSN, 10, 2*time.Second)
+	if err != nil {
+		log.Fatalf("postgres connect failed: %v", err)
+	}
+	defer db.Close()
+
+	stock := redisinfra.NewStockService(redisinfra.NewClient(redisinfra.ClientOptions{Addrs: cfg.RedisAddrs, Password: cfg.RedisPassword, MasterName: cfg.RedisMaster, Cluster: cfg.RedisCluster}))
+	defer stock.Client().Close()
+	if err := stock.Ping(ctx); err != nil {
+		log.Fatalf("redis connect failed: %v", err)
+	}
+
+	timeouts := postgres.Timeouts{Read: cfg.DBReadTimeout, Write: cfg.DBWriteTimeout}
+	host, _ := os.Hostname()
+	u := usecase.NewReconcileUsecase(postgres.NewTicketCategoryRepository(db, timeouts), postgres.NewReservationRepository(db, timeouts), stock, stock, time.Now, "reconcile-cli-"+host)
+
+	report, err := u.Run(ctx, *repair)
+	if err != nil {
+		log.Fatalf("reconcile failed: %v", err)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(report)
+
+	if *failOnDrift {
+		for _, d := range report.Discrepancies {
+			if !d.Repaired {
+				os.Exit(1)
+			}
+		}
+	}
+}
+
+func connectPostgresWithRetry(dsn string, attempts int, delay time.Duration) (*sql.DB, error) {
+	var lastErr error
+	for i := 0; i < attempts; i++ {
+		db, err := postgres.NewDB(dsn)
+		if err == nil {
+			return db, nil
+		}
+		lastErr = err
+		time.Sleep(delay)
+	}
+	return nil, lastErr
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"regexp"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"concert-booking/internal/app/config"
+	kafkainfra "concert-booking/internal/infrastructure/kafka"
+	"concert-booking/internal/infrastructure/memory"
+	"concert-booking/internal/infrastructure/postgres"
+	"concert-booking/internal/observability/metrics"
+	"concert-booking/internal/usecase"
+	"concert-booking/migrations"
+
+	"github.com/segmentio/kafka-go"
+)
+
+var schemaName = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+func main() {
+	topicsFlag := flag.String("topics", strings.Join(usecase.ReplayOrder, ","), "topics to replay; always applied in reserved, confirmed, expired order")
+	from := flag.Int64("from-offset", -1, "offset to start every partition at (-1 = first retained)")
+	since := flag.String("since", "", "start at the first message at or after this RFC3339 time (overrides -from-offset)")
+	dryRun := flag.Bool("dry-run", false, "replay into memory and print the differences with the current tables instead of writing")
+	schema := flag.String("schema", "", "rebuild into this Postgres schema (created and migrated) instead of the live tables")
+	metricsAddr := flag.String("metrics-addr", ":9092", "serve progress metrics on this address (empty = off)")
+	flag.Parse()
+
+	topics := make([]string, 0, len(usecase.ReplayOrder))
+	for _, t := range strings.Split(*topicsFlag, ",") {
+		if t = strings.TrimSpace(t); t == "" {
+			continue
+		}
+		if !slices.Contains(usecase.ReplayOrder, t) {
+			log.Fatalf("cannot replay topic %s", t)
+		}
+		topics = append(topics, t)
+	}
+	slices.SortFunc(topics, func(a, b string) int {
+		return slices.Index(usecase.ReplayOrder, a) - slices.Index(usecase.ReplayOrder, b)
+	})
+	start := kafkainfra.Start{Offset: *from}
+	if *since != "" {
+		t, err := time.Parse(time.RFC3339, *since)
+		if err != nil {
+			log.Fatalf("invalid -since: %v", err)
+		}
+		start.Time = t
+	}
+	if *schema != "" && !schemaName.MatchString(*schema) {
+		log.Fatalf("invalid -schema %q", *schema)
+	}
+
+	cfg := config.Load()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	db, err := postgres.NewDB(cfg.PostgresDSN)
+	if err != nil {
+		log.Fatalf("postgres connect failed: %v", err)
+	}
+	defer db.Close()
+	if *schema != "" {
+		if db, err = openSchema(ctx, db, cfg.PostgresDSN, *schema); err != nil {
+			log.Fatalf("prepare schema %s failed: %v", *schema, err)
+		}
+		defer db.Close()
+	}
+	timeouts := postgres.Timeouts{Read: cfg.DBReadTimeout, Write: cfg.DBWriteTimeout}
+	current := usecase.ProjectionStore{Reservations: postgres.NewReservationRepository(db, timeouts), Bookings: postgres.NewBookingRepository(db, timeouts)}
+	target := current
+	if *dryRun {
+		target = usecase.ProjectionStore{Reservations: memory.NewReservationRepository(), Bookings: memory.NewBookingRepository()}
+	}
+	replay := usecase.NewReplay(usecase.NewProjector(target.Reservations, target.Bookings))
+
+	if *metricsAddr != "" {
+		srv := &http.Server{Addr: *metricsAddr, Handler: http.HandlerFunc(metrics.Handler)}
+		go func() {
+			if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("replay metrics server error: %v", err)
+			}
+		}()
+		defer srv.Close()
+	}
+
+	security, err := kafkainfra.NewSecurity(kafkainfra.SecurityOptions{
+		SASLMechanism: cfg.KafkaSASLMechanism,
+		Username:      cfg.KafkaSASLUsername,
+		Password:      cfg.KafkaSASLPassword,
+		TLS:           cfg.KafkaTLS,
+		TLSCAFile:     cfg.KafkaTLSCAFile,
+		TLSSkipVerify: cfg.KafkaTLSSkipVerify,
+	})
+	if err != nil {
+		log.Fatalf("kafka security: %v", err)
+	}
+	history := kafkainfra.NewHistory(cfg.KafkaBrokers, security)
+	totals := map[string]int{}
+	for _, topic := range topics {
+		ranges, err := history.Ranges(ctx, topic, start)
+		if err != nil {
+			log.Fatalf("read offsets of %s failed: %v", topic, err)
+		}
+		var remaining atomic.Int64
+		for _, r := range ranges {
+			remaining.Add(r.End - r.Next)
+		}
+		log.Printf("replaying %d messages of %s", remaining.Load(), topic)
+		metrics.SetReplayRemaining(topic, remaining.Load())
+		done := progress(topic, &remaining)
+		for _, r := range ranges {
+			err := history.Read(ctx, topic, r, func(msg kafka.Message) error {
+				result, err := replay.Apply(ctx, topic, msg.Value)
+				if err != nil {
+					return fmt.Errorf("offset %d: %w", msg.Offset, err)
+				}
+				if result != usecase.ReplayApplied {
+					log.Printf("%s/%d@%d %s", topic, msg.Partition, msg.Offset, result)
+				}
+				totals[topic+" "+result]++
+				metrics.IncReplayMessage(topic, result)
+				metrics.SetReplayRemaining(topic, remaining.Add(-1))
+				return nil
+			})
+			if err != nil {
+				close(done)
+				log.Fatalf("replay %s partition %d failed: %v", topic, r.Partition, err)
+			}
+		}
+		close(done)
+	}
+	log.Printf("replay finished: %v", totals)
+
+	if *dryRun {
+		diffs, err := usecase.DiffProjections(ctx, replay.Touched(), target, current)
+		if err != nil {
+			log.Fatalf("diff failed: %v", err)
+		}
+		enc := json.NewEncoder(os.Stdout)
+		for _, d := range diffs {
+			_ = enc.Encode(d)
+		}
+		log.Printf("%d reservations replayed, %d differences", len(replay.Touched()), len(diffs))
+	}
+}
+
+// progress logs the remaining count of topic every few seconds until done
+// is closed.
+func progress(topic string, remaining *atomic.Int64) chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		t := time.NewTicker(5 * time.Second)
+		defer t.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-t.C:
+				log.Printf("%s: %d messages left", topic, remaining.Load())
+			}
+		}
+	}()
+	return done
+}
+
+// openSchema creates and migrates schema, then returns a connection whose
+// search_path points at it, so the repositories write there unchanged.
+func openSchema(ctx context.Context, db *sql.DB, dsn, schema string) (*sql.DB, error) {
+	if _, err := db.ExecContext(ctx, `CREATE SCHEMA IF NOT EXISTS `+schema); err != nil {
+		return nil, err
+	}
+	scoped, err := postgres.NewDB(withSearchPath(dsn, schema))
+	if err != nil {
+		return nil, err
+	}
+	migrator, err := postgres.NewMigrator(scoped, migrations.FS)
+	if err != nil {
+		scoped.Close()
+		return nil, err
+	}
+	n, err := migrator.Up(ctx)
+	if err != nil {
+		scoped.Close()
+		return nil, err
+	}
+	log.Printf("schema %s ready (%d migration(s) applied)", schema, n)
+	return scoped, nil
+}
+
+func withSearchPath(dsn, schema string) string {
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" {
+		q := u.Query()
+		q.Set("search_path", schema)
+		u.RawQuery = q.Encode()
+		return u.String()
+	}
+	return dsn + " search_path=" + schema
+}
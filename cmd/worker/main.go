@@ -2,8 +2,9 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"database/sql"
-	"encoding/json"
+	"encoding/hex"
 	"errors"
 	"log"
 	"net/http"
@@ -12,10 +13,15 @@ import (
 	"time"
 
 	"concert-booking/internal/app/config"
-	"concert-booking/internal/domain/entity"
 	kafkainfra "concert-booking/internal/infrastructure/kafka"
+	"concert-booking/internal/infrastructure/notify"
 	"concert-booking/internal/infrastructure/postgres"
+	redisinfra "concert-booking/internal/infrastructure/redis"
+	"concert-booking/internal/infrastructure/webhook"
 	"concert-booking/internal/observability/metrics"
+	"concert-booking/internal/usecase"
+
+	"github.com/segmentio/kafka-go"
 )
 
 func main() {
@@ -25,19 +31,89 @@ func main() {
 		log.Fatalf("postgres connect failed: %v", err)
 	}
 	defer db.Close()
+	security, err := kafkainfra.NewSecurity(kafkainfra.SecurityOptions{
+		SASLMechanism: cfg.KafkaSASLMechanism,
+		Username:      cfg.KafkaSASLUsername,
+		Password:      cfg.KafkaSASLPassword,
+		TLS:           cfg.KafkaTLS,
+		TLSCAFile:     cfg.KafkaTLSCAFile,
+		TLSSkipVerify: cfg.KafkaTLSSkipVerify,
+	})
+	if err != nil {
+		log.Fatalf("kafka security: %v", err)
+	}
 	if err := waitForDependency(10, 2*time.Second, func() error {
-		return kafkainfra.EnsureTopics(context.Background(), cfg.KafkaBrokers, []string{"ticket.reserved", "ticket.confirmed", "ticket.expired"}, 3, 1)
+		topics := []string{"ticket.reserved", "ticket.confirmed", "ticket.expired"}
+		dlqs := make([]string, 0, len(topics))
+		for _, topic := range topics {
+			dlqs = append(dlqs, kafkainfra.DeadLetterTopic(topic))
+		}
+		topics = append(topics, dlqs...)
+		return kafkainfra.EnsureTopics(context.Background(), cfg.KafkaBrokers, security, topics, 3, 1)
 	}); err != nil {
 		log.Fatalf("kafka topic ensure failed: %v", err)
 	}
 
-	reservationRepo := postgres.NewReservationRepository(db)
-	consumer := kafkainfra.NewConsumer(cfg.KafkaBrokers, cfg.KafkaGroupID, "ticket.reserved")
-	defer consumer.Close()
+	timeouts := postgres.Timeouts{Read: cfg.DBReadTimeout, Write: cfg.DBWriteTimeout}
+	projector := usecase.NewProjector(postgres.NewReservationRepository(db, timeouts), postgres.NewBookingRepository(db, timeouts))
+	router := newRouter(cfg, security, cfg.KafkaGroupID, projector.Handlers())
+	defer router.Close()
 
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
+	// Webhooks and notifications consume in their own groups so a slow
+	// partner endpoint or mail relay never holds back the projections, and
+	// vice versa.
+	var sideRouters []*kafkainfra.Router
+	if cfg.WebhooksEnabled {
+		webhooks := usecase.NewWebhookUsecase(postgres.NewWebhookRepository(db, timeouts), postgres.NewWebhookDeliveryRepository(db, timeouts), webhook.NewSender(cfg.WebhookTimeout, time.Now), time.Now, newID, usecase.WebhookOptions{
+			MaxAttempts:  cfg.WebhookAttempts,
+			Backoff:      cfg.WebhookBackoff,
+			MaxBackoff:   cfg.WebhookMaxDelay,
+			DisableAfter: cfg.WebhookDisable,
+			Lease:        2 * cfg.WebhookTimeout,
+		})
+		sideRouters = append(sideRouters, newRouter(cfg, security, cfg.KafkaGroupID+"-webhooks", webhooks.Handlers()))
+		go webhooks.StartDispatcher(ctx, cfg.WebhookInterval, cfg.WebhookBatch)
+	}
+	if cfg.NotifyEnabled {
+		channels, err := notify.Channels(notify.Options{
+			EmailSink:    cfg.NotifyEmailSink,
+			SMSSink:      cfg.NotifySMSSink,
+			File:         cfg.NotifyFile,
+			SMTPAddr:     cfg.SMTPAddr,
+			SMTPUsername: cfg.SMTPUsername,
+			SMTPPassword: cfg.SMTPPassword,
+			From:         cfg.SMTPFrom,
+		}, time.Now)
+		if err != nil {
+			log.Fatalf("notification channels: %v", err)
+		}
+		templates, err := notify.LoadTemplates(cfg.NotifyTemplates, cfg.NotifyLocale)
+		if err != nil {
+			log.Fatalf("load notification templates failed: %v", err)
+		}
+		notifications := usecase.NewNotificationUsecase(postgres.NewNotificationPreferenceRepository(db, timeouts), postgres.NewNotificationRepository(db, timeouts), postgres.NewReservationRepository(db, timeouts), postgres.NewEventRepository(db, timeouts), templates, channels, time.Now, newID)
+		sideRouters = append(sideRouters, newRouter(cfg, security, cfg.KafkaGroupID+"-notifications", notifications.Handlers()))
+	}
+	for _, r := range sideRouters {
+		defer r.Close()
+		go r.Run(ctx)
+	}
+
+	// With the Postgres stock backend movements are written straight to
+	// stock_ledger, so there is no Redis buffer to drain.
+	if cfg.StockBackend == "redis" {
+		stock := redisinfra.NewStockService(redisinfra.NewClient(redisinfra.ClientOptions{Addrs: cfg.RedisAddrs, Password: cfg.RedisPassword, MasterName: cfg.RedisMaster, Cluster: cfg.RedisCluster}))
+		defer stock.Client().Close()
+		if err := waitForDependency(10, 2*time.Second, func() error { return stock.Ping(ctx) }); err != nil {
+			log.Fatalf("redis connect failed: %v", err)
+		}
+		ledger := usecase.NewLedgerUsecase(postgres.NewTicketCategoryRepository(db, timeouts), postgres.NewStockLedgerRepository(db, timeouts), stock)
+		go ledger.StartDrain(ctx, stock, time.Second, 500)
+	}
+
 	metricsStop := make(chan struct{})
 	go func() {
 		t := time.NewTicker(5 * time.Second)
@@ -47,8 +123,11 @@ func main() {
 			case <-metricsStop:
 				return
 			case <-t.C:
-				stats := consumer.Stats()
-				metrics.SetKafkaLag(stats.Lag)
+				lag := router.Lag()
+				for _, r := range sideRouters {
+					lag += r.Lag()
+				}
+				metrics.SetKafkaLag(lag)
 			}
 		}
 	}()
@@ -60,29 +139,40 @@ func main() {
 		}
 	}()
 
-	for {
-		msg, err := consumer.ReadMessage(ctx)
-		if err != nil {
-			if ctx.Err() != nil {
-				close(metricsStop)
-				_ = httpSrv.Shutdown(context.Background())
-				log.Println("worker shutting down")
-				return
-			}
-			log.Printf("consume error: %v", err)
-			continue
-		}
-		var res entity.Reservation
-		if err := json.Unmarshal(msg.Value, &res); err != nil {
-			log.Printf("invalid message: %v", err)
-			continue
-		}
-		if err := reservationRepo.Upsert(res); err != nil {
-			log.Printf("upsert reservation failed: %v", err)
-			continue
-		}
-		log.Printf("reservation persisted: %s", res.ID)
+	log.Printf("worker consuming %v", router.Topics())
+	for _, r := range sideRouters {
+		log.Printf("worker consuming %v", r.Topics())
+	}
+	router.Run(ctx)
+	close(metricsStop)
+	_ = httpSrv.Shutdown(context.Background())
+	log.Println("worker shutting down")
+}
+
+func newRouter(cfg config.Config, security *kafkainfra.Security, groupID string, handlers map[string]usecase.EventHandler) *kafkainfra.Router {
+	router := kafkainfra.NewRouter(kafkainfra.RouterOptions{
+		Brokers:     cfg.KafkaBrokers,
+		Security:    security,
+		GroupID:     groupID,
+		MaxAttempts: cfg.WorkerAttempts,
+		Backoff:     cfg.WorkerBackoff,
+		MaxBackoff:  cfg.WorkerMaxDelay,
+		Permanent:   func(err error) bool { return errors.Is(err, usecase.ErrInvalidPayload) },
+	})
+	for topic, h := range handlers {
+		router.Handle(topic, func(ctx context.Context, msg kafka.Message) error {
+			return h(ctx, msg.Value)
+		})
+	}
+	return router
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().Format("20060102150405.000000000")
 	}
+	return hex.EncodeToString(b)
 }
 
 func connectPostgresWithRetry(dsn string, attempts int, delay time.Duration) (*sql.DB, error) {
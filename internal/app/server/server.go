@@ -11,23 +11,52 @@ import (
 	"time"
 
 	"concert-booking/internal/app/config"
+	"concert-booking/internal/domain/repository"
+	"concert-booking/internal/domain/service"
+	"concert-booking/internal/domain/ticketevent"
+	"concert-booking/internal/domain/ticketevent/ticketeventpb"
 	kafkainfra "concert-booking/internal/infrastructure/kafka"
 	"concert-booking/internal/infrastructure/memory"
+	"concert-booking/internal/infrastructure/notify"
 	"concert-booking/internal/infrastructure/postgres"
 	redisinfra "concert-booking/internal/infrastructure/redis"
+	"concert-booking/internal/infrastructure/schemaregistry"
+	"concert-booking/internal/infrastructure/webhook"
 	"concert-booking/internal/interface/http/handler"
 	"concert-booking/internal/interface/http/middleware"
 	"concert-booking/internal/interface/http/router"
 	"concert-booking/internal/observability/metrics"
 	"concert-booking/internal/usecase"
+	"concert-booking/migrations"
+
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/segmentio/kafka-go"
 )
 
 func NewHTTPServer(cfg config.Config) *http.Server {
 	var (
 		eventUsecase       *usecase.EventUsecase
 		reservationUsecase *usecase.ReservationUsecase
+		outboxRelay        *usecase.OutboxRelay
+		ledgerSource       service.LedgerSource
+		expiryWatcher      service.ExpiryWatcher
+		soldOutCache       *redisinfra.StockService
+		reconcileUsecase   *usecase.ReconcileUsecase
+		rehydrateUsecase   *usecase.RehydrateUsecase
+		ledgerUsecase      *usecase.LedgerUsecase
+		persistence        *memory.Persistence
+		webhookUsecase     *usecase.WebhookUsecase
+		notifyUsecase      *usecase.NotificationUsecase
+		eventBus           *memory.EventBus
+		requestUsecase     *usecase.ReservationRequestUsecase
+		allocator          *kafkainfra.Router
 		cleanup            []func()
 	)
+	switch cfg.ReserveMode {
+	case "sync", "async":
+	default:
+		log.Fatalf("unknown RESERVE_MODE %q", cfg.ReserveMode)
+	}
 
 	if cfg.AppMode == "production" {
 		db, err := connectPostgresWithRetry(cfg.PostgresDSN, 10, 2*time.Second)
@@ -36,29 +65,154 @@ func NewHTTPServer(cfg config.Config) *http.Server {
 		}
 		cleanup = append(cleanup, func() { _ = db.Close() })
 
-		stock := redisinfra.NewStockService(cfg.RedisAddr, cfg.RedisPassword)
-		if err := waitForDependency(10, 2*time.Second, func() error { return stock.Ping(context.Background()) }); err != nil {
-			log.Fatalf("redis connect failed: %v", err)
+		if cfg.MigrateOnStart {
+			migrator, err := postgres.NewMigrator(db, migrations.FS)
+			if err != nil {
+				log.Fatalf("load migrations failed: %v", err)
+			}
+			n, err := migrator.Up(context.Background())
+			if err != nil {
+				log.Fatalf("migrate up failed: %v", err)
+			}
+			log.Printf("applied %d migration(s)", n)
+		}
+
+		kafkaSecurity, err := kafkainfra.NewSecurity(kafkainfra.SecurityOptions{
+			SASLMechanism: cfg.KafkaSASLMechanism,
+			Username:      cfg.KafkaSASLUsername,
+			Password:      cfg.KafkaSASLPassword,
+			TLS:           cfg.KafkaTLS,
+			TLSCAFile:     cfg.KafkaTLSCAFile,
+			TLSSkipVerify: cfg.KafkaTLSSkipVerify,
+		})
+		if err != nil {
+			log.Fatalf("kafka security: %v", err)
+		}
+		topics := []string{"ticket.reserved", "ticket.confirmed", "ticket.expired"}
+		if cfg.ReserveMode == "async" {
+			topics = append(topics, usecase.TopicReservationRequests, kafkainfra.DeadLetterTopic(usecase.TopicReservationRequests))
 		}
 		if err := waitForDependency(10, 2*time.Second, func() error {
-			return kafkainfra.EnsureTopics(context.Background(), cfg.KafkaBrokers, []string{"ticket.reserved", "ticket.confirmed", "ticket.expired"}, 3, 1)
+			return kafkainfra.EnsureTopics(context.Background(), cfg.KafkaBrokers, kafkaSecurity, topics, 3, 1)
 		}); err != nil {
 			log.Fatalf("kafka topic ensure failed: %v", err)
 		}
 
-		producer := kafkainfra.NewProducer(cfg.KafkaBrokers)
-		cleanup = append(cleanup, func() { _ = producer.Close() })
+		timeouts := postgres.Timeouts{Read: cfg.DBReadTimeout, Write: cfg.DBWriteTimeout}
+		eventRepo := postgres.NewEventRepository(db, timeouts)
+		categoryRepo := postgres.NewTicketCategoryRepository(db, timeouts)
+		reservationRepo := postgres.NewReservationRepository(db, timeouts)
+		bookingRepo := postgres.NewBookingRepository(db, timeouts)
+		ledgerRepo := postgres.NewStockLedgerRepository(db, timeouts)
+		if cfg.WebhooksEnabled {
+			// Only the admin API lives here; the worker queues and sends.
+			webhookUsecase = newWebhookUsecase(cfg, postgres.NewWebhookRepository(db, timeouts), postgres.NewWebhookDeliveryRepository(db, timeouts))
+		}
+		if cfg.NotifyEnabled {
+			// Preferences and history only; the worker sends.
+			notifyUsecase = newNotificationUsecase(cfg, postgres.NewNotificationPreferenceRepository(db, timeouts), postgres.NewNotificationRepository(db, timeouts), reservationRepo, eventRepo, nil)
+		}
 
-		eventRepo := postgres.NewEventRepository(db)
-		categoryRepo := postgres.NewTicketCategoryRepository(db)
-		reservationRepo := postgres.NewReservationRepository(db)
-		bookingRepo := postgres.NewBookingRepository(db)
+		var (
+			stock       service.StockService
+			outbox      service.OutboxStore
+			redisClient goredis.UniversalClient
+		)
+		switch cfg.StockBackend {
+		case "postgres":
+			// Counters, holds, ledger and outbox share one database, so there is
+			// nothing to rehydrate or reconcile and no ledger buffer to drain.
+			pgStock := postgres.NewStockService(db, timeouts)
+			stock, outbox = pgStock, pgStock
+		case "redis":
+			redisStock := redisinfra.NewStockService(redisinfra.NewClient(redisinfra.ClientOptions{Addrs: cfg.RedisAddrs, Password: cfg.RedisPassword, MasterName: cfg.RedisMaster, Cluster: cfg.RedisCluster}))
+			if err := waitForDependency(10, 2*time.Second, func() error { return redisStock.Ping(context.Background()) }); err != nil {
+				log.Fatalf("redis connect failed: %v", err)
+			}
+			redisStock.EnableSharding(cfg.StockShards, cfg.StockShardMin)
+			stock, outbox = redisStock, redisStock
+			if cfg.ReserveWindow > 0 {
+				stock = redisinfra.NewReserveBatcher(redisStock, cfg.ReserveBatch, cfg.ReserveWindow)
+			}
+			redisClient = redisStock.Client()
+			if cfg.ExpiryNotify {
+				expiryWatcher = redisStock
+			}
+			if cfg.SoldOutCache {
+				soldOutCache = redisStock
+			}
+			reconcileUsecase = usecase.NewReconcileUsecase(categoryRepo, reservationRepo, redisStock, redisStock, time.Now, newID())
+			rehydrateUsecase = usecase.NewRehydrateUsecase(categoryRepo, reservationRepo, redisStock, redisStock, time.Now)
+		default:
+			log.Fatalf("unknown STOCK_BACKEND %q", cfg.StockBackend)
+		}
+
+		producerOpts := kafkainfra.ProducerOptions{
+			Brokers:      cfg.KafkaBrokers,
+			Security:     kafkaSecurity,
+			Acks:         cfg.ProducerAcks,
+			Async:        cfg.ProducerAsync,
+			Compression:  cfg.ProducerCompression,
+			BatchSize:    cfg.ProducerBatchSize,
+			BatchBytes:   cfg.ProducerBatchBytes,
+			BatchTimeout: cfg.ProducerLinger,
+			MaxAttempts:  cfg.ProducerAttempts,
+			WriteTimeout: cfg.ProducerTimeout,
+			// Async writes are not in the outbox; put the ones still failing
+			// at shutdown there so the relay publishes them.
+			OnError: kafkainfra.OutboxFallback(outbox),
+		}
+		producer, err := kafkainfra.NewProducer(producerOpts)
+		if err != nil {
+			log.Fatalf("kafka producer: %v", err)
+		}
+		// The relay acks an outbox entry once Publish returns, so it always
+		// waits for the broker; otherwise a broker outage would move the
+		// outbox into the writer's memory. KAFKA_PRODUCER_ASYNC only applies
+		// to the reservation request publishes.
+		relayOpts := producerOpts
+		relayOpts.Async, relayOpts.OnError = false, nil
+		relayProducer, err := kafkainfra.NewProducer(relayOpts)
+		if err != nil {
+			log.Fatalf("kafka producer: %v", err)
+		}
+		// Close flushes pending async writes, whose fallback still needs the
+		// outbox store, so it runs before anything else is torn down.
+		cleanup = append([]func(){func() { _ = producer.Close(); _ = relayProducer.Close() }}, cleanup...)
 
 		eventUsecase = usecase.NewEventUsecase(eventRepo, categoryRepo, stock, time.Now, newID)
-		reservationUsecase = usecase.NewReservationUsecase(categoryRepo, reservationRepo, bookingRepo, stock, producer, time.Now, newID, cfg.ReservationTTL, cfg.QueueThreshold, cfg.WorkerPoolSize, false)
+		reservationUsecase = usecase.NewReservationUsecase(categoryRepo, reservationRepo, bookingRepo, stock, time.Now, newID, cfg.ReservationTTL, cfg.QueueThreshold, cfg.WorkerPoolSize, false)
+		reservationUsecase.SetWriteTimeout(cfg.DBWriteTimeout)
+		outboxRelay = usecase.NewOutboxRelay(outbox, relayProducer, newID(), cfg.OutboxBatch, 5, 100*time.Millisecond)
+		outboxRelay.SetParkAfter(cfg.OutboxParkAfter)
+		ledgerUsecase = usecase.NewLedgerUsecase(categoryRepo, ledgerRepo, stock)
+		if rehydrateUsecase != nil {
+			reservationUsecase.SetReadinessGate(rehydrateUsecase)
+		}
+		if soldOutCache != nil {
+			reservationUsecase.SetSoldOutCache(soldOutCache)
+		}
+		if cfg.ReserveMode == "async" {
+			requestUsecase = usecase.NewReservationRequestUsecase(reservationUsecase, postgres.NewReservationRequestRepository(db, timeouts), producer, time.Now, newID)
+			// The consumer group gives each partition, and so each event, a
+			// single allocator across all API replicas.
+			allocator = kafkainfra.NewRouter(kafkainfra.RouterOptions{
+				Brokers:     cfg.KafkaBrokers,
+				Security:    kafkaSecurity,
+				GroupID:     cfg.KafkaGroupID + "-allocator",
+				MaxAttempts: cfg.WorkerAttempts,
+				Backoff:     cfg.WorkerBackoff,
+				MaxBackoff:  cfg.WorkerMaxDelay,
+				Permanent:   func(err error) bool { return errors.Is(err, usecase.ErrInvalidPayload) },
+			})
+			for topic, h := range requestUsecase.Handlers() {
+				allocator.Handle(topic, func(ctx context.Context, msg kafka.Message) error { return h(ctx, msg.Value) })
+			}
+			cleanup = append(cleanup, func() { _ = allocator.Close() })
+		}
 
 		collectorStop := make(chan struct{})
-		go metrics.StartInfraCollectors(db, stock.Client(), 5*time.Second, collectorStop)
+		go metrics.StartInfraCollectors(db, redisClient, 5*time.Second, collectorStop)
 		cleanup = append(cleanup, func() { close(collectorStop) })
 	} else {
 		eventRepo := memory.NewEventRepository()
@@ -66,18 +220,158 @@ func NewHTTPServer(cfg config.Config) *http.Server {
 		reservationRepo := memory.NewReservationRepository()
 		bookingRepo := memory.NewBookingRepository()
 		stock := memory.NewStockService()
-		producer := memory.NewEventProducer()
+		ledgerRepo := memory.NewStockLedgerRepository()
+		// Memory mode has no Kafka and no worker: events go over an in-process
+		// bus to the same handlers the worker would run.
+		bus := memory.NewEventBus(memory.BusOptions{
+			Partitions:  cfg.BusPartitions,
+			Buffer:      cfg.BusBuffer,
+			MaxAttempts: cfg.WorkerAttempts,
+			Backoff:     cfg.WorkerBackoff,
+			MaxBackoff:  cfg.WorkerMaxDelay,
+			Permanent:   func(err error) bool { return errors.Is(err, usecase.ErrInvalidPayload) },
+		}, time.Now)
+		eventBus = bus
+		webhookRepo := memory.NewWebhookRepository()
+		deliveryRepo := memory.NewWebhookDeliveryRepository()
+		notifyPrefRepo := memory.NewNotificationPreferenceRepository()
+		notifyRepo := memory.NewNotificationRepository()
+		requestRepo := memory.NewReservationRequestRepository()
+
+		if cfg.DataDir != "" {
+			p, err := memory.OpenPersistence(cfg.DataDir)
+			if err != nil {
+				log.Fatalf("open data dir failed: %v", err)
+			}
+			stores := []struct {
+				name  string
+				store memory.Durable
+			}{
+				{"events", eventRepo},
+				{"categories", categoryRepo},
+				{"reservations", reservationRepo},
+				{"bookings", bookingRepo},
+				{"stock", stock},
+				{"ledger", ledgerRepo},
+				{"webhooks", webhookRepo},
+				{"webhook_deliveries", deliveryRepo},
+				{"notification_preferences", notifyPrefRepo},
+				{"notifications", notifyRepo},
+				{"reservation_requests", requestRepo},
+			}
+			for _, st := range stores {
+				if err := p.Attach(st.name, st.store); err != nil {
+					log.Fatalf("replay %s journal failed: %v", st.name, err)
+				}
+			}
+			log.Printf("memory state restored from %s", cfg.DataDir)
+			persistence = p
+		}
 
 		eventUsecase = usecase.NewEventUsecase(eventRepo, categoryRepo, stock, time.Now, newID)
-		reservationUsecase = usecase.NewReservationUsecase(categoryRepo, reservationRepo, bookingRepo, stock, producer, time.Now, newID, cfg.ReservationTTL, cfg.QueueThreshold, cfg.WorkerPoolSize, true)
+		reservationUsecase = usecase.NewReservationUsecase(categoryRepo, reservationRepo, bookingRepo, stock, time.Now, newID, cfg.ReservationTTL, cfg.QueueThreshold, cfg.WorkerPoolSize, true)
+		outboxRelay = usecase.NewOutboxRelay(stock, bus, newID(), cfg.OutboxBatch, 1, 0)
+		outboxRelay.SetParkAfter(cfg.OutboxParkAfter)
+		ledgerUsecase = usecase.NewLedgerUsecase(categoryRepo, ledgerRepo, stock)
+		// Memory mode has no worker, so the API drains its own ledger buffer.
+		ledgerSource = stock
+
+		subscribe := func(group string, handlers map[string]usecase.EventHandler) {
+			for topic, h := range handlers {
+				if err := bus.Subscribe(topic, group, func(ctx context.Context, msg memory.BusMessage) error { return h(ctx, msg.Value) }); err != nil {
+					log.Fatalf("subscribe %s to %s: %v", group, topic, err)
+				}
+			}
+		}
+		subscribe(cfg.KafkaGroupID, usecase.NewProjector(reservationRepo, bookingRepo).Handlers())
+		if cfg.ReserveMode == "async" {
+			requestUsecase = usecase.NewReservationRequestUsecase(reservationUsecase, requestRepo, bus, time.Now, newID)
+			subscribe(cfg.KafkaGroupID+"-allocator", requestUsecase.Handlers())
+		}
+		if cfg.WebhooksEnabled {
+			webhookUsecase = newWebhookUsecase(cfg, webhookRepo, deliveryRepo)
+			subscribe(cfg.KafkaGroupID+"-webhooks", webhookUsecase.Handlers())
+		}
+		if cfg.NotifyEnabled {
+			channels, err := notify.Channels(notify.Options{
+				EmailSink:    cfg.NotifyEmailSink,
+				SMSSink:      cfg.NotifySMSSink,
+				File:         cfg.NotifyFile,
+				SMTPAddr:     cfg.SMTPAddr,
+				SMTPUsername: cfg.SMTPUsername,
+				SMTPPassword: cfg.SMTPPassword,
+				From:         cfg.SMTPFrom,
+			}, time.Now)
+			if err != nil {
+				log.Fatalf("notification channels: %v", err)
+			}
+			notifyUsecase = newNotificationUsecase(cfg, notifyPrefRepo, notifyRepo, reservationRepo, eventRepo, channels)
+			subscribe(cfg.KafkaGroupID+"-notifications", notifyUsecase.Handlers())
+		}
+		cleanup = append(cleanup, func() {
+			// Let queued events reach the stores before persistence closes.
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := bus.Shutdown(ctx); err != nil {
+				log.Printf("event bus shutdown: %v", err)
+			}
+		})
+	}
+
+	encoding, err := ticketevent.ParseEncoding(cfg.EventEncoding)
+	if err != nil {
+		log.Fatalf("EVENT_ENCODING: %v", err)
+	}
+	reservationUsecase.SetEventEncoding(encoding)
+
+	var schemaHandler *handler.SchemaHandler
+	if cfg.SchemaRegistry != "" {
+		registry, err := schemaregistry.Open(cfg.SchemaRegistry, time.Now)
+		if err != nil {
+			log.Fatalf("open schema registry failed: %v", err)
+		}
+		// The contract this build publishes must extend what was registered
+		// before, otherwise consumers of older versions would break.
+		v, err := registry.Register(ticketeventpb.Subject, ticketeventpb.Schema)
+		if err != nil {
+			log.Fatalf("register %s schema failed: %v", ticketeventpb.Subject, err)
+		}
+		log.Printf("schema %s at version %d", v.Subject, v.Version)
+		schemaHandler = handler.NewSchemaHandler(registry)
+	}
+
+	var webhookHandler *handler.WebhookHandler
+	if webhookUsecase != nil {
+		webhookHandler = handler.NewWebhookHandler(webhookUsecase)
+	}
+
+	var requestHandler *handler.ReservationRequestHandler
+	if requestUsecase != nil {
+		requestHandler = handler.NewReservationRequestHandler(requestUsecase)
+	}
+
+	var busHandler *handler.BusHandler
+	if eventBus != nil {
+		busHandler = handler.NewBusHandler(eventBus)
+	}
+
+	var notificationHandler *handler.NotificationHandler
+	if notifyUsecase != nil {
+		notificationHandler = handler.NewNotificationHandler(notifyUsecase)
 	}
 
 	h := router.New(router.Dependencies{
-		HealthHandler:      handler.NewHealthHandler(),
-		EventHandler:       handler.NewEventHandler(eventUsecase),
-		ReservationHandler: handler.NewReservationHandler(reservationUsecase),
-		Auth:               middleware.NewAuthMiddleware(cfg.JWTSecret),
-		RateLimiter:        middleware.NewRateLimiter(cfg.RateLimitPerMin, time.Minute),
+		HealthHandler:             handler.NewHealthHandler(rehydrateUsecase),
+		EventHandler:              handler.NewEventHandler(eventUsecase),
+		ReservationHandler:        handler.NewReservationHandler(reservationUsecase),
+		ReservationRequestHandler: requestHandler,
+		LedgerHandler:             handler.NewLedgerHandler(ledgerUsecase),
+		SchemaHandler:             schemaHandler,
+		WebhookHandler:            webhookHandler,
+		NotificationHandler:       notificationHandler,
+		BusHandler:                busHandler,
+		Auth:                      middleware.NewAuthMiddleware(cfg.JWTSecret),
+		RateLimiter:               middleware.NewRateLimiter(cfg.RateLimitPerMin, time.Minute),
 	})
 
 	srv := &http.Server{
@@ -90,7 +384,49 @@ func NewHTTPServer(cfg config.Config) *http.Server {
 	}
 
 	reaperCtx, cancel := context.WithCancel(context.Background())
+	if persistence != nil {
+		// Reservations whose TTL ran out while the process was down are
+		// released before serving; live ones keep their original expiry.
+		if err := reservationUsecase.ReleaseAllExpired(reaperCtx, time.Now(), 500); err != nil {
+			log.Printf("release expired after restore failed: %v", err)
+		}
+		go persistence.Run(reaperCtx, cfg.WALSyncEvery, cfg.SnapshotEvery)
+		cleanup = append(cleanup, func() {
+			if err := persistence.Close(); err != nil {
+				log.Printf("close data dir: %v", err)
+			}
+		})
+	}
 	go reservationUsecase.StartExpiryReaper(reaperCtx, 2*time.Second, 100)
+	if expiryWatcher != nil {
+		go reservationUsecase.WatchExpired(reaperCtx, expiryWatcher)
+	}
+	if soldOutCache != nil {
+		go func() {
+			if err := soldOutCache.WatchSoldOut(reaperCtx); err != nil {
+				log.Printf("sold-out cache stopped: %v", err)
+			}
+		}()
+	}
+	if cfg.OutboxRelay {
+		go outboxRelay.Run(reaperCtx, cfg.OutboxInterval)
+	}
+	if allocator != nil {
+		go allocator.Run(reaperCtx)
+	}
+	if webhookUsecase != nil && cfg.AppMode != "production" {
+		// Memory mode has no worker, so deliveries are sent from here.
+		go webhookUsecase.StartDispatcher(reaperCtx, cfg.WebhookInterval, cfg.WebhookBatch)
+	}
+	if ledgerSource != nil {
+		go ledgerUsecase.StartDrain(reaperCtx, ledgerSource, time.Second, 500)
+	}
+	if rehydrateUsecase != nil {
+		go rehydrateUsecase.Start(reaperCtx, cfg.RehydrateEvery)
+	}
+	if reconcileUsecase != nil && cfg.ReconcileEvery > 0 {
+		go reconcileUsecase.StartPeriodic(reaperCtx, cfg.ReconcileEvery, cfg.ReconcileRepair)
+	}
 
 	srv.RegisterOnShutdown(func() {
 		cancel()
@@ -138,3 +474,21 @@ func waitForDependency(attempts int, delay time.Duration, fn func() error) error
 	}
 	return lastErr
 }
+
+func newWebhookUsecase(cfg config.Config, webhooks repository.WebhookRepository, deliveries repository.WebhookDeliveryRepository) *usecase.WebhookUsecase {
+	return usecase.NewWebhookUsecase(webhooks, deliveries, webhook.NewSender(cfg.WebhookTimeout, time.Now), time.Now, newID, usecase.WebhookOptions{
+		MaxAttempts:  cfg.WebhookAttempts,
+		Backoff:      cfg.WebhookBackoff,
+		MaxBackoff:   cfg.WebhookMaxDelay,
+		DisableAfter: cfg.WebhookDisable,
+		Lease:        2 * cfg.WebhookTimeout,
+	})
+}
+
+func newNotificationUsecase(cfg config.Config, prefs repository.NotificationPreferenceRepository, records repository.NotificationRepository, reservations repository.ReservationRepository, events repository.EventRepository, channels map[string]service.Notifier) *usecase.NotificationUsecase {
+	templates, err := notify.LoadTemplates(cfg.NotifyTemplates, cfg.NotifyLocale)
+	if err != nil {
+		log.Fatalf("load notification templates failed: %v", err)
+	}
+	return usecase.NewNotificationUsecase(prefs, records, reservations, events, templates, channels, time.Now, newID)
+}
@@ -0,0 +1,48 @@
+package entity
+
+import "time"
+
+// NotificationPreference is where a user wants to hear about their
+// reservations, and in which language.
+type NotificationPreference struct {
+	UserID       string
+	Email        string
+	Phone        string
+	Locale       string
+	EmailEnabled bool
+	SMSEnabled   bool
+	UpdatedAt    time.Time
+}
+
+// Notification records one message to a user over one channel. A
+// reservation gets at most one notification per kind and channel.
+type Notification struct {
+	ID            string
+	UserID        string
+	ReservationID string
+	Kind          string
+	Channel       string
+	Recipient     string
+	Status        string
+	Attempts      int
+	LastError     string
+	SentAt        *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+const (
+	NotificationBookingConfirmed   = "booking_confirmed"
+	NotificationReservationExpired = "reservation_expired"
+)
+
+const (
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+)
+
+const (
+	NotificationPending = "pending"
+	NotificationSent    = "sent"
+	NotificationFailed  = "failed"
+)
@@ -1,6 +1,10 @@
 package entity
 
-import "time"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 type Reservation struct {
 	ID        string
@@ -9,6 +13,7 @@ type Reservation struct {
 	Category  string
 	Qty       int
 	Status    string
+	Version   int
 	ExpiredAt time.Time
 	CreatedAt time.Time
 }
@@ -17,4 +22,22 @@ const (
 	ReservationStatusReserved  = "reserved"
 	ReservationStatusConfirmed = "confirmed"
 	ReservationStatusExpired   = "expired"
+	ReservationStatusRefunded  = "refunded"
 )
+
+var ErrInvalidTransition = errors.New("invalid reservation status transition")
+
+// reservationTransitions lists the allowed next states; states absent as keys are terminal.
+var reservationTransitions = map[string][]string{
+	ReservationStatusReserved:  {ReservationStatusConfirmed, ReservationStatusExpired},
+	ReservationStatusConfirmed: {ReservationStatusRefunded},
+}
+
+func ValidateTransition(from, to string) error {
+	for _, next := range reservationTransitions[from] {
+		if next == to {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, from, to)
+}
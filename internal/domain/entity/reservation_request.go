@@ -0,0 +1,34 @@
+package entity
+
+import "time"
+
+// ReservationRequest is a reserve call queued for the allocator in async
+// reserve mode. ReservationID and ExpiredAt are set once stock was held;
+// Reason explains a rejection. A request found to have held stock that
+// expired before it was settled ends as expired.
+type ReservationRequest struct {
+	ID            string
+	UserID        string
+	EventID       string
+	Category      string
+	Qty           int
+	Status        string
+	ReservationID string
+	Reason        string
+	ExpiredAt     *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+const (
+	ReservationRequestQueued   = "queued"
+	ReservationRequestReserved = "reserved"
+	ReservationRequestRejected = "rejected"
+	ReservationRequestFailed   = "failed"
+	ReservationRequestExpired  = "expired"
+)
+
+// Done reports whether the request reached a final status.
+func (r ReservationRequest) Done() bool {
+	return r.Status != ReservationRequestQueued
+}
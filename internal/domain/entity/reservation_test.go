@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateTransition(t *testing.T) {
+	cases := []struct {
+		from, to string
+		ok       bool
+	}{
+		{ReservationStatusReserved, ReservationStatusConfirmed, true},
+		{ReservationStatusReserved, ReservationStatusExpired, true},
+		{ReservationStatusConfirmed, ReservationStatusRefunded, true},
+		{ReservationStatusConfirmed, ReservationStatusReserved, false},
+		{ReservationStatusConfirmed, ReservationStatusExpired, false},
+		{ReservationStatusExpired, ReservationStatusConfirmed, false},
+		{ReservationStatusRefunded, ReservationStatusConfirmed, false},
+	}
+	for _, c := range cases {
+		err := ValidateTransition(c.from, c.to)
+		if c.ok && err != nil {
+			t.Fatalf("%s -> %s: unexpected error %v", c.from, c.to, err)
+		}
+		if !c.ok && !errors.Is(err, ErrInvalidTransition) {
+			t.Fatalf("%s -> %s: expected ErrInvalidTransition, got %v", c.from, c.to, err)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package entity
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StockMovement is one immutable ledger entry. Delta is the change in
+// remaining stock and Balance the remaining stock right after the movement.
+type StockMovement struct {
+	ID            string
+	EventID       string
+	Category      string
+	Type          string
+	ReservationID string
+	UserID        string
+	Delta         int
+	Balance       int
+	CreatedAt     time.Time
+}
+
+const (
+	MovementInit    = "init"
+	MovementReserve = "reserve"
+	MovementConfirm = "confirm"
+	MovementRelease = "release"
+	MovementExpire  = "expire"
+	// MovementRebalance moves stock between shard counters of one category.
+	MovementRebalance = "rebalance"
+)
+
+// SplitMovementID turns a movement ID, a stream ID "<ms>-<seq>" optionally
+// prefixed with the "<partition>:" of the Redis stream it was read from, into
+// parts that sort in stream order.
+func SplitMovementID(id string) (int64, int64) {
+	if i := strings.LastIndex(id, ":"); i >= 0 {
+		id = id[i+1:]
+	}
+	msPart, seqPart, _ := strings.Cut(id, "-")
+	ms, _ := strconv.ParseInt(msPart, 10, 64)
+	seq, _ := strconv.ParseInt(seqPart, 10, 64)
+	return ms, seq
+}
@@ -0,0 +1,47 @@
+package entity
+
+import (
+	"slices"
+	"time"
+)
+
+// Webhook is a partner endpoint that receives ticket events. An empty
+// EventTypes list subscribes to every type.
+type Webhook struct {
+	ID                  string
+	URL                 string
+	Secret              string
+	EventTypes          []string
+	Active              bool
+	DisabledReason      string
+	ConsecutiveFailures int
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+func (w Webhook) Accepts(eventType string) bool {
+	return w.Active && (len(w.EventTypes) == 0 || slices.Contains(w.EventTypes, eventType))
+}
+
+// WebhookDelivery is one event queued for one webhook, and its delivery log.
+type WebhookDelivery struct {
+	ID             string
+	WebhookID      string
+	EventID        string
+	EventType      string
+	Payload        []byte
+	Status         string
+	Attempts       int
+	ResponseStatus int
+	LastError      string
+	NextAttemptAt  time.Time
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
@@ -0,0 +1,13 @@
+package repository
+
+import "errors"
+
+var (
+	// ErrQueryCanceled means the caller went away before the query finished.
+	ErrQueryCanceled = errors.New("query canceled")
+	// ErrQueryTimeout means the query exceeded its configured deadline.
+	ErrQueryTimeout = errors.New("query timed out")
+	ErrNotFound     = errors.New("record not found")
+	// ErrStaleVersion means the row changed concurrently on every CAS attempt.
+	ErrStaleVersion = errors.New("stale record version")
+)
@@ -1,8 +1,12 @@
 package repository
 
-import "concert-booking/internal/domain/entity"
+import (
+	"context"
+
+	"concert-booking/internal/domain/entity"
+)
 
 type EventRepository interface {
-	Create(event entity.Event) error
-	FindByID(id string) (entity.Event, error)
+	Create(ctx context.Context, event entity.Event) error
+	FindByID(ctx context.Context, id string) (entity.Event, error)
 }
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+
+	"concert-booking/internal/domain/entity"
+)
+
+type NotificationPreferenceRepository interface {
+	// Get returns ErrNotFound for a user who never saved preferences.
+	Get(ctx context.Context, userID string) (entity.NotificationPreference, error)
+	Upsert(ctx context.Context, pref entity.NotificationPreference) error
+}
+
+type NotificationRepository interface {
+	// Create records a notification unless one exists for the same
+	// reservation, kind and channel, in which case the existing record is
+	// returned with false.
+	Create(ctx context.Context, notification entity.Notification) (entity.Notification, bool, error)
+	Update(ctx context.Context, notification entity.Notification) error
+	// ListByUser returns the newest notifications of a user first.
+	ListByUser(ctx context.Context, userID string, limit int) ([]entity.Notification, error)
+}
@@ -1,14 +1,27 @@
 package repository
 
-import "concert-booking/internal/domain/entity"
+import (
+	"context"
+	"time"
+
+	"concert-booking/internal/domain/entity"
+)
 
 type ReservationRepository interface {
-	Upsert(reservation entity.Reservation) error
-	FindByID(id string) (entity.Reservation, error)
-	UpdateStatus(id, status string) error
+	// Upsert records a new reservation; an existing row is never overwritten,
+	// so a late reserved event cannot roll back a later status.
+	Upsert(ctx context.Context, reservation entity.Reservation) error
+	FindByID(ctx context.Context, id string) (entity.Reservation, error)
+	// Transition moves the reservation to status with compare-and-set on its
+	// version. Moving to the current status is a no-op; illegal moves return
+	// entity.ErrInvalidTransition and lost races ErrStaleVersion.
+	Transition(ctx context.Context, id, status string) (entity.Reservation, error)
+	// FindHolding returns reservations that still hold stock at now: confirmed
+	// or booked ones (reported as confirmed), and reserved ones that have not expired yet.
+	FindHolding(ctx context.Context, now time.Time) ([]entity.Reservation, error)
 }
 
 type BookingRepository interface {
-	CreateIfNotExists(booking entity.Booking) (bool, error)
-	FindByReservationID(reservationID string) (entity.Booking, error)
+	CreateIfNotExists(ctx context.Context, booking entity.Booking) (bool, error)
+	FindByReservationID(ctx context.Context, reservationID string) (entity.Booking, error)
 }
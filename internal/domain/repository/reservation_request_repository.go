@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"context"
+
+	"concert-booking/internal/domain/entity"
+)
+
+type ReservationRequestRepository interface {
+	Upsert(ctx context.Context, req entity.ReservationRequest) error
+	// Get returns ErrNotFound for an unknown request.
+	Get(ctx context.Context, id string) (entity.ReservationRequest, error)
+}
@@ -0,0 +1,13 @@
+package repository
+
+import (
+	"context"
+
+	"concert-booking/internal/domain/entity"
+)
+
+type StockLedgerRepository interface {
+	// Append stores movements idempotently by ID; existing entries are never modified.
+	Append(ctx context.Context, movements ...entity.StockMovement) error
+	FindByCategory(ctx context.Context, eventID, category string) ([]entity.StockMovement, error)
+}
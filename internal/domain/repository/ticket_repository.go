@@ -1,9 +1,14 @@
 package repository
 
-import "concert-booking/internal/domain/entity"
+import (
+	"context"
+
+	"concert-booking/internal/domain/entity"
+)
 
 type TicketCategoryRepository interface {
-	Create(category entity.TicketCategory) error
-	FindByEventID(eventID string) ([]entity.TicketCategory, error)
-	FindByEventAndName(eventID, name string) (entity.TicketCategory, error)
+	Create(ctx context.Context, category entity.TicketCategory) error
+	FindByEventID(ctx context.Context, eventID string) ([]entity.TicketCategory, error)
+	FindByEventAndName(ctx context.Context, eventID, name string) (entity.TicketCategory, error)
+	List(ctx context.Context) ([]entity.TicketCategory, error)
 }
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"concert-booking/internal/domain/entity"
+)
+
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook entity.Webhook) error
+	FindByID(ctx context.Context, id string) (entity.Webhook, error)
+	List(ctx context.Context) ([]entity.Webhook, error)
+	// Update stores the URL, filter and active flag; enabling a webhook
+	// clears its failure streak and disabled reason.
+	Update(ctx context.Context, webhook entity.Webhook) error
+	Delete(ctx context.Context, id string) error
+	// RecordAttempt resets the failure streak after a success. After a
+	// failure it extends the streak and, once it reaches disableAfter,
+	// deactivates the webhook with reason. The updated webhook is returned.
+	RecordAttempt(ctx context.Context, id string, ok bool, disableAfter int, reason string, at time.Time) (entity.Webhook, error)
+}
+
+type WebhookDeliveryRepository interface {
+	// Create queues a delivery unless the same event was already queued for
+	// the webhook; the bool reports whether it was created.
+	Create(ctx context.Context, delivery entity.WebhookDelivery) (bool, error)
+	FindByID(ctx context.Context, id string) (entity.WebhookDelivery, error)
+	// ListByWebhook returns the newest deliveries of a webhook first.
+	ListByWebhook(ctx context.Context, webhookID string, limit int) ([]entity.WebhookDelivery, error)
+	// ClaimDue returns up to limit pending deliveries due at now and pushes
+	// their next attempt to now+lease, so concurrent dispatchers skip them
+	// until the claimer records the outcome or dies.
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]entity.WebhookDelivery, error)
+	Update(ctx context.Context, delivery entity.WebhookDelivery) error
+}
@@ -0,0 +1,11 @@
+package service
+
+import "context"
+
+// ExpiryWatcher pushes holds released by the stock store as soon as their TTL
+// runs out, instead of waiting for the next reaper sweep.
+type ExpiryWatcher interface {
+	// WatchExpired blocks until ctx ends, recording event(meta) with every
+	// release and then calling fn for the hold.
+	WatchExpired(ctx context.Context, event ReleaseEvent, fn func(ReservationMeta)) error
+}
@@ -0,0 +1,13 @@
+package service
+
+import (
+	"context"
+
+	"concert-booking/internal/domain/entity"
+)
+
+// LedgerSource is the stock store's buffer of movements not yet drained to durable storage.
+type LedgerSource interface {
+	ReadLedger(ctx context.Context, limit int) ([]entity.StockMovement, error)
+	AckLedger(ctx context.Context, ids ...string) error
+}
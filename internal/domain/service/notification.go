@@ -0,0 +1,23 @@
+package service
+
+import "context"
+
+// NotificationMessage is one rendered notification. SMS channels send Text
+// only; HTML is optional for email.
+type NotificationMessage struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Notifier delivers messages over one channel (email, SMS, ...).
+type Notifier interface {
+	Send(ctx context.Context, msg NotificationMessage) error
+}
+
+// NotificationRenderer renders the template of kind in locale, falling back
+// to its default locale. The result has no recipient.
+type NotificationRenderer interface {
+	Render(kind, locale string, data any) (NotificationMessage, error)
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"time"
+)
+
+// OutboxEvent is a domain event waiting to be relayed to the broker.
+// ID is stable across redeliveries so consumers can deduplicate.
+type OutboxEvent struct {
+	ID      string
+	Topic   string
+	Key     string
+	Payload []byte
+	// Ref is the store-specific position used to acknowledge the event.
+	Ref string
+}
+
+// ParkedEvent is an outbox event the relay stopped retrying, with the error
+// of its last publish.
+type ParkedEvent struct {
+	OutboxEvent
+	Error    string
+	ParkedAt time.Time
+}
+
+type OutboxStore interface {
+	Append(ctx context.Context, events ...OutboxEvent) error
+	Pending(ctx context.Context, limit int) ([]OutboxEvent, error)
+	Ack(ctx context.Context, refs ...string) error
+	// Park moves an event out of the pending set into a parked set, kept for
+	// inspection and manual replay.
+	Park(ctx context.Context, e ParkedEvent) error
+	AcquireRelayLease(ctx context.Context, owner string, ttl time.Duration) (bool, error)
+}
+
+type messageIDKey struct{}
+
+// WithMessageID attaches a dedupe ID that producers forward as a message header.
+func WithMessageID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, messageIDKey{}, id)
+}
+
+func MessageIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(messageIDKey{}).(string)
+	return id, ok && id != ""
+}
+
+type traceParentKey struct{}
+
+// WithTraceParent attaches the W3C trace context events created under ctx carry.
+func WithTraceParent(ctx context.Context, traceParent string) context.Context {
+	return context.WithValue(ctx, traceParentKey{}, traceParent)
+}
+
+func TraceParentFromContext(ctx context.Context) (string, bool) {
+	v, ok := ctx.Value(traceParentKey{}).(string)
+	return v, ok && v != ""
+}
@@ -0,0 +1,16 @@
+package service
+
+import (
+	"context"
+	"time"
+)
+
+// StockReconciler exposes the raw stock state needed to detect and repair drift.
+type StockReconciler interface {
+	// ReservationSnapshot returns every reservation the stock store still tracks, in any status.
+	ReservationSnapshot(ctx context.Context) ([]ReservationMeta, error)
+	// CompareAndSetStock overwrites remaining stock only if it still equals current.
+	CompareAndSetStock(ctx context.Context, eventID, category string, current, value int) (bool, error)
+	AcquireLock(ctx context.Context, name, owner string, ttl time.Duration) (bool, error)
+	ReleaseLock(ctx context.Context, name, owner string) error
+}
@@ -0,0 +1,14 @@
+package service
+
+import "context"
+
+// StockRehydrator rebuilds stock state after the stock store lost its data.
+type StockRehydrator interface {
+	// MissingStock returns the categories whose stock counter does not exist.
+	MissingStock(ctx context.Context, eventID string, categories []string) ([]string, error)
+	// PrepareStock fixes how a category's stock will be split before its
+	// reservations are restored, so each lands where InitStock expects it.
+	PrepareStock(ctx context.Context, eventID, category string, total int) error
+	// RestoreReservations recreates reservation records that are absent, leaving existing ones untouched.
+	RestoreReservations(ctx context.Context, reservations []ReservationMeta) error
+}
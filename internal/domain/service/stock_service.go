@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 )
 
@@ -10,6 +11,10 @@ var (
 	ErrOutOfStock           = errors.New("out of stock")
 	ErrReservationNotFound  = errors.New("reservation not found")
 	ErrReservationFinalized = errors.New("reservation already finalized")
+	// ErrReservationReleased is returned by GetReservation for a hold that
+	// was taken and has since expired or been released, for as long as the
+	// store keeps its record. It matches ErrReservationNotFound.
+	ErrReservationReleased = fmt.Errorf("%w: hold released", ErrReservationNotFound)
 )
 
 type ReservationMeta struct {
@@ -25,13 +30,27 @@ type ReservationMeta struct {
 type StockService interface {
 	InitStock(ctx context.Context, eventID, category string, total int) error
 	GetStocks(ctx context.Context, eventID string, categories []string) (map[string]int, error)
-	Reserve(ctx context.Context, meta ReservationMeta, ttl time.Duration) error
+	// Reserve holds stock and records the outbox events in the same atomic step.
+	Reserve(ctx context.Context, meta ReservationMeta, ttl time.Duration, events ...OutboxEvent) error
 	GetReservation(ctx context.Context, reservationID string) (ReservationMeta, error)
-	ConfirmReservation(ctx context.Context, reservationID string) error
-	ReleaseReservation(ctx context.Context, reservationID string) (ReservationMeta, error)
-	ReleaseExpired(ctx context.Context, now time.Time, limit int) ([]ReservationMeta, error)
+	// ConfirmReservation and ReleaseReservation record the outbox events only
+	// when they flip the hold, in the same atomic step.
+	ConfirmReservation(ctx context.Context, reservationID string, events ...OutboxEvent) error
+	ReleaseReservation(ctx context.Context, reservationID string, events ...OutboxEvent) (ReservationMeta, error)
+	// ReleaseExpired records event(meta) for every hold it releases; event may be nil.
+	ReleaseExpired(ctx context.Context, now time.Time, limit int, event ReleaseEvent) ([]ReservationMeta, error)
 }
 
+// ReleaseEvent builds the outbox event for a hold the stock store releases on
+// its own, so the store can write it in the same step as the release.
+type ReleaseEvent func(meta ReservationMeta) (OutboxEvent, error)
+
 type EventProducer interface {
 	Publish(ctx context.Context, topic string, key string, value []byte) error
 }
+
+// SoldOutCache answers from local state whether a category is known to be
+// empty, so requests can be rejected without a round trip to the stock store.
+type SoldOutCache interface {
+	SoldOut(eventID, category string) bool
+}
@@ -0,0 +1,18 @@
+package service
+
+import "context"
+
+// WebhookRequest is one signed POST to a partner endpoint.
+type WebhookRequest struct {
+	URL        string
+	Secret     string
+	DeliveryID string
+	EventType  string
+	Body       []byte
+}
+
+// WebhookSender delivers a webhook. It returns the response status; any
+// error or non-2xx status counts as a failed attempt.
+type WebhookSender interface {
+	Send(ctx context.Context, req WebhookRequest) (int, error)
+}
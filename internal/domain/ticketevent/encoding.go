@@ -0,0 +1,193 @@
+package ticketevent
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"concert-booking/internal/domain/ticketevent/ticketeventpb"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Encoding selects the wire format New writes.
+type Encoding string
+
+const (
+	EncodingJSON     Encoding = "json"
+	EncodingProtobuf Encoding = "protobuf"
+
+	ContentTypeJSON     = "application/json"
+	ContentTypeProtobuf = "application/protobuf"
+)
+
+func ParseEncoding(v string) (Encoding, error) {
+	switch Encoding(v) {
+	case "", EncodingJSON:
+		return EncodingJSON, nil
+	case EncodingProtobuf:
+		return EncodingProtobuf, nil
+	}
+	return "", fmt.Errorf("unknown event encoding %q", v)
+}
+
+// isProto tells the encodings apart: JSON envelopes and legacy payloads are
+// objects, a protobuf envelope starts with a field tag.
+func isProto(value []byte) bool {
+	value = bytes.TrimLeft(value, " \t\r\n")
+	return len(value) > 0 && value[0] != '{'
+}
+
+func marshalProto(env Envelope, data any) ([]byte, error) {
+	msg, err := toProto(data)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(&ticketeventpb.Envelope{
+		Id:              env.ID,
+		Type:            env.Type,
+		Source:          env.Source,
+		SpecVersion:     env.SpecVersion,
+		Time:            timestamppb.New(env.Time),
+		Subject:         env.Subject,
+		DataContentType: ContentTypeProtobuf,
+		DataVersion:     int32(env.DataVersion),
+		TraceParent:     env.TraceParent,
+		Data:            raw,
+	})
+}
+
+func unmarshalProto(value []byte) (Envelope, error) {
+	var pb ticketeventpb.Envelope
+	if err := proto.Unmarshal(value, &pb); err != nil {
+		return Envelope{}, err
+	}
+	env := Envelope{
+		ID:              pb.GetId(),
+		Type:            pb.GetType(),
+		Source:          pb.GetSource(),
+		SpecVersion:     pb.GetSpecVersion(),
+		Time:            pb.GetTime().AsTime(),
+		Subject:         pb.GetSubject(),
+		DataContentType: pb.GetDataContentType(),
+		DataVersion:     int(pb.GetDataVersion()),
+		TraceParent:     pb.GetTraceParent(),
+	}
+	data, err := fromProto(env.Type, pb.GetData())
+	if err != nil {
+		return Envelope{}, err
+	}
+	if env.Data, err = json.Marshal(data); err != nil {
+		return Envelope{}, err
+	}
+	return env, nil
+}
+
+func toProto(data any) (proto.Message, error) {
+	switch d := data.(type) {
+	case Reserved:
+		return &ticketeventpb.Reserved{
+			ReservationId: d.ReservationID,
+			UserId:        d.UserID,
+			EventId:       d.EventID,
+			Category:      d.Category,
+			Qty:           int32(d.Qty),
+			Status:        d.Status,
+			ExpiredAt:     timestamp(d.ExpiredAt),
+			CreatedAt:     timestamp(d.CreatedAt),
+		}, nil
+	case Confirmed:
+		return &ticketeventpb.Confirmed{
+			BookingId:     d.BookingID,
+			ReservationId: d.ReservationID,
+			EventId:       d.EventID,
+			PaymentStatus: d.PaymentStatus,
+			CreatedAt:     timestamp(d.CreatedAt),
+		}, nil
+	case Expired:
+		return &ticketeventpb.Expired{ReservationId: d.ReservationID, EventId: d.EventID, Status: d.Status}, nil
+	case Refunded:
+		return &ticketeventpb.Refunded{
+			ReservationId: d.ReservationID,
+			BookingId:     d.BookingID,
+			EventId:       d.EventID,
+			Category:      d.Category,
+			Qty:           int32(d.Qty),
+			RefundedAt:    timestamp(d.RefundedAt),
+		}, nil
+	}
+	return nil, fmt.Errorf("no protobuf message for %T", data)
+}
+
+func fromProto(typ string, raw []byte) (any, error) {
+	switch typ {
+	case TypeReserved:
+		var m ticketeventpb.Reserved
+		if err := proto.Unmarshal(raw, &m); err != nil {
+			return nil, err
+		}
+		return Reserved{
+			ReservationID: m.GetReservationId(),
+			UserID:        m.GetUserId(),
+			EventID:       m.GetEventId(),
+			Category:      m.GetCategory(),
+			Qty:           int(m.GetQty()),
+			Status:        m.GetStatus(),
+			ExpiredAt:     fromTimestamp(m.GetExpiredAt()),
+			CreatedAt:     fromTimestamp(m.GetCreatedAt()),
+		}, nil
+	case TypeConfirmed:
+		var m ticketeventpb.Confirmed
+		if err := proto.Unmarshal(raw, &m); err != nil {
+			return nil, err
+		}
+		return Confirmed{
+			BookingID:     m.GetBookingId(),
+			ReservationID: m.GetReservationId(),
+			EventID:       m.GetEventId(),
+			PaymentStatus: m.GetPaymentStatus(),
+			CreatedAt:     fromTimestamp(m.GetCreatedAt()),
+		}, nil
+	case TypeExpired:
+		var m ticketeventpb.Expired
+		if err := proto.Unmarshal(raw, &m); err != nil {
+			return nil, err
+		}
+		return Expired{ReservationID: m.GetReservationId(), EventID: m.GetEventId(), Status: m.GetStatus()}, nil
+	case TypeRefunded:
+		var m ticketeventpb.Refunded
+		if err := proto.Unmarshal(raw, &m); err != nil {
+			return nil, err
+		}
+		return Refunded{
+			ReservationID: m.GetReservationId(),
+			BookingID:     m.GetBookingId(),
+			EventID:       m.GetEventId(),
+			Category:      m.GetCategory(),
+			Qty:           int(m.GetQty()),
+			RefundedAt:    fromTimestamp(m.GetRefundedAt()),
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownType, typ)
+}
+
+// timestamp leaves zero times unset so they decode back to zero, not 1970.
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func fromTimestamp(ts *timestamppb.Timestamp) time.Time {
+	if ts == nil {
+		return time.Time{}
+	}
+	return ts.AsTime()
+}
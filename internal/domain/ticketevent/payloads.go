@@ -0,0 +1,122 @@
+package ticketevent
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"concert-booking/internal/domain/entity"
+)
+
+// Reserved is the data of concert.ticket.reserved.
+type Reserved struct {
+	ReservationID string    `json:"reservation_id"`
+	UserID        string    `json:"user_id"`
+	EventID       string    `json:"event_id"`
+	Category      string    `json:"category"`
+	Qty           int       `json:"qty"`
+	Status        string    `json:"status"`
+	ExpiredAt     time.Time `json:"expired_at"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Confirmed is the data of concert.ticket.confirmed.
+type Confirmed struct {
+	BookingID     string    `json:"booking_id"`
+	ReservationID string    `json:"reservation_id"`
+	EventID       string    `json:"event_id,omitempty"`
+	PaymentStatus string    `json:"payment_status"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Expired is the data of concert.ticket.expired.
+type Expired struct {
+	ReservationID string `json:"reservation_id"`
+	EventID       string `json:"event_id,omitempty"`
+	Status        string `json:"status"`
+}
+
+// Refunded is the data of concert.ticket.refunded.
+type Refunded struct {
+	ReservationID string    `json:"reservation_id"`
+	BookingID     string    `json:"booking_id"`
+	EventID       string    `json:"event_id"`
+	Category      string    `json:"category"`
+	Qty           int       `json:"qty"`
+	RefundedAt    time.Time `json:"refunded_at"`
+}
+
+func ReservedFrom(r entity.Reservation) Reserved {
+	return Reserved{ReservationID: r.ID, UserID: r.UserID, EventID: r.EventID, Category: r.Category, Qty: r.Qty, Status: r.Status, ExpiredAt: r.ExpiredAt, CreatedAt: r.CreatedAt}
+}
+
+func (r Reserved) Reservation() entity.Reservation {
+	return entity.Reservation{ID: r.ReservationID, UserID: r.UserID, EventID: r.EventID, Category: r.Category, Qty: r.Qty, Status: r.Status, ExpiredAt: r.ExpiredAt, CreatedAt: r.CreatedAt}
+}
+
+func ConfirmedFrom(b entity.Booking, eventID string) Confirmed {
+	return Confirmed{BookingID: b.ID, ReservationID: b.ReservationID, EventID: eventID, PaymentStatus: b.PaymentStatus, CreatedAt: b.CreatedAt}
+}
+
+func (c Confirmed) Booking() entity.Booking {
+	return entity.Booking{ID: c.BookingID, ReservationID: c.ReservationID, PaymentStatus: c.PaymentStatus, CreatedAt: c.CreatedAt}
+}
+
+// Reserved decodes the data, upgrading version 0 (a bare entity.Reservation).
+func (e Envelope) Reserved() (Reserved, error) {
+	if err := e.expect(TypeReserved); err != nil {
+		return Reserved{}, err
+	}
+	if e.DataVersion == 0 {
+		var legacy entity.Reservation
+		if err := json.Unmarshal(e.Data, &legacy); err != nil {
+			return Reserved{}, err
+		}
+		return ReservedFrom(legacy), nil
+	}
+	var out Reserved
+	return out, json.Unmarshal(e.Data, &out)
+}
+
+// Confirmed decodes the data, upgrading version 0 (a bare entity.Booking).
+func (e Envelope) Confirmed() (Confirmed, error) {
+	if err := e.expect(TypeConfirmed); err != nil {
+		return Confirmed{}, err
+	}
+	if e.DataVersion == 0 {
+		var legacy entity.Booking
+		if err := json.Unmarshal(e.Data, &legacy); err != nil {
+			return Confirmed{}, err
+		}
+		return ConfirmedFrom(legacy, ""), nil
+	}
+	var out Confirmed
+	return out, json.Unmarshal(e.Data, &out)
+}
+
+// Expired decodes the data; version 0 already used the same field names.
+func (e Envelope) Expired() (Expired, error) {
+	if err := e.expect(TypeExpired); err != nil {
+		return Expired{}, err
+	}
+	var out Expired
+	return out, json.Unmarshal(e.Data, &out)
+}
+
+func (e Envelope) Refunded() (Refunded, error) {
+	if err := e.expect(TypeRefunded); err != nil {
+		return Refunded{}, err
+	}
+	var out Refunded
+	return out, json.Unmarshal(e.Data, &out)
+}
+
+func (e Envelope) expect(typ string) error {
+	if e.Type != typ {
+		return fmt.Errorf("%w: %s, want %s", ErrUnknownType, e.Type, typ)
+	}
+	if e.DataVersion > DataVersion {
+		return fmt.Errorf("%s data version %d is newer than supported %d", e.Type, e.DataVersion, DataVersion)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+// Package ticketevent defines the envelope every ticket event is published
+// in and the typed payloads consumers decode from it.
+//
+// The envelope follows CloudEvents 1.0 (JSON format) with two extension
+// attributes: dataversion, the schema version of data, and traceparent, the
+// W3C trace context of the request that caused the event. Messages published
+// before the envelope existed carry the bare payload; Decode reports them as
+// data version 0 and the typed accessors upgrade them.
+//
+// Envelopes are written as JSON or, with EncodingProtobuf, as the messages in
+// ticketeventpb/events.proto. Decode accepts both, so consumers keep working
+// while producers switch.
+package ticketevent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"concert-booking/internal/domain/service"
+)
+
+const (
+	SpecVersion = "1.0"
+	// DataVersion is the schema version written by this build.
+	DataVersion = 1
+	Source      = "/concert-booking"
+
+	TopicReserved  = "ticket.reserved"
+	TopicConfirmed = "ticket.confirmed"
+	TopicExpired   = "ticket.expired"
+	// TopicRefunded and TypeRefunded are reserved for the refund flow;
+	// nothing produces them yet, so no consumer subscribes to them.
+	TopicRefunded = "ticket.refunded"
+
+	TypeReserved  = "concert.ticket.reserved"
+	TypeConfirmed = "concert.ticket.confirmed"
+	TypeExpired   = "concert.ticket.expired"
+	TypeRefunded  = "concert.ticket.refunded"
+)
+
+var ErrUnknownType = errors.New("unknown event type")
+
+type Envelope struct {
+	ID              string          `json:"id"`
+	Type            string          `json:"type"`
+	Source          string          `json:"source"`
+	SpecVersion     string          `json:"specversion"`
+	Time            time.Time       `json:"time"`
+	Subject         string          `json:"subject,omitempty"`
+	DataContentType string          `json:"datacontenttype,omitempty"`
+	DataVersion     int             `json:"dataversion"`
+	TraceParent     string          `json:"traceparent,omitempty"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// TypeOf maps a topic to the event type published on it.
+func TypeOf(topic string) (string, error) {
+	switch topic {
+	case TopicReserved:
+		return TypeReserved, nil
+	case TopicConfirmed:
+		return TypeConfirmed, nil
+	case TopicExpired:
+		return TypeExpired, nil
+	case TopicRefunded:
+		return TypeRefunded, nil
+	}
+	return "", fmt.Errorf("%w: topic %s", ErrUnknownType, topic)
+}
+
+// New wraps data for topic in enc. The trace context comes from ctx, or a
+// new trace is started when the caller has none.
+func (enc Encoding) New(ctx context.Context, id, topic, subject string, data any, at time.Time) ([]byte, error) {
+	typ, err := TypeOf(topic)
+	if err != nil {
+		return nil, err
+	}
+	trace, ok := service.TraceParentFromContext(ctx)
+	if !ok {
+		trace = NewTraceParent()
+	}
+	env := Envelope{
+		ID:          id,
+		Type:        typ,
+		Source:      Source,
+		SpecVersion: SpecVersion,
+		Time:        at.UTC(),
+		Subject:     subject,
+		DataVersion: DataVersion,
+		TraceParent: trace,
+	}
+	if enc == EncodingProtobuf {
+		return marshalProto(env, data)
+	}
+	env.DataContentType = ContentTypeJSON
+	if env.Data, err = json.Marshal(data); err != nil {
+		return nil, err
+	}
+	return json.Marshal(env)
+}
+
+// Decode reads an envelope from a message on topic in either encoding. A
+// bare legacy payload is returned as an envelope of data version 0 around it.
+// Protobuf data is converted to JSON, so the typed accessors read both alike.
+func Decode(topic string, value []byte) (Envelope, error) {
+	if isProto(value) {
+		return unmarshalProto(value)
+	}
+	var probe struct {
+		SpecVersion string `json:"specversion"`
+	}
+	if err := json.Unmarshal(value, &probe); err != nil {
+		return Envelope{}, err
+	}
+	if probe.SpecVersion == "" {
+		typ, err := TypeOf(topic)
+		if err != nil {
+			return Envelope{}, err
+		}
+		return Envelope{Type: typ, SpecVersion: SpecVersion, Data: value}, nil
+	}
+	var env Envelope
+	if err := json.Unmarshal(value, &env); err != nil {
+		return Envelope{}, err
+	}
+	if !strings.HasPrefix(env.SpecVersion, "1.") {
+		return Envelope{}, fmt.Errorf("unsupported specversion %q", env.SpecVersion)
+	}
+	return env, nil
+}
+
+// NewTraceParent starts a sampled W3C trace with a random trace and span ID.
+func NewTraceParent() string {
+	b := make([]byte, 24)
+	_, _ = rand.Read(b)
+	return "00-" + hex.EncodeToString(b[:16]) + "-" + hex.EncodeToString(b[16:]) + "-01"
+}
+
+// ValidTraceParent checks the version-00 traceparent layout.
+func ValidTraceParent(v string) bool {
+	parts := strings.Split(v, "-")
+	if len(parts) != 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return false
+	}
+	for _, p := range parts {
+		if _, err := hex.DecodeString(p); err != nil {
+			return false
+		}
+	}
+	return parts[1] != strings.Repeat("0", 32) && parts[2] != strings.Repeat("0", 16)
+}
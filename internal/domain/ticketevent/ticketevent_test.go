@@ -0,0 +1,151 @@
+package ticketevent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"concert-booking/internal/domain/entity"
+	"concert-booking/internal/domain/service"
+)
+
+func TestEnvelopeRoundTrip(t *testing.T) {
+	trace := NewTraceParent()
+	ctx := service.WithTraceParent(context.Background(), trace)
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	data := Reserved{ReservationID: "r1", UserID: "u1", EventID: "e1", Category: "VIP", Qty: 2, Status: entity.ReservationStatusReserved, ExpiredAt: at, CreatedAt: at}
+
+	raw, err := EncodingJSON.New(ctx, "m1", TopicReserved, "r1", data, at)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := Decode(TopicReserved, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.ID != "m1" || env.Type != TypeReserved || env.Source != Source || env.SpecVersion != SpecVersion || env.DataVersion != DataVersion {
+		t.Fatalf("unexpected envelope %+v", env)
+	}
+	if env.TraceParent != trace {
+		t.Fatalf("expected traceparent %s, got %s", trace, env.TraceParent)
+	}
+	got, err := env.Reserved()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != data {
+		t.Fatalf("expected %+v, got %+v", data, got)
+	}
+	if _, err := env.Confirmed(); !errors.Is(err, ErrUnknownType) {
+		t.Fatalf("expected ErrUnknownType reading a reserved event as confirmed, got %v", err)
+	}
+}
+
+func TestNewStartsTraceWithoutCaller(t *testing.T) {
+	raw, err := EncodingJSON.New(context.Background(), "m1", TopicExpired, "r1", Expired{ReservationID: "r1"}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := Decode(TopicExpired, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ValidTraceParent(env.TraceParent) {
+		t.Fatalf("expected a valid traceparent, got %q", env.TraceParent)
+	}
+}
+
+func TestDecodeUpgradesLegacyPayloads(t *testing.T) {
+	booking, _ := json.Marshal(entity.Booking{ID: "b1", ReservationID: "r1", PaymentStatus: "paid"})
+	env, err := Decode(TopicConfirmed, booking)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.DataVersion != 0 {
+		t.Fatalf("expected data version 0, got %d", env.DataVersion)
+	}
+	c, err := env.Confirmed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.BookingID != "b1" || c.ReservationID != "r1" || c.PaymentStatus != "paid" {
+		t.Fatalf("unexpected upgrade %+v", c)
+	}
+}
+
+func TestDecodeRejectsNewerDataVersion(t *testing.T) {
+	raw, _ := json.Marshal(Envelope{Type: TypeExpired, SpecVersion: SpecVersion, DataVersion: DataVersion + 1, Data: json.RawMessage(`{}`)})
+	env, err := Decode(TopicExpired, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := env.Expired(); err == nil {
+		t.Fatal("expected an error for a newer data version")
+	}
+}
+
+func TestValidTraceParent(t *testing.T) {
+	cases := map[string]bool{
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01": true,
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01": false,
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7":    false,
+		"zz-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01": false,
+		"": false,
+	}
+	for v, want := range cases {
+		if got := ValidTraceParent(v); got != want {
+			t.Fatalf("ValidTraceParent(%q) = %v, want %v", v, got, want)
+		}
+	}
+}
+
+func TestProtobufRoundTrip(t *testing.T) {
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	cases := []struct {
+		topic string
+		data  any
+		read  func(Envelope) (any, error)
+	}{
+		{TopicReserved, Reserved{ReservationID: "r1", UserID: "u1", EventID: "e1", Category: "VIP", Qty: 2, Status: "reserved", ExpiredAt: at, CreatedAt: at}, func(e Envelope) (any, error) { return e.Reserved() }},
+		{TopicConfirmed, Confirmed{BookingID: "b1", ReservationID: "r1", EventID: "e1", PaymentStatus: "paid", CreatedAt: at}, func(e Envelope) (any, error) { return e.Confirmed() }},
+		{TopicExpired, Expired{ReservationID: "r1", EventID: "e1", Status: "expired"}, func(e Envelope) (any, error) { return e.Expired() }},
+		{TopicRefunded, Refunded{ReservationID: "r1", BookingID: "b1", EventID: "e1", Category: "VIP", Qty: 2, RefundedAt: at}, func(e Envelope) (any, error) { return e.Refunded() }},
+	}
+	for _, c := range cases {
+		raw, err := EncodingProtobuf.New(context.Background(), "m1", c.topic, "r1", c.data, at)
+		if err != nil {
+			t.Fatalf("%s: %v", c.topic, err)
+		}
+		if json.Valid(raw) {
+			t.Fatalf("%s: expected a protobuf payload", c.topic)
+		}
+		env, err := Decode(c.topic, raw)
+		if err != nil {
+			t.Fatalf("%s: %v", c.topic, err)
+		}
+		if env.ID != "m1" || env.DataContentType != ContentTypeProtobuf || env.DataVersion != DataVersion || !env.Time.Equal(at) {
+			t.Fatalf("%s: unexpected envelope %+v", c.topic, env)
+		}
+		got, err := c.read(env)
+		if err != nil {
+			t.Fatalf("%s: %v", c.topic, err)
+		}
+		if got != c.data {
+			t.Fatalf("%s: expected %+v, got %+v", c.topic, c.data, got)
+		}
+	}
+}
+
+func TestParseEncoding(t *testing.T) {
+	if enc, err := ParseEncoding(""); err != nil || enc != EncodingJSON {
+		t.Fatalf("expected json default, got %q %v", enc, err)
+	}
+	if enc, err := ParseEncoding("protobuf"); err != nil || enc != EncodingProtobuf {
+		t.Fatalf("expected protobuf, got %q %v", enc, err)
+	}
+	if _, err := ParseEncoding("avro"); err == nil {
+		t.Fatal("expected an error for an unknown encoding")
+	}
+}
@@ -0,0 +1,12 @@
+package ticketeventpb
+
+import _ "embed"
+
+// Subject is the schema registry subject events.proto is registered under.
+const Subject = "concertbooking.ticketevent.v1"
+
+// Schema is the source of events.proto, registered at startup so
+// incompatible changes are caught before they are published.
+//
+//go:embed events.proto
+var Schema string
@@ -0,0 +1,196 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Headers added to a dead letter next to the original message's own headers.
+const (
+	HeaderError          = "dlq-error"
+	HeaderOriginalTopic  = "dlq-original-topic"
+	HeaderOriginalPart   = "dlq-original-partition"
+	HeaderOriginalOffset = "dlq-original-offset"
+	HeaderAttempts       = "dlq-attempts"
+	HeaderFailedAt       = "dlq-failed-at"
+)
+
+func DeadLetterTopic(topic string) string { return topic + ".dlq" }
+
+// deadLetter copies msg for the dead-letter topic with the failure recorded in headers.
+func deadLetter(msg kafka.Message, cause error, attempts int, at time.Time) kafka.Message {
+	headers := make([]kafka.Header, 0, len(msg.Headers)+6)
+	headers = append(headers, msg.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: HeaderError, Value: []byte(cause.Error())},
+		kafka.Header{Key: HeaderOriginalTopic, Value: []byte(msg.Topic)},
+		kafka.Header{Key: HeaderOriginalPart, Value: []byte(strconv.Itoa(msg.Partition))},
+		kafka.Header{Key: HeaderOriginalOffset, Value: []byte(strconv.FormatInt(msg.Offset, 10))},
+		kafka.Header{Key: HeaderAttempts, Value: []byte(strconv.Itoa(attempts))},
+		kafka.Header{Key: HeaderFailedAt, Value: []byte(at.UTC().Format(time.RFC3339Nano))},
+	)
+	return kafka.Message{Topic: DeadLetterTopic(msg.Topic), Key: msg.Key, Value: msg.Value, Headers: headers}
+}
+
+// Header returns the value of the first header named key.
+func Header(msg kafka.Message, key string) string {
+	for _, h := range msg.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// Resubmission strips the dead-letter headers so the message can go back to
+// its original topic; the message-id header is kept for consumer dedupe.
+func Resubmission(msg kafka.Message) (kafka.Message, error) {
+	topic := Header(msg, HeaderOriginalTopic)
+	if topic == "" {
+		return kafka.Message{}, errors.New("dead letter has no original topic header")
+	}
+	headers := make([]kafka.Header, 0, len(msg.Headers))
+	for _, h := range msg.Headers {
+		switch h.Key {
+		case HeaderError, HeaderOriginalTopic, HeaderOriginalPart, HeaderOriginalOffset, HeaderAttempts, HeaderFailedAt:
+			continue
+		}
+		headers = append(headers, h)
+	}
+	return kafka.Message{Topic: topic, Key: msg.Key, Value: msg.Value, Headers: headers}, nil
+}
+
+// DeadLetters reads a dead-letter topic partition by partition. Its cursor is
+// the committed offset of a dedicated consumer group: purge and replay move
+// it forward, so handled letters are not listed again; Kafka retention
+// removes them for good.
+type DeadLetters struct {
+	brokers  []string
+	security *Security
+	group    string
+	client   *kafka.Client
+}
+
+func NewDeadLetters(brokers []string, security *Security, group string) *DeadLetters {
+	return &DeadLetters{brokers: brokers, security: security, group: group, client: &kafka.Client{Addr: kafka.TCP(brokers...), Timeout: 10 * time.Second, Transport: security.Transport()}}
+}
+
+// PartitionRange is the span of a partition still to be handled: [Next, End).
+type PartitionRange struct {
+	Partition int
+	Next      int64
+	End       int64
+}
+
+// Ranges returns, per partition, the cursor (or the first retained offset
+// when fromStart is set or nothing was committed) and the end of the log.
+func (d *DeadLetters) Ranges(ctx context.Context, topic string, fromStart bool) ([]PartitionRange, error) {
+	parts, err := partitions(ctx, d.client, topic)
+	if err != nil {
+		return nil, err
+	}
+	reqs := make([]kafka.OffsetRequest, 0, 2*len(parts))
+	for _, p := range parts {
+		reqs = append(reqs, kafka.FirstOffsetOf(p), kafka.LastOffsetOf(p))
+	}
+	offsets, err := d.client.ListOffsets(ctx, &kafka.ListOffsetsRequest{Topics: map[string][]kafka.OffsetRequest{topic: reqs}})
+	if err != nil {
+		return nil, err
+	}
+	committed := map[int]int64{}
+	if !fromStart {
+		res, err := d.client.OffsetFetch(ctx, &kafka.OffsetFetchRequest{GroupID: d.group, Topics: map[string][]int{topic: parts}})
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range res.Topics[topic] {
+			committed[p.Partition] = p.CommittedOffset
+		}
+	}
+	out := make([]PartitionRange, 0, len(parts))
+	for _, p := range offsets.Topics[topic] {
+		if p.Error != nil {
+			return nil, p.Error
+		}
+		r := PartitionRange{Partition: p.Partition, Next: p.FirstOffset, End: p.LastOffset}
+		if c, ok := committed[p.Partition]; ok && c > r.Next {
+			r.Next = c
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// Read calls fn for every message in r, in offset order.
+func (d *DeadLetters) Read(ctx context.Context, topic string, r PartitionRange, fn func(kafka.Message) error) error {
+	return readRange(ctx, d.brokers, d.security, topic, r, fn)
+}
+
+// Commit moves the cursor of a partition to offset (the next letter to handle).
+func (d *DeadLetters) Commit(ctx context.Context, topic string, partition int, offset int64) error {
+	res, err := d.client.OffsetCommit(ctx, &kafka.OffsetCommitRequest{
+		GroupID:      d.group,
+		GenerationID: -1,
+		Topics:       map[string][]kafka.OffsetCommit{topic: {{Partition: partition, Offset: offset}}},
+	})
+	if err != nil {
+		return err
+	}
+	for _, p := range res.Topics[topic] {
+		if p.Error != nil {
+			return p.Error
+		}
+	}
+	return nil
+}
+
+func partitions(ctx context.Context, client *kafka.Client, topic string) ([]int, error) {
+	meta, err := client.Metadata(ctx, &kafka.MetadataRequest{Topics: []string{topic}})
+	if err != nil {
+		return nil, err
+	}
+	if len(meta.Topics) != 1 || meta.Topics[0].Error != nil {
+		return nil, fmt.Errorf("topic %s: %v", topic, topicError(meta))
+	}
+	out := make([]int, 0, len(meta.Topics[0].Partitions))
+	for _, p := range meta.Topics[0].Partitions {
+		out = append(out, p.ID)
+	}
+	return out, nil
+}
+
+// readRange reads [r.Next, r.End) of one partition without a consumer group.
+func readRange(ctx context.Context, brokers []string, security *Security, topic string, r PartitionRange, fn func(kafka.Message) error) error {
+	if r.Next >= r.End {
+		return nil
+	}
+	reader := kafka.NewReader(kafka.ReaderConfig{Brokers: brokers, Dialer: security.Dialer(), Topic: topic, Partition: r.Partition, MinBytes: 1, MaxBytes: 10e6})
+	defer reader.Close()
+	if err := reader.SetOffset(r.Next); err != nil {
+		return err
+	}
+	for {
+		msg, err := reader.ReadMessage(ctx)
+		if err != nil {
+			return err
+		}
+		if err := fn(msg); err != nil {
+			return err
+		}
+		if msg.Offset+1 >= r.End {
+			return nil
+		}
+	}
+}
+
+func topicError(meta *kafka.MetadataResponse) error {
+	if len(meta.Topics) == 0 {
+		return errors.New("not found")
+	}
+	return meta.Topics[0].Error
+}
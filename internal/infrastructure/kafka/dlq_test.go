@@ -0,0 +1,28 @@
+package kafka
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func TestDeadLetterRoundTrip(t *testing.T) {
+	msg := kafka.Message{Topic: "ticket.reserved", Partition: 2, Offset: 41, Key: []byte("event-1"), Value: []byte(`{}`),
+		Headers: []kafka.Header{{Key: MessageIDHeader, Value: []byte("ticket.reserved:res-1")}}}
+	letter := deadLetter(msg, errors.New("db down"), 5, time.Now())
+	if letter.Topic != "ticket.reserved.dlq" || Header(letter, HeaderError) != "db down" || Header(letter, HeaderOriginalOffset) != "41" || Header(letter, HeaderAttempts) != "5" {
+		t.Fatalf("unexpected dead letter %+v", letter)
+	}
+	back, err := Resubmission(letter)
+	if err != nil {
+		t.Fatalf("resubmission: %v", err)
+	}
+	if back.Topic != "ticket.reserved" || len(back.Headers) != 1 || Header(back, MessageIDHeader) != "ticket.reserved:res-1" {
+		t.Fatalf("unexpected resubmission %+v", back)
+	}
+	if _, err := Resubmission(msg); err == nil {
+		t.Fatal("expected error for a message without dead-letter headers")
+	}
+}
@@ -0,0 +1,73 @@
+package kafka
+
+import (
+	"context"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// History reads retained messages of a topic without joining a consumer
+// group, so nothing is committed and live consumers are not rebalanced.
+type History struct {
+	brokers  []string
+	security *Security
+	client   *kafka.Client
+}
+
+func NewHistory(brokers []string, security *Security) *History {
+	return &History{brokers: brokers, security: security, client: &kafka.Client{Addr: kafka.TCP(brokers...), Timeout: 10 * time.Second, Transport: security.Transport()}}
+}
+
+// Start is where a history read begins: the first message at or after Time
+// when it is set, otherwise Offset (clamped to what is retained; a negative
+// offset means the first retained message).
+type Start struct {
+	Offset int64
+	Time   time.Time
+}
+
+// Ranges returns, per partition, the span from start to the current end of
+// the log. Messages produced after the call are not part of it.
+func (h *History) Ranges(ctx context.Context, topic string, start Start) ([]PartitionRange, error) {
+	parts, err := partitions(ctx, h.client, topic)
+	if err != nil {
+		return nil, err
+	}
+	reqs := make([]kafka.OffsetRequest, 0, 3*len(parts))
+	for _, p := range parts {
+		reqs = append(reqs, kafka.FirstOffsetOf(p), kafka.LastOffsetOf(p))
+		if !start.Time.IsZero() {
+			reqs = append(reqs, kafka.TimeOffsetOf(p, start.Time))
+		}
+	}
+	offsets, err := h.client.ListOffsets(ctx, &kafka.ListOffsetsRequest{Topics: map[string][]kafka.OffsetRequest{topic: reqs}})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]PartitionRange, 0, len(parts))
+	for _, p := range offsets.Topics[topic] {
+		if p.Error != nil {
+			return nil, p.Error
+		}
+		r := PartitionRange{Partition: p.Partition, Next: p.FirstOffset, End: p.LastOffset}
+		if start.Time.IsZero() {
+			r.Next = max(r.Next, min(start.Offset, r.End))
+		} else {
+			// No message at or after the time comes back as offset -1.
+			r.Next = r.End
+			for off := range p.Offsets {
+				if off >= 0 && off < r.Next {
+					r.Next = max(off, p.FirstOffset)
+				}
+			}
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// Read calls fn for every message in r, in offset order.
+func (h *History) Read(ctx context.Context, topic string, r PartitionRange, fn func(kafka.Message) error) error {
+	return readRange(ctx, h.brokers, h.security, topic, r, fn)
+}
@@ -1,29 +1,261 @@
 package kafka
 
 import (
+	"cmp"
 	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
 	"time"
 
+	"concert-booking/internal/domain/service"
+	"concert-booking/internal/domain/ticketevent"
+	"concert-booking/internal/observability/metrics"
+
 	"github.com/segmentio/kafka-go"
 )
 
+// MessageIDHeader carries the outbox event ID so consumers can drop redeliveries.
+const MessageIDHeader = "message-id"
+
+// Envelope attributes copied into headers (CloudEvents Kafka binding) so
+// consumers can route and trace a message without parsing its value.
+const (
+	HeaderEventID     = "ce_id"
+	HeaderEventType   = "ce_type"
+	HeaderSpecVersion = "ce_specversion"
+	HeaderTraceParent = "traceparent"
+	HeaderContentType = "content-type"
+)
+
+// ProducerOptions configures the writer used for ticket events.
+type ProducerOptions struct {
+	Brokers  []string
+	Security *Security
+	// Acks is all (every in-sync replica), one (the leader, the default) or none.
+	Acks string
+	// Async returns from Publish before the broker acknowledged the write
+	// (only metadata errors such as unreachable brokers are returned);
+	// failed writes are counted and retried in place.
+	Async bool
+	// Compression is none, gzip, snappy, lz4 or zstd.
+	Compression  string
+	BatchSize    int
+	BatchBytes   int64
+	BatchTimeout time.Duration
+	// MaxAttempts bounds how often one batch is written before giving up.
+	// Retried writes keep their message-id header, so consumers that dedupe
+	// on it (or on the envelope ID) see each event once.
+	MaxAttempts  int
+	WriteTimeout time.Duration
+	// OnError receives the messages of a failed async write that were still
+	// undelivered when Close was called. It runs on the writer's goroutine
+	// and Close waits for it.
+	OnError func(msgs []kafka.Message, err error)
+}
+
+// Messages are partitioned by a hash of their key, so every event of one
+// key goes to the same partition.
 type Producer struct {
 	writer *kafka.Writer
+	// resend rewrites a failed async batch synchronously; nil in sync mode.
+	resend   func(ctx context.Context, msgs ...kafka.Message) error
+	retry    *kafka.Writer
+	timeout  time.Duration
+	onError  func(msgs []kafka.Message, err error)
+	stopping context.Context
+	stop     context.CancelFunc
 }
 
-func NewProducer(brokers []string) *Producer {
-	return &Producer{writer: &kafka.Writer{
-		Addr:         kafka.TCP(brokers...),
-		RequiredAcks: kafka.RequireOne,
-		Async:        true,
-		BatchTimeout: 5 * time.Millisecond,
-	}}
+func NewProducer(opts ProducerOptions) (*Producer, error) {
+	acks, err := requiredAcks(opts.Acks)
+	if err != nil {
+		return nil, err
+	}
+	codec, err := compression(opts.Compression)
+	if err != nil {
+		return nil, err
+	}
+	p := &Producer{timeout: cmp.Or(opts.WriteTimeout, 10*time.Second)}
+	p.stopping, p.stop = context.WithCancel(context.Background())
+	if opts.Async {
+		// Sync writes return the error to the caller, which retries.
+		p.onError = opts.OnError
+		p.retry = &kafka.Writer{
+			Addr:         kafka.TCP(opts.Brokers...),
+			Transport:    opts.Security.Transport(),
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: acks,
+			Compression:  codec,
+			MaxAttempts:  opts.MaxAttempts,
+			WriteTimeout: opts.WriteTimeout,
+		}
+		p.resend = p.retry.WriteMessages
+	}
+	p.writer = &kafka.Writer{
+		Addr:         kafka.TCP(opts.Brokers...),
+		Transport:    opts.Security.Transport(),
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: acks,
+		Async:        opts.Async,
+		Compression:  codec,
+		BatchSize:    opts.BatchSize,
+		BatchBytes:   opts.BatchBytes,
+		BatchTimeout: opts.BatchTimeout,
+		MaxAttempts:  opts.MaxAttempts,
+		WriteTimeout: opts.WriteTimeout,
+		Completion:   p.completed,
+	}
+	return p, nil
 }
 
 func (p *Producer) Publish(ctx context.Context, topic string, key string, value []byte) error {
-	return p.writer.WriteMessages(ctx, kafka.Message{Topic: topic, Key: []byte(key), Value: value})
+	msg := kafka.Message{Topic: topic, Key: []byte(key), Value: value}
+	if id, ok := service.MessageIDFromContext(ctx); ok {
+		msg.Headers = append(msg.Headers, kafka.Header{Key: MessageIDHeader, Value: []byte(id)})
+	}
+	msg.Headers = append(msg.Headers, envelopeHeaders(topic, value)...)
+	return p.writer.WriteMessages(ctx, msg)
 }
 
+// Close stops retrying failed async batches, hands them to OnError and
+// flushes what is still queued.
 func (p *Producer) Close() error {
-	return p.writer.Close()
+	p.stop()
+	err := p.writer.Close()
+	if p.retry != nil {
+		err = errors.Join(err, p.retry.Close())
+	}
+	return err
+}
+
+// completed runs once per written partition batch, in both modes.
+func (p *Producer) completed(msgs []kafka.Message, err error) {
+	result := "delivered"
+	if err != nil {
+		result = "failed"
+	}
+	for _, msg := range msgs {
+		metrics.IncProducerMessage(msg.Topic, result)
+	}
+	if err != nil && p.resend != nil {
+		p.redeliver(msgs, err)
+	}
+}
+
+// redeliver retries a failed async batch until it is written. It runs on
+// the partition's writer goroutine, so the partition's later batches, and
+// with them later events of the same keys, wait behind it. Only when the
+// producer closes first are the messages handed to OnError.
+func (p *Producer) redeliver(msgs []kafka.Message, cause error) {
+	retry := make([]kafka.Message, len(msgs))
+	for i, msg := range msgs {
+		retry[i] = kafka.Message{Topic: msg.Topic, Key: msg.Key, Value: msg.Value, Headers: msg.Headers}
+	}
+	backoff := 100 * time.Millisecond
+	for {
+		select {
+		case <-p.stopping.Done():
+			if p.onError != nil {
+				p.onError(msgs, cause)
+			}
+			return
+		case <-time.After(backoff):
+		}
+		ctx, cancel := context.WithTimeout(p.stopping, p.timeout)
+		err := p.resend(ctx, retry...)
+		cancel()
+		if err == nil {
+			for _, msg := range msgs {
+				metrics.IncProducerMessage(msg.Topic, "redelivered")
+			}
+			return
+		}
+		if p.stopping.Err() == nil {
+			cause = err
+			log.Printf("kafka producer: redeliver %d messages to %s: %v", len(msgs), msgs[0].Topic, err)
+		}
+		backoff = min(backoff*2, 5*time.Second)
+	}
+}
+
+// envelopeHeaders lifts the envelope attributes into headers; values that are
+// not an envelope get none.
+func envelopeHeaders(topic string, value []byte) []kafka.Header {
+	env, err := ticketevent.Decode(topic, value)
+	if err != nil || env.ID == "" {
+		return nil
+	}
+	contentType := "application/cloudevents+json"
+	if env.DataContentType == ticketevent.ContentTypeProtobuf {
+		contentType = "application/cloudevents+protobuf"
+	}
+	headers := []kafka.Header{
+		{Key: HeaderEventID, Value: []byte(env.ID)},
+		{Key: HeaderEventType, Value: []byte(env.Type)},
+		{Key: HeaderSpecVersion, Value: []byte(env.SpecVersion)},
+		{Key: HeaderContentType, Value: []byte(contentType)},
+	}
+	if env.TraceParent != "" {
+		headers = append(headers, kafka.Header{Key: HeaderTraceParent, Value: []byte(env.TraceParent)})
+	}
+	return headers
+}
+
+func requiredAcks(acks string) (kafka.RequiredAcks, error) {
+	switch strings.ToLower(acks) {
+	case "all", "-1":
+		return kafka.RequireAll, nil
+	case "", "one", "1":
+		return kafka.RequireOne, nil
+	case "none", "0":
+		return kafka.RequireNone, nil
+	}
+	return 0, fmt.Errorf("kafka producer: unknown acks %q", acks)
+}
+
+func compression(name string) (kafka.Compression, error) {
+	switch strings.ToLower(name) {
+	case "", "none":
+		return 0, nil
+	case "gzip":
+		return kafka.Gzip, nil
+	case "snappy":
+		return kafka.Snappy, nil
+	case "lz4":
+		return kafka.Lz4, nil
+	case "zstd":
+		return kafka.Zstd, nil
+	}
+	return 0, fmt.Errorf("kafka producer: unknown compression %q", name)
+}
+
+// OutboxFallback returns an OnError callback that appends the messages a
+// closing producer could not deliver back to the outbox, so the relay of
+// the next run publishes them. They land behind events already queued for
+// their key; consumers retry events that arrive ahead of the state they
+// depend on. While the producer runs, failed writes are retried in place.
+func OutboxFallback(store service.OutboxStore) func([]kafka.Message, error) {
+	return func(msgs []kafka.Message, cause error) {
+		events := make([]service.OutboxEvent, 0, len(msgs))
+		for _, msg := range msgs {
+			events = append(events, service.OutboxEvent{
+				ID:      Header(msg, MessageIDHeader),
+				Topic:   msg.Topic,
+				Key:     string(msg.Key),
+				Payload: append([]byte(nil), msg.Value...),
+			})
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		result := "requeued"
+		if err := store.Append(ctx, events...); err != nil {
+			log.Printf("producer fallback: %d messages lost after %v: %v", len(msgs), cause, err)
+			result = "dropped"
+		}
+		for _, msg := range msgs {
+			metrics.IncProducerMessage(msg.Topic, result)
+		}
+	}
 }
@@ -0,0 +1,103 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"concert-booking/internal/infrastructure/memory"
+
+	"github.com/segmentio/kafka-go"
+)
+
+func TestProducerOptionsRejectUnknownValues(t *testing.T) {
+	if _, err := NewProducer(ProducerOptions{Acks: "quorum"}); err == nil {
+		t.Fatal("expected error for unknown acks")
+	}
+	if _, err := NewProducer(ProducerOptions{Compression: "brotli"}); err == nil {
+		t.Fatal("expected error for unknown compression")
+	}
+	if _, err := NewSecurity(SecurityOptions{SASLMechanism: "gssapi"}); err == nil {
+		t.Fatal("expected error for unknown sasl mechanism")
+	}
+	if _, err := NewSecurity(SecurityOptions{SASLMechanism: "SCRAM-SHA-512", Username: "u", Password: "p", TLS: true}); err != nil {
+		t.Fatalf("scram over tls: %v", err)
+	}
+}
+
+func TestAsyncProducerRetriesFailedWritesInPlace(t *testing.T) {
+	var failed []kafka.Message
+	p, err := NewProducer(ProducerOptions{
+		Brokers: []string{"localhost:9092"},
+		Async:   true,
+		OnError: func(msgs []kafka.Message, _ error) { failed = append(failed, msgs...) },
+	})
+	if err != nil {
+		t.Fatalf("new producer: %v", err)
+	}
+	defer p.Close()
+	var resent [][]kafka.Message
+	p.resend = func(_ context.Context, msgs ...kafka.Message) error {
+		resent = append(resent, msgs)
+		if len(resent) == 1 {
+			return errors.New("leader not available")
+		}
+		return nil
+	}
+	msgs := []kafka.Message{{Topic: "ticket.expired", Key: []byte("event-1"), Value: []byte("a")}}
+	p.completed(msgs, errors.New("not enough replicas"))
+	if len(resent) != 2 || string(resent[1][0].Value) != "a" {
+		t.Fatalf("expected the batch written again until it succeeds, got %v", resent)
+	}
+	if len(failed) != 0 {
+		t.Fatalf("expected nothing on OnError while the producer runs, got %v", failed)
+	}
+}
+
+func TestProducerHandsUndeliveredWritesToOnErrorOnClose(t *testing.T) {
+	for _, async := range []bool{true, false} {
+		var failed []kafka.Message
+		p, err := NewProducer(ProducerOptions{
+			Brokers: []string{"localhost:9092"},
+			Async:   async,
+			OnError: func(msgs []kafka.Message, _ error) { failed = append(failed, msgs...) },
+		})
+		if err != nil {
+			t.Fatalf("new producer: %v", err)
+		}
+		if async {
+			p.resend = func(context.Context, ...kafka.Message) error { return errors.New("broker down") }
+		}
+		msgs := []kafka.Message{{Topic: "ticket.reserved", Key: []byte("event-1")}}
+		done := make(chan struct{})
+		go func() {
+			p.completed(msgs, nil)
+			p.completed(msgs, errors.New("not enough replicas"))
+			close(done)
+		}()
+		time.Sleep(50 * time.Millisecond)
+		_ = p.Close()
+		<-done
+		if want := map[bool]int{true: 1, false: 0}[async]; len(failed) != want {
+			t.Fatalf("async=%v: expected %d messages on OnError, got %d", async, want, len(failed))
+		}
+	}
+}
+
+func TestOutboxFallbackRequeuesMessages(t *testing.T) {
+	store := memory.NewStockService()
+	OutboxFallback(store)([]kafka.Message{{
+		Topic:   "ticket.confirmed",
+		Key:     []byte("event-1"),
+		Value:   []byte(`{"id":"evt-2"}`),
+		Headers: []kafka.Header{{Key: MessageIDHeader, Value: []byte("evt-2")}},
+	}}, errors.New("leader not available"))
+	pending, err := store.Pending(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("pending: %v", err)
+	}
+	if len(pending) != 1 || pending[0].ID != "evt-2" || pending[0].Topic != "ticket.confirmed" || pending[0].Key != "event-1" || string(pending[0].Payload) != `{"id":"evt-2"}` {
+		t.Fatalf("unexpected outbox %+v", pending)
+	}
+}
@@ -0,0 +1,185 @@
+package kafka
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"concert-booking/internal/domain/service"
+	"concert-booking/internal/observability/metrics"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Handler processes one message. A returned error is retried with backoff
+// and, once attempts run out, the message goes to the dead-letter topic.
+type Handler func(ctx context.Context, msg kafka.Message) error
+
+type RouterOptions struct {
+	Brokers     []string
+	Security    *Security
+	GroupID     string
+	MaxAttempts int
+	// Backoff is the first retry delay; it doubles per attempt up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Permanent reports errors that retrying cannot fix, e.g. undecodable
+	// payloads; they are dead-lettered at once.
+	Permanent func(error) bool
+}
+
+// Router consumes several topics in one consumer group and dispatches each
+// message to the handler registered for its topic. Every topic has its own
+// reader processed sequentially, so messages with the same key (same
+// partition) are handled in order. Offsets are committed only after a
+// message was handled or dead-lettered, so a crash redelivers it instead of
+// losing it.
+type Router struct {
+	opts     RouterOptions
+	handlers map[string]Handler
+	dlq      *kafka.Writer
+
+	mu      sync.Mutex
+	readers []*kafka.Reader
+}
+
+func NewRouter(opts RouterOptions) *Router {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 1
+	}
+	if opts.Permanent == nil {
+		opts.Permanent = func(error) bool { return false }
+	}
+	return &Router{
+		opts:     opts,
+		handlers: map[string]Handler{},
+		dlq:      &kafka.Writer{Addr: kafka.TCP(opts.Brokers...), Transport: opts.Security.Transport(), RequiredAcks: kafka.RequireAll},
+	}
+}
+
+func (r *Router) Handle(topic string, h Handler) {
+	r.handlers[topic] = h
+}
+
+func (r *Router) Topics() []string {
+	out := make([]string, 0, len(r.handlers))
+	for topic := range r.handlers {
+		out = append(out, topic)
+	}
+	return out
+}
+
+// Run consumes every registered topic until ctx ends.
+func (r *Router) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for topic, h := range r.handlers {
+		reader := kafka.NewReader(kafka.ReaderConfig{Brokers: r.opts.Brokers, Dialer: r.opts.Security.Dialer(), GroupID: r.opts.GroupID, Topic: topic, MinBytes: 1, MaxBytes: 10e6})
+		r.mu.Lock()
+		r.readers = append(r.readers, reader)
+		r.mu.Unlock()
+		wg.Add(1)
+		go func(topic string, h Handler) {
+			defer wg.Done()
+			r.consume(ctx, reader, topic, h)
+		}(topic, h)
+	}
+	wg.Wait()
+}
+
+func (r *Router) consume(ctx context.Context, reader *kafka.Reader, topic string, h Handler) {
+	for {
+		msg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("consume %s: %v", topic, err)
+			continue
+		}
+		if !r.dispatch(ctx, msg, h) {
+			return
+		}
+		if err := reader.CommitMessages(ctx, msg); err != nil && ctx.Err() == nil {
+			log.Printf("commit %s/%d@%d: %v", msg.Topic, msg.Partition, msg.Offset, err)
+		}
+	}
+}
+
+// dispatch returns once the message was handled or dead-lettered; false
+// means ctx ended first and the message must not be committed.
+func (r *Router) dispatch(ctx context.Context, msg kafka.Message, h Handler) bool {
+	delay := r.opts.Backoff
+	for _, header := range msg.Headers {
+		if header.Key == HeaderTraceParent {
+			ctx = service.WithTraceParent(ctx, string(header.Value))
+		}
+	}
+	for attempt := 1; ; attempt++ {
+		err := h(ctx, msg)
+		if err == nil {
+			metrics.IncWorkerMessage(msg.Topic, "processed")
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+		if attempt >= r.opts.MaxAttempts || r.opts.Permanent(err) {
+			log.Printf("handle %s/%d@%d failed after %d attempts, dead-lettering: %v", msg.Topic, msg.Partition, msg.Offset, attempt, err)
+			return r.deadLetter(ctx, msg, err, attempt)
+		}
+		metrics.IncWorkerMessage(msg.Topic, "retried")
+		if !sleep(ctx, delay) {
+			return false
+		}
+		delay = min(delay*2, r.opts.MaxBackoff)
+	}
+}
+
+// deadLetter keeps trying until the dead letter is written: committing the
+// offset without it would lose the message.
+func (r *Router) deadLetter(ctx context.Context, msg kafka.Message, cause error, attempts int) bool {
+	letter := deadLetter(msg, cause, attempts, time.Now())
+	delay := r.opts.Backoff
+	for {
+		err := r.dlq.WriteMessages(ctx, letter)
+		if err == nil {
+			metrics.IncWorkerMessage(msg.Topic, "dead_lettered")
+			return true
+		}
+		log.Printf("write dead letter to %s: %v", letter.Topic, err)
+		if !sleep(ctx, delay) {
+			return false
+		}
+		delay = min(delay*2, r.opts.MaxBackoff)
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// Lag sums the lag of every topic reader.
+func (r *Router) Lag() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var lag int64
+	for _, reader := range r.readers {
+		lag += reader.Stats().Lag
+	}
+	return lag
+}
+
+func (r *Router) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, reader := range r.readers {
+		_ = reader.Close()
+	}
+	return r.dlq.Close()
+}
@@ -0,0 +1,92 @@
+package kafka
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+// SecurityOptions configures how clients authenticate to the brokers.
+// SASLMechanism is one of plain, scram-sha-256 or scram-sha-512; empty
+// disables SASL.
+type SecurityOptions struct {
+	SASLMechanism string
+	Username      string
+	Password      string
+	TLS           bool
+	// TLSCAFile is a PEM bundle trusted in addition to the system roots.
+	TLSCAFile     string
+	TLSSkipVerify bool
+}
+
+// Security is the resolved TLS config and SASL mechanism shared by
+// producers, consumers and admin clients. A nil *Security is plaintext.
+type Security struct {
+	tls  *tls.Config
+	sasl sasl.Mechanism
+}
+
+func NewSecurity(opts SecurityOptions) (*Security, error) {
+	s := &Security{}
+	switch strings.ToLower(opts.SASLMechanism) {
+	case "":
+	case "plain":
+		s.sasl = plain.Mechanism{Username: opts.Username, Password: opts.Password}
+	case "scram-sha-256", "scram-sha-512":
+		algo := scram.SHA256
+		if strings.EqualFold(opts.SASLMechanism, "scram-sha-512") {
+			algo = scram.SHA512
+		}
+		m, err := scram.Mechanism(algo, opts.Username, opts.Password)
+		if err != nil {
+			return nil, fmt.Errorf("kafka sasl: %w", err)
+		}
+		s.sasl = m
+	default:
+		return nil, fmt.Errorf("kafka sasl: unknown mechanism %q", opts.SASLMechanism)
+	}
+	if opts.TLS || opts.TLSCAFile != "" {
+		s.tls = &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: opts.TLSSkipVerify}
+		if opts.TLSCAFile != "" {
+			pem, err := os.ReadFile(opts.TLSCAFile)
+			if err != nil {
+				return nil, fmt.Errorf("kafka tls: %w", err)
+			}
+			pool, err := x509.SystemCertPool()
+			if err != nil {
+				pool = x509.NewCertPool()
+			}
+			if !pool.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("kafka tls: no certificates in %s", opts.TLSCAFile)
+			}
+			s.tls.RootCAs = pool
+		}
+	}
+	return s, nil
+}
+
+// Dialer is used by readers and admin connections.
+func (s *Security) Dialer() *kafka.Dialer {
+	d := &kafka.Dialer{Timeout: 10 * time.Second, DualStack: true}
+	if s != nil {
+		d.TLS = s.tls
+		d.SASLMechanism = s.sasl
+	}
+	return d
+}
+
+// Transport is used by writers and clients.
+func (s *Security) Transport() kafka.RoundTripper {
+	if s == nil || (s.tls == nil && s.sasl == nil) {
+		return kafka.DefaultTransport
+	}
+	return &kafka.Transport{DialTimeout: 10 * time.Second, TLS: s.tls, SASL: s.sasl}
+}
@@ -2,14 +2,12 @@ package kafka
 
 import (
 	"context"
-	"time"
 
 	"github.com/segmentio/kafka-go"
 )
 
-func EnsureTopics(ctx context.Context, brokers []string, topics []string, partitions, replicationFactor int) error {
-	dialer := &kafka.Dialer{Timeout: 10 * time.Second}
-	conn, err := dialer.DialContext(ctx, "tcp", brokers[0])
+func EnsureTopics(ctx context.Context, brokers []string, security *Security, topics []string, partitions, replicationFactor int) error {
+	conn, err := security.Dialer().DialContext(ctx, "tcp", brokers[0])
 	if err != nil {
 		return err
 	}
@@ -1,6 +1,7 @@
 package memory
 
 import (
+	"context"
 	"sync"
 
 	"concert-booking/internal/domain/entity"
@@ -10,13 +11,14 @@ type BookingRepository struct {
 	mu              sync.RWMutex
 	items           map[string]entity.Booking
 	byReservationID map[string]string
+	journal         *Journal
 }
 
 func NewBookingRepository() *BookingRepository {
 	return &BookingRepository{items: map[string]entity.Booking{}, byReservationID: map[string]string{}}
 }
 
-func (r *BookingRepository) CreateIfNotExists(booking entity.Booking) (bool, error) {
+func (r *BookingRepository) CreateIfNotExists(_ context.Context, booking entity.Booking) (bool, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	if _, ok := r.byReservationID[booking.ReservationID]; ok {
@@ -24,10 +26,10 @@ func (r *BookingRepository) CreateIfNotExists(booking entity.Booking) (bool, err
 	}
 	r.items[booking.ID] = booking
 	r.byReservationID[booking.ReservationID] = booking.ID
-	return true, nil
+	return true, r.journal.Append(opPut, booking)
 }
 
-func (r *BookingRepository) FindByReservationID(reservationID string) (entity.Booking, error) {
+func (r *BookingRepository) FindByReservationID(_ context.Context, reservationID string) (entity.Booking, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	id, ok := r.byReservationID[reservationID]
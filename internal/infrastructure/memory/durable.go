@@ -0,0 +1,506 @@
+package memory
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"concert-booking/internal/domain/entity"
+	"concert-booking/internal/domain/service"
+)
+
+const (
+	opPut    = "put"
+	opApply  = "apply"
+	opDelete = "delete"
+)
+
+func unknownOp(op string) error {
+	return fmt.Errorf("unknown journal op %q", op)
+}
+
+func (r *EventRepository) setJournal(j *Journal) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.journal = j
+}
+
+func (r *EventRepository) restoreSnapshot(data []byte) error {
+	return json.Unmarshal(data, &r.events)
+}
+
+func (r *EventRepository) applyRecord(op string, data json.RawMessage) error {
+	if op != opPut {
+		return unknownOp(op)
+	}
+	var e entity.Event
+	if err := json.Unmarshal(data, &e); err != nil {
+		return err
+	}
+	r.events[e.ID] = e
+	return nil
+}
+
+func (r *EventRepository) compact() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data, err := json.Marshal(r.events)
+	if err != nil {
+		return err
+	}
+	return r.journal.Compact(data)
+}
+
+func (r *TicketCategoryRepository) setJournal(j *Journal) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.journal = j
+}
+
+func (r *TicketCategoryRepository) restoreSnapshot(data []byte) error {
+	var items []entity.TicketCategory
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	for _, c := range items {
+		r.putLocked(c)
+	}
+	return nil
+}
+
+func (r *TicketCategoryRepository) applyRecord(op string, data json.RawMessage) error {
+	if op != opPut {
+		return unknownOp(op)
+	}
+	var c entity.TicketCategory
+	if err := json.Unmarshal(data, &c); err != nil {
+		return err
+	}
+	if _, ok := r.byEventKey[c.EventID+":"+c.Name]; !ok {
+		r.putLocked(c)
+	}
+	return nil
+}
+
+func (r *TicketCategoryRepository) compact() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	items := make([]entity.TicketCategory, 0, len(r.byEventKey))
+	for _, list := range r.byEvent {
+		items = append(items, list...)
+	}
+	data, err := json.Marshal(items)
+	if err != nil {
+		return err
+	}
+	return r.journal.Compact(data)
+}
+
+func (r *ReservationRepository) setJournal(j *Journal) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.journal = j
+}
+
+func (r *ReservationRepository) restoreSnapshot(data []byte) error {
+	return json.Unmarshal(data, &r.items)
+}
+
+func (r *ReservationRepository) applyRecord(op string, data json.RawMessage) error {
+	if op != opPut {
+		return unknownOp(op)
+	}
+	var v entity.Reservation
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	r.items[v.ID] = v
+	return nil
+}
+
+func (r *ReservationRepository) compact() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data, err := json.Marshal(r.items)
+	if err != nil {
+		return err
+	}
+	return r.journal.Compact(data)
+}
+
+func (r *BookingRepository) setJournal(j *Journal) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.journal = j
+}
+
+func (r *BookingRepository) restoreSnapshot(data []byte) error {
+	if err := json.Unmarshal(data, &r.items); err != nil {
+		return err
+	}
+	for id, b := range r.items {
+		r.byReservationID[b.ReservationID] = id
+	}
+	return nil
+}
+
+func (r *BookingRepository) applyRecord(op string, data json.RawMessage) error {
+	if op != opPut {
+		return unknownOp(op)
+	}
+	var b entity.Booking
+	if err := json.Unmarshal(data, &b); err != nil {
+		return err
+	}
+	r.items[b.ID] = b
+	r.byReservationID[b.ReservationID] = b.ID
+	return nil
+}
+
+func (r *BookingRepository) compact() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data, err := json.Marshal(r.items)
+	if err != nil {
+		return err
+	}
+	return r.journal.Compact(data)
+}
+
+func (r *StockLedgerRepository) setJournal(j *Journal) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.journal = j
+}
+
+func (r *StockLedgerRepository) restoreSnapshot(data []byte) error {
+	var items []entity.StockMovement
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	r.appendLocked(items)
+	return nil
+}
+
+func (r *StockLedgerRepository) applyRecord(op string, data json.RawMessage) error {
+	if op != opPut {
+		return unknownOp(op)
+	}
+	return r.restoreSnapshot(data)
+}
+
+func (r *StockLedgerRepository) compact() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data, err := json.Marshal(r.entries)
+	if err != nil {
+		return err
+	}
+	return r.journal.Compact(data)
+}
+
+func (r *WebhookRepository) setJournal(j *Journal) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.journal = j
+}
+
+func (r *WebhookRepository) restoreSnapshot(data []byte) error {
+	return json.Unmarshal(data, &r.items)
+}
+
+func (r *WebhookRepository) applyRecord(op string, data json.RawMessage) error {
+	switch op {
+	case opPut:
+		var w entity.Webhook
+		if err := json.Unmarshal(data, &w); err != nil {
+			return err
+		}
+		r.items[w.ID] = w
+	case opDelete:
+		var id string
+		if err := json.Unmarshal(data, &id); err != nil {
+			return err
+		}
+		delete(r.items, id)
+	default:
+		return unknownOp(op)
+	}
+	return nil
+}
+
+func (r *WebhookRepository) compact() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data, err := json.Marshal(r.items)
+	if err != nil {
+		return err
+	}
+	return r.journal.Compact(data)
+}
+
+func (r *WebhookDeliveryRepository) setJournal(j *Journal) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.journal = j
+}
+
+func (r *WebhookDeliveryRepository) restoreSnapshot(data []byte) error {
+	var items map[string]entity.WebhookDelivery
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	for _, d := range items {
+		r.putLocked(d)
+	}
+	return nil
+}
+
+func (r *WebhookDeliveryRepository) applyRecord(op string, data json.RawMessage) error {
+	if op != opPut {
+		return unknownOp(op)
+	}
+	var d entity.WebhookDelivery
+	if err := json.Unmarshal(data, &d); err != nil {
+		return err
+	}
+	r.putLocked(d)
+	return nil
+}
+
+func (r *WebhookDeliveryRepository) compact() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data, err := json.Marshal(r.items)
+	if err != nil {
+		return err
+	}
+	return r.journal.Compact(data)
+}
+
+func (r *NotificationPreferenceRepository) setJournal(j *Journal) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.journal = j
+}
+
+func (r *NotificationPreferenceRepository) restoreSnapshot(data []byte) error {
+	return json.Unmarshal(data, &r.items)
+}
+
+func (r *NotificationPreferenceRepository) applyRecord(op string, data json.RawMessage) error {
+	if op != opPut {
+		return unknownOp(op)
+	}
+	var p entity.NotificationPreference
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	r.items[p.UserID] = p
+	return nil
+}
+
+func (r *NotificationPreferenceRepository) compact() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data, err := json.Marshal(r.items)
+	if err != nil {
+		return err
+	}
+	return r.journal.Compact(data)
+}
+
+func (r *NotificationRepository) setJournal(j *Journal) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.journal = j
+}
+
+func (r *NotificationRepository) restoreSnapshot(data []byte) error {
+	var items map[string]entity.Notification
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+	for _, n := range items {
+		r.putLocked(n)
+	}
+	return nil
+}
+
+func (r *NotificationRepository) applyRecord(op string, data json.RawMessage) error {
+	if op != opPut {
+		return unknownOp(op)
+	}
+	var n entity.Notification
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	r.putLocked(n)
+	return nil
+}
+
+func (r *NotificationRepository) compact() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data, err := json.Marshal(r.items)
+	if err != nil {
+		return err
+	}
+	return r.journal.Compact(data)
+}
+
+func (r *ReservationRequestRepository) setJournal(j *Journal) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.journal = j
+}
+
+func (r *ReservationRequestRepository) restoreSnapshot(data []byte) error {
+	return json.Unmarshal(data, &r.items)
+}
+
+func (r *ReservationRequestRepository) applyRecord(op string, data json.RawMessage) error {
+	if op != opPut {
+		return unknownOp(op)
+	}
+	var req entity.ReservationRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return err
+	}
+	r.items[req.ID] = req
+	return nil
+}
+
+func (r *ReservationRequestRepository) compact() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	data, err := json.Marshal(r.items)
+	if err != nil {
+		return err
+	}
+	return r.journal.Compact(data)
+}
+
+type stockValue struct {
+	EventID  string `json:"event_id"`
+	Category string `json:"category"`
+	Value    int    `json:"value"`
+}
+
+// stockMutation is everything one StockService call changed, journaled as a
+// single record so a crash never replays half of a reservation.
+type stockMutation struct {
+	Stocks       []stockValue              `json:"stocks,omitempty"`
+	Reservations []service.ReservationMeta `json:"reservations,omitempty"`
+	Outbox       []service.OutboxEvent     `json:"outbox,omitempty"`
+	OutboxAck    []string                  `json:"outbox_ack,omitempty"`
+	OutboxSeq    int64                     `json:"outbox_seq"`
+	Parked       []service.ParkedEvent     `json:"parked,omitempty"`
+	Ledger       []entity.StockMovement    `json:"ledger,omitempty"`
+	LedgerAck    []string                  `json:"ledger_ack,omitempty"`
+	LedgerSeq    int64                     `json:"ledger_seq"`
+}
+
+func (m stockMutation) empty() bool {
+	return len(m.Stocks) == 0 && len(m.Reservations) == 0 && len(m.Outbox) == 0 &&
+		len(m.OutboxAck) == 0 && len(m.Parked) == 0 && len(m.Ledger) == 0 && len(m.LedgerAck) == 0
+}
+
+func (s *StockService) setStockLocked(k stockKey, value int) {
+	s.stocks[k] = value
+	s.pending.Stocks = append(s.pending.Stocks, stockValue{EventID: k.eventID, Category: k.category, Value: value})
+}
+
+func (s *StockService) putReservationLocked(meta service.ReservationMeta) {
+	s.reservations[meta.ReservationID] = meta
+	s.pending.Reservations = append(s.pending.Reservations, meta)
+}
+
+// commitLocked journals the changes collected since the last commit. The
+// in-memory state is already updated, so a journal error is only logged.
+func (s *StockService) commitLocked() {
+	m := s.pending
+	s.pending = stockMutation{}
+	if s.journal == nil || m.empty() {
+		return
+	}
+	m.OutboxSeq = s.outboxSeq
+	m.LedgerSeq = s.ledgerSeq
+	if err := s.journal.Append(opApply, m); err != nil {
+		log.Printf("stock journal append: %v", err)
+	}
+}
+
+func (s *StockService) setJournal(j *Journal) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.journal = j
+}
+
+func (s *StockService) restoreSnapshot(data []byte) error {
+	var m stockMutation
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	s.applyMutation(m)
+	return nil
+}
+
+func (s *StockService) applyRecord(op string, data json.RawMessage) error {
+	if op != opApply {
+		return unknownOp(op)
+	}
+	return s.restoreSnapshot(data)
+}
+
+func (s *StockService) applyMutation(m stockMutation) {
+	for _, v := range m.Stocks {
+		s.stocks[stockKey{eventID: v.EventID, category: v.Category}] = v.Value
+	}
+	for _, meta := range m.Reservations {
+		s.reservations[meta.ReservationID] = meta
+	}
+	s.outbox = append(s.outbox, m.Outbox...)
+	s.outbox = withoutRefs(s.outbox, m.OutboxAck, func(e service.OutboxEvent) string { return e.Ref })
+	s.parked = append(s.parked, m.Parked...)
+	s.ledger = append(s.ledger, m.Ledger...)
+	s.ledger = withoutRefs(s.ledger, m.LedgerAck, func(e entity.StockMovement) string { return e.ID })
+	s.outboxSeq = max(s.outboxSeq, m.OutboxSeq)
+	s.ledgerSeq = max(s.ledgerSeq, m.LedgerSeq)
+}
+
+// compact snapshots the whole store in the same shape as a journal record.
+func (s *StockService) compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commitLocked()
+	m := stockMutation{Outbox: s.outbox, OutboxSeq: s.outboxSeq, Parked: s.parked, Ledger: s.ledger, LedgerSeq: s.ledgerSeq}
+	for k, v := range s.stocks {
+		m.Stocks = append(m.Stocks, stockValue{EventID: k.eventID, Category: k.category, Value: v})
+	}
+	for _, meta := range s.reservations {
+		m.Reservations = append(m.Reservations, meta)
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return s.journal.Compact(data)
+}
+
+func withoutRefs[T any](items []T, refs []string, ref func(T) string) []T {
+	if len(refs) == 0 {
+		return items
+	}
+	drop := make(map[string]struct{}, len(refs))
+	for _, r := range refs {
+		drop[r] = struct{}{}
+	}
+	kept := items[:0]
+	for _, item := range items {
+		if _, ok := drop[ref(item)]; !ok {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
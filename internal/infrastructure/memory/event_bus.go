@@ -0,0 +1,328 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"concert-booking/internal/domain/service"
+	"concert-booking/internal/domain/ticketevent"
+	"concert-booking/internal/observability/metrics"
+)
+
+var (
+	// ErrBufferFull rejects a publish while a subscriber's buffer is full;
+	// the outbox relay keeps the event and retries.
+	ErrBufferFull = errors.New("event bus buffer full")
+	ErrBusClosed  = errors.New("event bus closed")
+)
+
+// BusMessage is one published event as a subscriber sees it.
+type BusMessage struct {
+	Topic       string
+	Key         string
+	Value       []byte
+	MessageID   string
+	TraceParent string
+	Offset      int64
+	PublishedAt time.Time
+}
+
+type BusHandler func(ctx context.Context, msg BusMessage) error
+
+type BusOptions struct {
+	// Partitions per subscription; messages with the same key land in the
+	// same partition and are handled in publish order.
+	Partitions int
+	// Buffer is the capacity of each partition queue.
+	Buffer int
+	// MaxAttempts, Backoff and MaxBackoff retry a failing handler like the
+	// Kafka router does; Permanent errors are dead-lettered at once.
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	Permanent   func(error) bool
+}
+
+// EventBus is an in-process service.EventProducer for memory mode. Every
+// subscription (a group on a topic) receives every message published after
+// it subscribed, like a Kafka consumer group with one member.
+type EventBus struct {
+	opts   BusOptions
+	now    func() time.Time
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu     sync.Mutex
+	topics map[string]*busTopic
+	closed bool
+}
+
+type busTopic struct {
+	mu        sync.Mutex
+	name      string
+	offset    int64
+	published uint64
+	rejected  uint64
+	closed    bool
+	subs      []*busSubscription
+}
+
+type busSubscription struct {
+	group        string
+	partitions   []chan BusMessage
+	processed    atomic.Uint64
+	retried      atomic.Uint64
+	deadLettered atomic.Uint64
+	lastError    atomic.Value
+}
+
+func NewEventBus(opts BusOptions, now func() time.Time) *EventBus {
+	if opts.Partitions <= 0 {
+		opts.Partitions = 4
+	}
+	if opts.Buffer <= 0 {
+		opts.Buffer = 1024
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 1
+	}
+	if opts.Permanent == nil {
+		opts.Permanent = func(error) bool { return false }
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &EventBus{opts: opts, now: now, ctx: ctx, cancel: cancel, topics: map[string]*busTopic{}}
+}
+
+func (b *EventBus) topic(name string) (*busTopic, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, ErrBusClosed
+	}
+	t, ok := b.topics[name]
+	if !ok {
+		t = &busTopic{name: name}
+		b.topics[name] = t
+	}
+	return t, nil
+}
+
+// Subscribe starts handling topic for group. A group subscribes to a topic
+// once.
+func (b *EventBus) Subscribe(topic, group string, h BusHandler) error {
+	t, err := b.topic(topic)
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return ErrBusClosed
+	}
+	for _, s := range t.subs {
+		if s.group == group {
+			return fmt.Errorf("group %s already subscribed to %s", group, topic)
+		}
+	}
+	sub := &busSubscription{group: group, partitions: make([]chan BusMessage, b.opts.Partitions)}
+	for i := range sub.partitions {
+		ch := make(chan BusMessage, b.opts.Buffer)
+		sub.partitions[i] = ch
+		b.wg.Add(1)
+		go func() {
+			defer b.wg.Done()
+			for msg := range ch {
+				b.dispatch(sub, msg, h)
+			}
+		}()
+	}
+	t.subs = append(t.subs, sub)
+	return nil
+}
+
+// Publish hands the message to every subscription of topic, or to none:
+// when any target partition is full nothing is enqueued and ErrBufferFull
+// is returned. Messages on a topic without subscribers are dropped.
+func (b *EventBus) Publish(ctx context.Context, topic, key string, value []byte) error {
+	t, err := b.topic(topic)
+	if err != nil {
+		return err
+	}
+	msg := BusMessage{Topic: topic, Key: key, Value: value, PublishedAt: b.now()}
+	msg.MessageID, _ = service.MessageIDFromContext(ctx)
+	if env, err := ticketevent.Decode(topic, value); err == nil && env.TraceParent != "" {
+		msg.TraceParent = env.TraceParent
+	} else {
+		msg.TraceParent, _ = service.TraceParentFromContext(ctx)
+	}
+	part := partition(key, b.opts.Partitions)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.closed {
+		return ErrBusClosed
+	}
+	// Only publishers fill the queues and they hold t.mu, so a queue with
+	// room now still has room when we send below.
+	for _, s := range t.subs {
+		if ch := s.partitions[part]; len(ch) == cap(ch) {
+			t.rejected++
+			return fmt.Errorf("%w: %s group %s", ErrBufferFull, topic, s.group)
+		}
+	}
+	msg.Offset = t.offset
+	t.offset++
+	t.published++
+	for _, s := range t.subs {
+		s.partitions[part] <- msg
+	}
+	return nil
+}
+
+func (b *EventBus) dispatch(sub *busSubscription, msg BusMessage, h BusHandler) {
+	ctx := b.ctx
+	if msg.MessageID != "" {
+		ctx = service.WithMessageID(ctx, msg.MessageID)
+	}
+	if msg.TraceParent != "" {
+		ctx = service.WithTraceParent(ctx, msg.TraceParent)
+	}
+	delay := b.opts.Backoff
+	for attempt := 1; ; attempt++ {
+		err := h(ctx, msg)
+		if err == nil {
+			sub.processed.Add(1)
+			metrics.IncWorkerMessage(msg.Topic, "processed")
+			return
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		sub.lastError.Store(err.Error())
+		if attempt >= b.opts.MaxAttempts || b.opts.Permanent(err) {
+			// There is no dead letter topic in memory; the log and the
+			// counters are the record.
+			log.Printf("bus %s group %s offset %d failed after %d attempts, dropping: %v", msg.Topic, sub.group, msg.Offset, attempt, err)
+			sub.deadLettered.Add(1)
+			metrics.IncWorkerMessage(msg.Topic, "dead_lettered")
+			return
+		}
+		sub.retried.Add(1)
+		metrics.IncWorkerMessage(msg.Topic, "retried")
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, b.opts.MaxBackoff)
+	}
+}
+
+// Shutdown stops accepting messages and waits until the subscribers have
+// handled what is queued. When ctx ends first, handlers are cancelled and
+// the rest of the queue is dropped.
+func (b *EventBus) Shutdown(ctx context.Context) error {
+	b.mu.Lock()
+	b.closed = true
+	topics := make([]*busTopic, 0, len(b.topics))
+	for _, t := range b.topics {
+		topics = append(topics, t)
+	}
+	b.mu.Unlock()
+	for _, t := range topics {
+		t.mu.Lock()
+		if !t.closed {
+			t.closed = true
+			for _, s := range t.subs {
+				for _, ch := range s.partitions {
+					close(ch)
+				}
+			}
+		}
+		t.mu.Unlock()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		b.cancel()
+		return nil
+	case <-ctx.Done():
+		b.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
+
+type TopicStats struct {
+	Topic string `json:"topic"`
+	// Published counts accepted messages, including those no one received.
+	Published     uint64              `json:"published"`
+	Rejected      uint64              `json:"rejected"`
+	Subscriptions []SubscriptionStats `json:"subscriptions"`
+}
+
+type SubscriptionStats struct {
+	Group        string `json:"group"`
+	Pending      int    `json:"pending"`
+	Capacity     int    `json:"capacity"`
+	Processed    uint64 `json:"processed"`
+	Retried      uint64 `json:"retried"`
+	DeadLettered uint64 `json:"dead_lettered"`
+	LastError    string `json:"last_error,omitempty"`
+}
+
+// Topics reports every topic that was published or subscribed to, sorted by
+// name.
+func (b *EventBus) Topics() []TopicStats {
+	b.mu.Lock()
+	topics := make([]*busTopic, 0, len(b.topics))
+	for _, t := range b.topics {
+		topics = append(topics, t)
+	}
+	b.mu.Unlock()
+	sort.Slice(topics, func(i, j int) bool { return topics[i].name < topics[j].name })
+
+	out := make([]TopicStats, 0, len(topics))
+	for _, t := range topics {
+		t.mu.Lock()
+		stats := TopicStats{Topic: t.name, Published: t.published, Rejected: t.rejected, Subscriptions: make([]SubscriptionStats, 0, len(t.subs))}
+		for _, s := range t.subs {
+			sub := SubscriptionStats{
+				Group:        s.group,
+				Capacity:     len(s.partitions) * b.opts.Buffer,
+				Processed:    s.processed.Load(),
+				Retried:      s.retried.Load(),
+				DeadLettered: s.deadLettered.Load(),
+			}
+			for _, ch := range s.partitions {
+				sub.Pending += len(ch)
+			}
+			if v, ok := s.lastError.Load().(string); ok {
+				sub.LastError = v
+			}
+			stats.Subscriptions = append(stats.Subscriptions, sub)
+		}
+		t.mu.Unlock()
+		out = append(out, stats)
+	}
+	return out
+}
+
+func partition(key string, n int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return int(h.Sum32() % uint32(n))
+}
@@ -0,0 +1,150 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"concert-booking/internal/domain/service"
+)
+
+type busRecorder struct {
+	mu    sync.Mutex
+	byKey map[string][]string
+	ids   []string
+}
+
+func (r *busRecorder) handle(_ context.Context, msg BusMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.byKey == nil {
+		r.byKey = map[string][]string{}
+	}
+	r.byKey[msg.Key] = append(r.byKey[msg.Key], string(msg.Value))
+	r.ids = append(r.ids, msg.MessageID)
+	return nil
+}
+
+func shutdown(t *testing.T, bus *EventBus) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := bus.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+}
+
+func TestEventBusFansOutInKeyOrder(t *testing.T) {
+	bus := NewEventBus(BusOptions{Partitions: 4, Buffer: 1000}, time.Now)
+	var a, b busRecorder
+	if err := bus.Subscribe("t", "a", a.handle); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	if err := bus.Subscribe("t", "b", b.handle); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	if err := bus.Subscribe("t", "a", a.handle); err == nil {
+		t.Fatal("expected duplicate group to be rejected")
+	}
+
+	var wg sync.WaitGroup
+	for k := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key := fmt.Sprintf("key-%d", k)
+			for i := range 50 {
+				ctx := service.WithMessageID(context.Background(), fmt.Sprintf("%s-%d", key, i))
+				if err := bus.Publish(ctx, "t", key, []byte(fmt.Sprint(i))); err != nil {
+					t.Errorf("publish: %v", err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	shutdown(t, bus)
+
+	for _, rec := range []*busRecorder{&a, &b} {
+		if len(rec.byKey) != 8 || len(rec.ids) != 400 {
+			t.Fatalf("expected 8 keys and 400 messages, got %d and %d", len(rec.byKey), len(rec.ids))
+		}
+		for key, values := range rec.byKey {
+			for i, v := range values {
+				if v != fmt.Sprint(i) {
+					t.Fatalf("%s out of order: %v", key, values)
+				}
+			}
+		}
+	}
+	stats := bus.Topics()
+	if len(stats) != 1 || stats[0].Published != 400 || len(stats[0].Subscriptions) != 2 || stats[0].Subscriptions[0].Processed != 400 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestEventBusRejectsWhenBufferFull(t *testing.T) {
+	bus := NewEventBus(BusOptions{Partitions: 1, Buffer: 2}, time.Now)
+	release := make(chan struct{})
+	started := make(chan struct{}, 10)
+	_ = bus.Subscribe("t", "slow", func(context.Context, BusMessage) error {
+		started <- struct{}{}
+		<-release
+		return nil
+	})
+	ctx := context.Background()
+	_ = bus.Publish(ctx, "t", "k", []byte("1"))
+	<-started // the first message is in flight, the queue is empty again
+	for i := range 2 {
+		if err := bus.Publish(ctx, "t", "k", []byte("queued")); err != nil {
+			t.Fatalf("publish %d: %v", i, err)
+		}
+	}
+	if err := bus.Publish(ctx, "t", "k", []byte("overflow")); !errors.Is(err, ErrBufferFull) {
+		t.Fatalf("expected ErrBufferFull, got %v", err)
+	}
+	stats := bus.Topics()[0]
+	if stats.Rejected != 1 || stats.Published != 3 || stats.Subscriptions[0].Pending != 2 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	close(release)
+	shutdown(t, bus)
+	if err := bus.Publish(ctx, "t", "k", nil); !errors.Is(err, ErrBusClosed) {
+		t.Fatalf("expected ErrBusClosed, got %v", err)
+	}
+}
+
+func TestEventBusRetriesThenDropsPermanentFailures(t *testing.T) {
+	permanent := errors.New("bad payload")
+	bus := NewEventBus(BusOptions{Partitions: 1, MaxAttempts: 3, Backoff: time.Millisecond, MaxBackoff: time.Millisecond, Permanent: func(err error) bool { return errors.Is(err, permanent) }}, time.Now)
+	attempts := map[string]int{}
+	var mu sync.Mutex
+	_ = bus.Subscribe("t", "g", func(_ context.Context, msg BusMessage) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts[string(msg.Value)]++
+		switch {
+		case string(msg.Value) == "poison":
+			return permanent
+		case string(msg.Value) == "flaky" && attempts["flaky"] < 2:
+			return errors.New("not yet")
+		case string(msg.Value) == "broken":
+			return errors.New("still broken")
+		}
+		return nil
+	})
+	for _, v := range []string{"flaky", "poison", "broken", "ok"} {
+		_ = bus.Publish(context.Background(), "t", "k", []byte(v))
+	}
+	shutdown(t, bus)
+
+	if attempts["flaky"] != 2 || attempts["poison"] != 1 || attempts["broken"] != 3 || attempts["ok"] != 1 {
+		t.Fatalf("unexpected attempts %v", attempts)
+	}
+	sub := bus.Topics()[0].Subscriptions[0]
+	if sub.Processed != 2 || sub.DeadLettered != 2 || sub.Retried != 3 || sub.LastError != "still broken" {
+		t.Fatalf("unexpected stats %+v", sub)
+	}
+}
@@ -1,31 +1,35 @@
 package memory
 
 import (
-	"errors"
+	"context"
 	"sync"
 
 	"concert-booking/internal/domain/entity"
+	"concert-booking/internal/domain/repository"
 )
 
-var errMemoryNotFound = errors.New("not found")
+// errMemoryNotFound is the repository sentinel, so callers can tell a
+// missing row from a failure the same way for every backend.
+var errMemoryNotFound = repository.ErrNotFound
 
 type EventRepository struct {
-	mu     sync.RWMutex
-	events map[string]entity.Event
+	mu      sync.RWMutex
+	events  map[string]entity.Event
+	journal *Journal
 }
 
 func NewEventRepository() *EventRepository {
 	return &EventRepository{events: map[string]entity.Event{}}
 }
 
-func (r *EventRepository) Create(event entity.Event) error {
+func (r *EventRepository) Create(_ context.Context, event entity.Event) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	r.events[event.ID] = event
-	return nil
+	return r.journal.Append(opPut, event)
 }
 
-func (r *EventRepository) FindByID(id string) (entity.Event, error) {
+func (r *EventRepository) FindByID(_ context.Context, id string) (entity.Event, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	e, ok := r.events[id]
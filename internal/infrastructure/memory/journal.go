@@ -0,0 +1,271 @@
+package memory
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Journal is a per-store write-ahead log plus snapshot. Records are JSON
+// lines buffered in memory and made durable by Sync, which the Persistence
+// loop calls on a short interval so many mutations share one fsync.
+type Journal struct {
+	mu       sync.Mutex
+	walPath  string
+	snapPath string
+	file     *os.File
+	buf      *bufio.Writer
+	dirty    bool
+}
+
+type journalRecord struct {
+	Op   string          `json:"op"`
+	Data json.RawMessage `json:"data"`
+}
+
+func openJournal(dir, name string) (*Journal, error) {
+	walPath := filepath.Join(dir, name+".wal")
+	f, err := os.OpenFile(walPath, os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &Journal{walPath: walPath, snapPath: filepath.Join(dir, name+".snap"), file: f, buf: bufio.NewWriter(f)}, nil
+}
+
+// Append is a no-op on a nil journal so stores work unchanged without persistence.
+func (j *Journal) Append(op string, v any) error {
+	if j == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(journalRecord{Op: op, Data: data})
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.buf.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	j.dirty = true
+	return nil
+}
+
+func (j *Journal) Sync() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.syncLocked()
+}
+
+func (j *Journal) syncLocked() error {
+	if !j.dirty {
+		return nil
+	}
+	if err := j.buf.Flush(); err != nil {
+		return err
+	}
+	if err := j.file.Sync(); err != nil {
+		return err
+	}
+	j.dirty = false
+	return nil
+}
+
+// load feeds the snapshot and then every WAL record to the store. A torn final
+// line from a crash mid-write is dropped and truncated away.
+func (j *Journal) load(restore func([]byte) error, apply func(op string, data json.RawMessage) error) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	snap, err := os.ReadFile(j.snapPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if len(snap) > 0 {
+		if err := restore(snap); err != nil {
+			return fmt.Errorf("restore %s: %w", j.snapPath, err)
+		}
+	}
+	if _, err := j.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	reader := bufio.NewReader(j.file)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			if len(bytes.TrimSpace(line)) > 0 {
+				log.Printf("journal %s: dropping torn record at offset %d", j.walPath, offset)
+			}
+			break
+		}
+		if err != nil {
+			return err
+		}
+		var rec journalRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			log.Printf("journal %s: dropping corrupt tail at offset %d: %v", j.walPath, offset, err)
+			break
+		}
+		if err := apply(rec.Op, rec.Data); err != nil {
+			return fmt.Errorf("replay %s at offset %d: %w", j.walPath, offset, err)
+		}
+		offset += int64(len(line))
+	}
+	if err := j.file.Truncate(offset); err != nil {
+		return err
+	}
+	_, err = j.file.Seek(offset, io.SeekStart)
+	return err
+}
+
+// Compact replaces the snapshot with state and empties the WAL. Callers must
+// hold their store lock so no mutation slips in between.
+func (j *Journal) Compact(state []byte) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.syncLocked(); err != nil {
+		return err
+	}
+	tmp := j.snapPath + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(state); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, j.snapPath); err != nil {
+		return err
+	}
+	if dir, err := os.Open(filepath.Dir(j.snapPath)); err == nil {
+		_ = dir.Sync()
+		dir.Close()
+	}
+	if err := j.file.Truncate(0); err != nil {
+		return err
+	}
+	_, err = j.file.Seek(0, io.SeekStart)
+	return err
+}
+
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if err := j.syncLocked(); err != nil {
+		return err
+	}
+	return j.file.Close()
+}
+
+// Durable is implemented by every memory store that can be attached to a Persistence.
+type Durable interface {
+	restoreSnapshot(data []byte) error
+	applyRecord(op string, data json.RawMessage) error
+	setJournal(j *Journal)
+	compact() error
+}
+
+// Persistence makes the memory stores survive restarts by journaling every
+// mutation under a data directory.
+type Persistence struct {
+	dir      string
+	mu       sync.Mutex
+	journals []*Journal
+	stores   []Durable
+}
+
+func OpenPersistence(dir string) (*Persistence, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Persistence{dir: dir}, nil
+}
+
+// Attach replays the store's snapshot and WAL, then journals its future mutations.
+func (p *Persistence) Attach(name string, store Durable) error {
+	j, err := openJournal(p.dir, name)
+	if err != nil {
+		return err
+	}
+	if err := j.load(store.restoreSnapshot, store.applyRecord); err != nil {
+		j.file.Close()
+		return err
+	}
+	store.setJournal(j)
+	p.mu.Lock()
+	p.journals = append(p.journals, j)
+	p.stores = append(p.stores, store)
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *Persistence) Run(ctx context.Context, syncInterval, snapshotInterval time.Duration) {
+	syncTicker := time.NewTicker(syncInterval)
+	defer syncTicker.Stop()
+	snapTicker := time.NewTicker(snapshotInterval)
+	defer snapTicker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-syncTicker.C:
+			p.syncAll()
+		case <-snapTicker.C:
+			p.compactAll()
+		}
+	}
+}
+
+// Close writes a final snapshot of every store and closes the journals.
+func (p *Persistence) Close() error {
+	p.compactAll()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var firstErr error
+	for _, j := range p.journals {
+		if err := j.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (p *Persistence) syncAll() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, j := range p.journals {
+		if err := j.Sync(); err != nil {
+			log.Printf("journal sync %s: %v", j.walPath, err)
+		}
+	}
+}
+
+func (p *Persistence) compactAll() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, s := range p.stores {
+		if err := s.compact(); err != nil {
+			log.Printf("journal compact %s: %v", p.journals[i].walPath, err)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"concert-booking/internal/domain/entity"
+	"concert-booking/internal/domain/service"
+)
+
+func openStock(t *testing.T, dir string) (*Persistence, *StockService, *ReservationRepository) {
+	t.Helper()
+	p, err := OpenPersistence(dir)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	stock := NewStockService()
+	reservations := NewReservationRepository()
+	if err := p.Attach("stock", stock); err != nil {
+		t.Fatalf("attach stock: %v", err)
+	}
+	if err := p.Attach("reservations", reservations); err != nil {
+		t.Fatalf("attach reservations: %v", err)
+	}
+	return p, stock, reservations
+}
+
+func TestPersistenceReplaysWALAndSnapshot(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	p, stock, reservations := openStock(t, dir)
+	_ = stock.InitStock(ctx, "event-1", "VIP", 10)
+	_ = stock.Reserve(ctx, service.ReservationMeta{ReservationID: "res-1", UserID: "u1", EventID: "event-1", Category: "VIP", Qty: 3}, time.Hour)
+	_ = reservations.Upsert(ctx, entity.Reservation{ID: "res-1", EventID: "event-1", Category: "VIP", Qty: 3, Status: entity.ReservationStatusReserved})
+	p.compactAll()
+	_ = stock.Reserve(ctx, service.ReservationMeta{ReservationID: "res-2", UserID: "u2", EventID: "event-1", Category: "VIP", Qty: 2}, time.Millisecond)
+	_ = stock.ConfirmReservation(ctx, "res-1")
+	p.syncAll()
+
+	// Simulate a crash: no final snapshot, plus a torn record at the tail.
+	f, err := os.OpenFile(filepath.Join(dir, "stock.wal"), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("open wal: %v", err)
+	}
+	_, _ = f.WriteString(`{"op":"apply","data":{"stocks":[`)
+	_ = f.Close()
+
+	_, restored, restoredRes := openStock(t, dir)
+	stocks, _ := restored.GetStocks(ctx, "event-1", []string{"VIP"})
+	if stocks["VIP"] != 5 {
+		t.Fatalf("expected restored stock 5, got %d", stocks["VIP"])
+	}
+	if meta, err := restored.GetReservation(ctx, "res-1"); err != nil || meta.Status != "confirmed" {
+		t.Fatalf("expected confirmed res-1, got %+v (%v)", meta, err)
+	}
+	if r, err := restoredRes.FindByID(ctx, "res-1"); err != nil || r.Version != 1 {
+		t.Fatalf("expected persisted reservation, got %+v (%v)", r, err)
+	}
+	if pending, _ := restored.Pending(ctx, 10); len(pending) != 0 {
+		t.Fatalf("expected empty outbox, got %d", len(pending))
+	}
+	if entries, _ := restored.ReadLedger(ctx, 10); len(entries) != 4 {
+		t.Fatalf("expected 4 buffered ledger entries, got %d", len(entries))
+	}
+
+	// res-2 expired while down and is released on the first sweep.
+	released, err := restored.ReleaseExpired(ctx, time.Now().Add(time.Second), 10, nil)
+	if err != nil || len(released) != 1 || released[0].ReservationID != "res-2" {
+		t.Fatalf("expected res-2 released, got %+v (%v)", released, err)
+	}
+	stocks, _ = restored.GetStocks(ctx, "event-1", []string{"VIP"})
+	if stocks["VIP"] != 7 {
+		t.Fatalf("expected stock 7 after release, got %d", stocks["VIP"])
+	}
+}
+
+func TestJournalAppendWithoutPersistence(t *testing.T) {
+	var j *Journal
+	if err := j.Append(opPut, entity.Event{ID: "e"}); err != nil {
+		t.Fatalf("nil journal append: %v", err)
+	}
+	repo := NewEventRepository()
+	if err := repo.Create(context.Background(), entity.Event{ID: "e"}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := repo.FindByID(context.Background(), "missing"); !errors.Is(err, errMemoryNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
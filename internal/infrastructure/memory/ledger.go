@@ -0,0 +1,59 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"concert-booking/internal/domain/entity"
+	"concert-booking/internal/domain/service"
+)
+
+func (s *StockService) ReadLedger(_ context.Context, limit int) ([]entity.StockMovement, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.ledger)
+	if limit > 0 && n > limit {
+		n = limit
+	}
+	out := make([]entity.StockMovement, n)
+	copy(out, s.ledger[:n])
+	return out, nil
+}
+
+func (s *StockService) AckLedger(_ context.Context, ids ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.commitLocked()
+	s.pending.LedgerAck = append(s.pending.LedgerAck, ids...)
+	acked := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		acked[id] = struct{}{}
+	}
+	kept := s.ledger[:0]
+	for _, m := range s.ledger {
+		if _, ok := acked[m.ID]; !ok {
+			kept = append(kept, m)
+		}
+	}
+	s.ledger = kept
+	return nil
+}
+
+func (s *StockService) recordLocked(movement string, meta service.ReservationMeta, delta int) {
+	now := time.Now().UTC()
+	s.ledgerSeq++
+	m := entity.StockMovement{
+		ID:            fmt.Sprintf("%d-%d", now.UnixMilli(), s.ledgerSeq),
+		EventID:       meta.EventID,
+		Category:      meta.Category,
+		Type:          movement,
+		ReservationID: meta.ReservationID,
+		UserID:        meta.UserID,
+		Delta:         delta,
+		Balance:       s.stocks[stockKey{eventID: meta.EventID, category: meta.Category}],
+		CreatedAt:     now,
+	}
+	s.ledger = append(s.ledger, m)
+	s.pending.Ledger = append(s.pending.Ledger, m)
+}
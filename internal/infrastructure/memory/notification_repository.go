@@ -0,0 +1,100 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"concert-booking/internal/domain/entity"
+)
+
+type NotificationPreferenceRepository struct {
+	mu      sync.RWMutex
+	items   map[string]entity.NotificationPreference
+	journal *Journal
+}
+
+func NewNotificationPreferenceRepository() *NotificationPreferenceRepository {
+	return &NotificationPreferenceRepository{items: map[string]entity.NotificationPreference{}}
+}
+
+func (r *NotificationPreferenceRepository) Get(_ context.Context, userID string) (entity.NotificationPreference, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.items[userID]
+	if !ok {
+		return entity.NotificationPreference{}, errMemoryNotFound
+	}
+	return p, nil
+}
+
+func (r *NotificationPreferenceRepository) Upsert(_ context.Context, p entity.NotificationPreference) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items[p.UserID] = p
+	return r.journal.Append(opPut, p)
+}
+
+type NotificationRepository struct {
+	mu      sync.RWMutex
+	items   map[string]entity.Notification
+	byKey   map[string]string
+	journal *Journal
+}
+
+func NewNotificationRepository() *NotificationRepository {
+	return &NotificationRepository{items: map[string]entity.Notification{}, byKey: map[string]string{}}
+}
+
+func notificationKey(n entity.Notification) string {
+	return n.ReservationID + "|" + n.Kind + "|" + n.Channel
+}
+
+func (r *NotificationRepository) Create(_ context.Context, n entity.Notification) (entity.Notification, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id, ok := r.byKey[notificationKey(n)]; ok {
+		return r.items[id], false, nil
+	}
+	r.putLocked(n)
+	return n, true, r.journal.Append(opPut, n)
+}
+
+func (r *NotificationRepository) Update(_ context.Context, n entity.Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cur, ok := r.items[n.ID]
+	if !ok {
+		return errMemoryNotFound
+	}
+	cur.Recipient, cur.Status, cur.Attempts, cur.LastError = n.Recipient, n.Status, n.Attempts, n.LastError
+	cur.SentAt, cur.UpdatedAt = n.SentAt, n.UpdatedAt
+	r.items[n.ID] = cur
+	return r.journal.Append(opPut, cur)
+}
+
+func (r *NotificationRepository) ListByUser(_ context.Context, userID string, limit int) ([]entity.Notification, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]entity.Notification, 0)
+	for _, n := range r.items {
+		if n.UserID == userID {
+			out = append(out, n)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.After(out[j].CreatedAt)
+		}
+		return out[i].ID > out[j].ID
+	})
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (r *NotificationRepository) putLocked(n entity.Notification) {
+	r.items[n.ID] = n
+	r.byKey[notificationKey(n)] = n.ID
+}
@@ -0,0 +1,72 @@
+package memory
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"concert-booking/internal/domain/service"
+)
+
+func (s *StockService) Append(_ context.Context, events ...service.OutboxEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.commitLocked()
+	s.appendOutboxLocked(events)
+	return nil
+}
+
+func (s *StockService) Pending(_ context.Context, limit int) ([]service.OutboxEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.outbox)
+	if limit > 0 && n > limit {
+		n = limit
+	}
+	out := make([]service.OutboxEvent, n)
+	copy(out, s.outbox[:n])
+	return out, nil
+}
+
+func (s *StockService) Ack(_ context.Context, refs ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.commitLocked()
+	s.ackOutboxLocked(refs)
+	return nil
+}
+
+func (s *StockService) Park(_ context.Context, e service.ParkedEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.commitLocked()
+	s.ackOutboxLocked([]string{e.Ref})
+	s.parked = append(s.parked, e)
+	s.pending.Parked = append(s.pending.Parked, e)
+	return nil
+}
+
+// Parked returns the events the relay gave up on.
+func (s *StockService) Parked() []service.ParkedEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]service.ParkedEvent(nil), s.parked...)
+}
+
+func (s *StockService) ackOutboxLocked(refs []string) {
+	s.pending.OutboxAck = append(s.pending.OutboxAck, refs...)
+	s.outbox = withoutRefs(s.outbox, refs, func(e service.OutboxEvent) string { return e.Ref })
+}
+
+func (s *StockService) AcquireRelayLease(_ context.Context, _ string, _ time.Duration) (bool, error) {
+	return true, nil
+}
+
+func (s *StockService) appendOutboxLocked(events []service.OutboxEvent) {
+	for _, e := range events {
+		s.outboxSeq++
+		e.Ref = strconv.FormatInt(s.outboxSeq, 10)
+		s.outbox = append(s.outbox, e)
+		s.pending.Outbox = append(s.pending.Outbox, e)
+	}
+}
@@ -0,0 +1,38 @@
+package memory
+
+import (
+	"context"
+	"time"
+
+	"concert-booking/internal/domain/service"
+)
+
+func (s *StockService) ReservationSnapshot(_ context.Context) ([]service.ReservationMeta, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]service.ReservationMeta, 0, len(s.reservations))
+	for _, v := range s.reservations {
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+func (s *StockService) CompareAndSetStock(_ context.Context, eventID, category string, current, value int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.commitLocked()
+	k := stockKey{eventID: eventID, category: category}
+	if s.stocks[k] != current {
+		return false, nil
+	}
+	s.setStockLocked(k, value)
+	return true, nil
+}
+
+func (s *StockService) AcquireLock(_ context.Context, _, _ string, _ time.Duration) (bool, error) {
+	return true, nil
+}
+
+func (s *StockService) ReleaseLock(_ context.Context, _, _ string) error {
+	return nil
+}
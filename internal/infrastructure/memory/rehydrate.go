@@ -0,0 +1,34 @@
+package memory
+
+import (
+	"context"
+
+	"concert-booking/internal/domain/service"
+)
+
+func (s *StockService) MissingStock(_ context.Context, eventID string, categories []string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]string, 0)
+	for _, category := range categories {
+		if _, ok := s.stocks[stockKey{eventID: eventID, category: category}]; !ok {
+			out = append(out, category)
+		}
+	}
+	return out, nil
+}
+
+// PrepareStock is a no-op: the memory store keeps one counter per category.
+func (s *StockService) PrepareStock(context.Context, string, string, int) error { return nil }
+
+func (s *StockService) RestoreReservations(_ context.Context, reservations []service.ReservationMeta) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	defer s.commitLocked()
+	for _, meta := range reservations {
+		if _, ok := s.reservations[meta.ReservationID]; !ok {
+			s.putReservationLocked(meta)
+		}
+	}
+	return nil
+}
@@ -1,28 +1,36 @@
 package memory
 
 import (
+	"context"
 	"sync"
+	"time"
 
 	"concert-booking/internal/domain/entity"
+	"concert-booking/internal/domain/repository"
 )
 
 type ReservationRepository struct {
-	mu    sync.RWMutex
-	items map[string]entity.Reservation
+	mu      sync.RWMutex
+	items   map[string]entity.Reservation
+	journal *Journal
 }
 
 func NewReservationRepository() *ReservationRepository {
 	return &ReservationRepository{items: map[string]entity.Reservation{}}
 }
 
-func (r *ReservationRepository) Upsert(reservation entity.Reservation) error {
+func (r *ReservationRepository) Upsert(_ context.Context, reservation entity.Reservation) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	if _, ok := r.items[reservation.ID]; ok {
+		return nil
+	}
+	reservation.Version = 1
 	r.items[reservation.ID] = reservation
-	return nil
+	return r.journal.Append(opPut, reservation)
 }
 
-func (r *ReservationRepository) FindByID(id string) (entity.Reservation, error) {
+func (r *ReservationRepository) FindByID(_ context.Context, id string) (entity.Reservation, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	v, ok := r.items[id]
@@ -32,14 +40,33 @@ func (r *ReservationRepository) FindByID(id string) (entity.Reservation, error)
 	return v, nil
 }
 
-func (r *ReservationRepository) UpdateStatus(id, status string) error {
+func (r *ReservationRepository) Transition(_ context.Context, id, status string) (entity.Reservation, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	v, ok := r.items[id]
 	if !ok {
-		return errMemoryNotFound
+		return entity.Reservation{}, repository.ErrNotFound
+	}
+	if v.Status == status {
+		return v, nil
+	}
+	if err := entity.ValidateTransition(v.Status, status); err != nil {
+		return v, err
 	}
 	v.Status = status
+	v.Version++
 	r.items[id] = v
-	return nil
+	return v, r.journal.Append(opPut, v)
+}
+
+func (r *ReservationRepository) FindHolding(_ context.Context, now time.Time) ([]entity.Reservation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]entity.Reservation, 0)
+	for _, v := range r.items {
+		if v.Status == entity.ReservationStatusConfirmed || (v.Status == entity.ReservationStatusReserved && v.ExpiredAt.After(now)) {
+			out = append(out, v)
+		}
+	}
+	return out, nil
 }
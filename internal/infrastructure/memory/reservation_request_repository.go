@@ -0,0 +1,35 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"concert-booking/internal/domain/entity"
+)
+
+type ReservationRequestRepository struct {
+	mu      sync.RWMutex
+	items   map[string]entity.ReservationRequest
+	journal *Journal
+}
+
+func NewReservationRequestRepository() *ReservationRequestRepository {
+	return &ReservationRequestRepository{items: map[string]entity.ReservationRequest{}}
+}
+
+func (r *ReservationRequestRepository) Upsert(_ context.Context, req entity.ReservationRequest) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items[req.ID] = req
+	return r.journal.Append(opPut, req)
+}
+
+func (r *ReservationRequestRepository) Get(_ context.Context, id string) (entity.ReservationRequest, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	req, ok := r.items[id]
+	if !ok {
+		return entity.ReservationRequest{}, errMemoryNotFound
+	}
+	return req, nil
+}
@@ -0,0 +1,54 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"concert-booking/internal/domain/entity"
+)
+
+type StockLedgerRepository struct {
+	mu      sync.RWMutex
+	entries []entity.StockMovement
+	seen    map[string]struct{}
+	journal *Journal
+}
+
+func NewStockLedgerRepository() *StockLedgerRepository {
+	return &StockLedgerRepository{seen: map[string]struct{}{}}
+}
+
+func (r *StockLedgerRepository) Append(_ context.Context, movements ...entity.StockMovement) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	added := r.appendLocked(movements)
+	if len(added) == 0 {
+		return nil
+	}
+	return r.journal.Append(opPut, added)
+}
+
+func (r *StockLedgerRepository) appendLocked(movements []entity.StockMovement) []entity.StockMovement {
+	added := make([]entity.StockMovement, 0, len(movements))
+	for _, m := range movements {
+		if _, ok := r.seen[m.ID]; ok {
+			continue
+		}
+		r.seen[m.ID] = struct{}{}
+		r.entries = append(r.entries, m)
+		added = append(added, m)
+	}
+	return added
+}
+
+func (r *StockLedgerRepository) FindByCategory(_ context.Context, eventID, category string) ([]entity.StockMovement, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]entity.StockMovement, 0)
+	for _, m := range r.entries {
+		if m.EventID == eventID && m.Category == category {
+			out = append(out, m)
+		}
+	}
+	return out, nil
+}
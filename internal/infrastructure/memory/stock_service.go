@@ -5,6 +5,7 @@ import (
 	"sync"
 	"time"
 
+	"concert-booking/internal/domain/entity"
 	"concert-booking/internal/domain/service"
 )
 
@@ -17,6 +18,13 @@ type StockService struct {
 	mu           sync.Mutex
 	stocks       map[stockKey]int
 	reservations map[string]service.ReservationMeta
+	outbox       []service.OutboxEvent
+	outboxSeq    int64
+	parked       []service.ParkedEvent
+	ledger       []entity.StockMovement
+	ledgerSeq    int64
+	journal      *Journal
+	pending      stockMutation
 }
 
 func NewStockService() *StockService {
@@ -26,7 +34,9 @@ func NewStockService() *StockService {
 func (s *StockService) InitStock(_ context.Context, eventID, category string, total int) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.stocks[stockKey{eventID: eventID, category: category}] = total
+	defer s.commitLocked()
+	s.setStockLocked(stockKey{eventID: eventID, category: category}, total)
+	s.recordLocked(entity.MovementInit, service.ReservationMeta{EventID: eventID, Category: category}, total)
 	return nil
 }
 
@@ -40,41 +50,50 @@ func (s *StockService) GetStocks(_ context.Context, eventID string, categories [
 	return out, nil
 }
 
-func (s *StockService) Reserve(_ context.Context, meta service.ReservationMeta, ttl time.Duration) error {
+func (s *StockService) Reserve(_ context.Context, meta service.ReservationMeta, ttl time.Duration, events ...service.OutboxEvent) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	defer s.commitLocked()
 	k := stockKey{eventID: meta.EventID, category: meta.Category}
 	stock := s.stocks[k]
 	if stock < meta.Qty {
 		return service.ErrOutOfStock
 	}
-	s.stocks[k] = stock - meta.Qty
+	s.setStockLocked(k, stock-meta.Qty)
 	meta.Status = "reserved"
 	meta.ExpiredAt = time.Now().Add(ttl)
-	s.reservations[meta.ReservationID] = meta
+	s.putReservationLocked(meta)
+	s.recordLocked(entity.MovementReserve, meta, -meta.Qty)
+	s.appendOutboxLocked(events)
 	return nil
 }
 
 func (s *StockService) GetReservation(_ context.Context, reservationID string) (service.ReservationMeta, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	defer s.commitLocked()
 	v, ok := s.reservations[reservationID]
 	if !ok {
 		return service.ReservationMeta{}, service.ErrReservationNotFound
 	}
 	if time.Now().After(v.ExpiredAt) && v.Status == "reserved" {
 		k := stockKey{eventID: v.EventID, category: v.Category}
-		s.stocks[k] += v.Qty
+		s.setStockLocked(k, s.stocks[k]+v.Qty)
 		v.Status = "expired"
-		s.reservations[reservationID] = v
-		return service.ReservationMeta{}, service.ErrReservationNotFound
+		s.putReservationLocked(v)
+		s.recordLocked(entity.MovementExpire, v, v.Qty)
+		return service.ReservationMeta{}, service.ErrReservationReleased
+	}
+	if v.Status == "expired" {
+		return service.ReservationMeta{}, service.ErrReservationReleased
 	}
 	return v, nil
 }
 
-func (s *StockService) ConfirmReservation(_ context.Context, reservationID string) error {
+func (s *StockService) ConfirmReservation(_ context.Context, reservationID string, events ...service.OutboxEvent) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	defer s.commitLocked()
 	v, ok := s.reservations[reservationID]
 	if !ok {
 		return service.ErrReservationNotFound
@@ -83,13 +102,16 @@ func (s *StockService) ConfirmReservation(_ context.Context, reservationID strin
 		return service.ErrReservationFinalized
 	}
 	v.Status = "confirmed"
-	s.reservations[reservationID] = v
+	s.putReservationLocked(v)
+	s.recordLocked(entity.MovementConfirm, v, 0)
+	s.appendOutboxLocked(events)
 	return nil
 }
 
-func (s *StockService) ReleaseReservation(_ context.Context, reservationID string) (service.ReservationMeta, error) {
+func (s *StockService) ReleaseReservation(_ context.Context, reservationID string, events ...service.OutboxEvent) (service.ReservationMeta, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	defer s.commitLocked()
 	v, ok := s.reservations[reservationID]
 	if !ok {
 		return service.ReservationMeta{}, service.ErrReservationNotFound
@@ -98,25 +120,36 @@ func (s *StockService) ReleaseReservation(_ context.Context, reservationID strin
 		return service.ReservationMeta{}, service.ErrReservationFinalized
 	}
 	k := stockKey{eventID: v.EventID, category: v.Category}
-	s.stocks[k] += v.Qty
+	s.setStockLocked(k, s.stocks[k]+v.Qty)
 	v.Status = "expired"
-	s.reservations[reservationID] = v
+	s.putReservationLocked(v)
+	s.recordLocked(entity.MovementRelease, v, v.Qty)
+	s.appendOutboxLocked(events)
 	return v, nil
 }
 
-func (s *StockService) ReleaseExpired(_ context.Context, now time.Time, limit int) ([]service.ReservationMeta, error) {
+func (s *StockService) ReleaseExpired(_ context.Context, now time.Time, limit int, event service.ReleaseEvent) ([]service.ReservationMeta, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	out := make([]service.ReservationMeta, 0, limit)
-	for id, v := range s.reservations {
+	defer s.commitLocked()
+	var out []service.ReservationMeta
+	for _, v := range s.reservations {
 		if len(out) >= limit {
 			break
 		}
 		if v.Status == "reserved" && !v.ExpiredAt.After(now) {
-			k := stockKey{eventID: v.EventID, category: v.Category}
-			s.stocks[k] += v.Qty
 			v.Status = "expired"
-			s.reservations[id] = v
+			if event != nil {
+				e, err := event(v)
+				if err != nil {
+					return out, err
+				}
+				s.appendOutboxLocked([]service.OutboxEvent{e})
+			}
+			k := stockKey{eventID: v.EventID, category: v.Category}
+			s.setStockLocked(k, s.stocks[k]+v.Qty)
+			s.putReservationLocked(v)
+			s.recordLocked(entity.MovementExpire, v, v.Qty)
 			out = append(out, v)
 		}
 	}
@@ -0,0 +1,12 @@
+package memory
+
+import (
+	"testing"
+
+	"concert-booking/internal/domain/service"
+	"concert-booking/internal/infrastructure/stocktest"
+)
+
+func TestStockServiceBehavior(t *testing.T) {
+	stocktest.Run(t, func(t *testing.T) service.StockService { return NewStockService() })
+}
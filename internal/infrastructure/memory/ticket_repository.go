@@ -1,6 +1,7 @@
 package memory
 
 import (
+	"context"
 	"errors"
 	"sync"
 
@@ -13,6 +14,7 @@ type TicketCategoryRepository struct {
 	mu         sync.RWMutex
 	byEvent    map[string][]entity.TicketCategory
 	byEventKey map[string]entity.TicketCategory
+	journal    *Journal
 }
 
 func NewTicketCategoryRepository() *TicketCategoryRepository {
@@ -22,19 +24,18 @@ func NewTicketCategoryRepository() *TicketCategoryRepository {
 	}
 }
 
-func (r *TicketCategoryRepository) Create(category entity.TicketCategory) error {
+func (r *TicketCategoryRepository) Create(_ context.Context, category entity.TicketCategory) error {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	k := category.EventID + ":" + category.Name
 	if _, ok := r.byEventKey[k]; ok {
 		return errCategoryAlreadyExists
 	}
-	r.byEventKey[k] = category
-	r.byEvent[category.EventID] = append(r.byEvent[category.EventID], category)
-	return nil
+	r.putLocked(category)
+	return r.journal.Append(opPut, category)
 }
 
-func (r *TicketCategoryRepository) FindByEventID(eventID string) ([]entity.TicketCategory, error) {
+func (r *TicketCategoryRepository) FindByEventID(_ context.Context, eventID string) ([]entity.TicketCategory, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	items := r.byEvent[eventID]
@@ -43,7 +44,7 @@ func (r *TicketCategoryRepository) FindByEventID(eventID string) ([]entity.Ticke
 	return out, nil
 }
 
-func (r *TicketCategoryRepository) FindByEventAndName(eventID, name string) (entity.TicketCategory, error) {
+func (r *TicketCategoryRepository) FindByEventAndName(_ context.Context, eventID, name string) (entity.TicketCategory, error) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	k := eventID + ":" + name
@@ -53,3 +54,18 @@ func (r *TicketCategoryRepository) FindByEventAndName(eventID, name string) (ent
 	}
 	return c, nil
 }
+
+func (r *TicketCategoryRepository) List(_ context.Context) ([]entity.TicketCategory, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]entity.TicketCategory, 0, len(r.byEventKey))
+	for _, items := range r.byEvent {
+		out = append(out, items...)
+	}
+	return out, nil
+}
+
+func (r *TicketCategoryRepository) putLocked(category entity.TicketCategory) {
+	r.byEventKey[category.EventID+":"+category.Name] = category
+	r.byEvent[category.EventID] = append(r.byEvent[category.EventID], category)
+}
@@ -0,0 +1,198 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"concert-booking/internal/domain/entity"
+)
+
+type WebhookRepository struct {
+	mu      sync.RWMutex
+	items   map[string]entity.Webhook
+	journal *Journal
+}
+
+func NewWebhookRepository() *WebhookRepository {
+	return &WebhookRepository{items: map[string]entity.Webhook{}}
+}
+
+func (r *WebhookRepository) Create(_ context.Context, w entity.Webhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	w.EventTypes = slices.Clone(w.EventTypes)
+	r.items[w.ID] = w
+	return r.journal.Append(opPut, w)
+}
+
+func (r *WebhookRepository) FindByID(_ context.Context, id string) (entity.Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	w, ok := r.items[id]
+	if !ok {
+		return entity.Webhook{}, errMemoryNotFound
+	}
+	return w, nil
+}
+
+func (r *WebhookRepository) List(_ context.Context) ([]entity.Webhook, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]entity.Webhook, 0, len(r.items))
+	for _, w := range r.items {
+		out = append(out, w)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.Before(out[j].CreatedAt)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out, nil
+}
+
+func (r *WebhookRepository) Update(_ context.Context, w entity.Webhook) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cur, ok := r.items[w.ID]
+	if !ok {
+		return errMemoryNotFound
+	}
+	if w.Active && !cur.Active {
+		cur.ConsecutiveFailures = 0
+	}
+	if w.Active {
+		cur.DisabledReason = ""
+	}
+	cur.URL, cur.EventTypes, cur.Active, cur.UpdatedAt = w.URL, slices.Clone(w.EventTypes), w.Active, w.UpdatedAt
+	r.items[w.ID] = cur
+	return r.journal.Append(opPut, cur)
+}
+
+func (r *WebhookRepository) Delete(_ context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.items[id]; !ok {
+		return errMemoryNotFound
+	}
+	delete(r.items, id)
+	return r.journal.Append(opDelete, id)
+}
+
+func (r *WebhookRepository) RecordAttempt(_ context.Context, id string, ok bool, disableAfter int, reason string, at time.Time) (entity.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	w, found := r.items[id]
+	if !found {
+		return entity.Webhook{}, errMemoryNotFound
+	}
+	if ok {
+		w.ConsecutiveFailures = 0
+	} else {
+		w.ConsecutiveFailures++
+		if w.Active && w.ConsecutiveFailures >= disableAfter {
+			w.Active = false
+			w.DisabledReason = reason
+		}
+	}
+	w.UpdatedAt = at
+	r.items[id] = w
+	return w, r.journal.Append(opPut, w)
+}
+
+type WebhookDeliveryRepository struct {
+	mu      sync.RWMutex
+	items   map[string]entity.WebhookDelivery
+	byEvent map[string]string
+	journal *Journal
+}
+
+func NewWebhookDeliveryRepository() *WebhookDeliveryRepository {
+	return &WebhookDeliveryRepository{items: map[string]entity.WebhookDelivery{}, byEvent: map[string]string{}}
+}
+
+func deliveryEventKey(d entity.WebhookDelivery) string {
+	return d.WebhookID + "|" + d.EventID
+}
+
+func (r *WebhookDeliveryRepository) Create(_ context.Context, d entity.WebhookDelivery) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.byEvent[deliveryEventKey(d)]; ok {
+		return false, nil
+	}
+	r.putLocked(d)
+	return true, r.journal.Append(opPut, d)
+}
+
+func (r *WebhookDeliveryRepository) FindByID(_ context.Context, id string) (entity.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	d, ok := r.items[id]
+	if !ok {
+		return entity.WebhookDelivery{}, errMemoryNotFound
+	}
+	return d, nil
+}
+
+func (r *WebhookDeliveryRepository) ListByWebhook(_ context.Context, webhookID string, limit int) ([]entity.WebhookDelivery, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]entity.WebhookDelivery, 0)
+	for _, d := range r.items {
+		if d.WebhookID == webhookID {
+			out = append(out, d)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].CreatedAt.After(out[j].CreatedAt)
+		}
+		return out[i].ID > out[j].ID
+	})
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (r *WebhookDeliveryRepository) ClaimDue(_ context.Context, now time.Time, lease time.Duration, limit int) ([]entity.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	due := make([]entity.WebhookDelivery, 0)
+	for _, d := range r.items {
+		if d.Status == entity.DeliveryPending && !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].NextAttemptAt.Before(due[j].NextAttemptAt) })
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	for i := range due {
+		due[i].NextAttemptAt = now.Add(lease)
+		r.items[due[i].ID] = due[i]
+	}
+	return due, nil
+}
+
+func (r *WebhookDeliveryRepository) Update(_ context.Context, d entity.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cur, ok := r.items[d.ID]
+	if !ok {
+		return errMemoryNotFound
+	}
+	cur.Status, cur.Attempts, cur.ResponseStatus, cur.LastError = d.Status, d.Attempts, d.ResponseStatus, d.LastError
+	cur.NextAttemptAt, cur.DeliveredAt, cur.UpdatedAt = d.NextAttemptAt, d.DeliveredAt, d.UpdatedAt
+	r.items[d.ID] = cur
+	return r.journal.Append(opPut, cur)
+}
+
+func (r *WebhookDeliveryRepository) putLocked(d entity.WebhookDelivery) {
+	r.items[d.ID] = d
+	r.byEvent[deliveryEventKey(d)] = d.ID
+}
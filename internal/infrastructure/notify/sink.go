@@ -0,0 +1,112 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"concert-booking/internal/domain/entity"
+	"concert-booking/internal/domain/service"
+)
+
+// LogSender writes notifications to the process log instead of sending
+// them.
+type LogSender struct {
+	channel string
+}
+
+func NewLogSender(channel string) *LogSender {
+	return &LogSender{channel: channel}
+}
+
+func (s *LogSender) Send(_ context.Context, msg service.NotificationMessage) error {
+	log.Printf("notification %s to=%s subject=%q\n%s", s.channel, msg.To, msg.Subject, msg.Text)
+	return nil
+}
+
+// FileSender appends notifications to a file as JSON lines, so local
+// development and tests can inspect exactly what would have been sent.
+type FileSender struct {
+	mu      sync.Mutex
+	path    string
+	channel string
+	now     func() time.Time
+}
+
+func NewFileSender(path, channel string, now func() time.Time) *FileSender {
+	return &FileSender{path: path, channel: channel, now: now}
+}
+
+type fileRecord struct {
+	Channel string    `json:"channel"`
+	At      time.Time `json:"at"`
+	To      string    `json:"to"`
+	Subject string    `json:"subject,omitempty"`
+	Text    string    `json:"text"`
+	HTML    string    `json:"html,omitempty"`
+}
+
+func (s *FileSender) Send(_ context.Context, msg service.NotificationMessage) error {
+	line, err := json.Marshal(fileRecord{Channel: s.channel, At: s.now().UTC(), To: msg.To, Subject: msg.Subject, Text: msg.Text, HTML: msg.HTML})
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Options selects the sink of each channel: "smtp" (email only), "log",
+// "file" or "none".
+type Options struct {
+	EmailSink    string
+	SMSSink      string
+	File         string
+	SMTPAddr     string
+	SMTPUsername string
+	SMTPPassword string
+	From         string
+}
+
+// Channels builds the notifiers keyed by channel name. A channel with sink
+// "none" is left out, so users who opted into it get nothing on it. SMS has
+// no provider yet; one plugs in as another service.Notifier.
+func Channels(opts Options, now func() time.Time) (map[string]service.Notifier, error) {
+	out := map[string]service.Notifier{}
+	for _, ch := range []struct{ name, sink string }{{entity.ChannelEmail, opts.EmailSink}, {entity.ChannelSMS, opts.SMSSink}} {
+		switch ch.sink {
+		case "none", "":
+		case "log":
+			out[ch.name] = NewLogSender(ch.name)
+		case "file":
+			if opts.File == "" {
+				return nil, fmt.Errorf("%s sink file needs a path", ch.name)
+			}
+			out[ch.name] = NewFileSender(opts.File, ch.name, now)
+		case "smtp":
+			if ch.name != entity.ChannelEmail {
+				return nil, fmt.Errorf("smtp is not an %s sink", ch.name)
+			}
+			s, err := NewSMTPSender(opts.SMTPAddr, opts.SMTPUsername, opts.SMTPPassword, opts.From, now)
+			if err != nil {
+				return nil, err
+			}
+			out[ch.name] = s
+		default:
+			return nil, fmt.Errorf("unknown %s sink %q", ch.name, ch.sink)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,151 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"concert-booking/internal/domain/service"
+)
+
+// smtpStandIn accepts one SMTP session and records what it was told.
+type smtpStandIn struct {
+	addr     string
+	auth     string
+	from     string
+	rcpt     []string
+	data     []byte
+	received chan struct{}
+}
+
+func startSMTPStandIn(t *testing.T) *smtpStandIn {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+	s := &smtpStandIn{addr: ln.Addr().String(), received: make(chan struct{})}
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		defer close(s.received)
+		tp := textproto.NewConn(conn)
+		_ = tp.PrintfLine("220 standin ESMTP")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			verb, arg, _ := strings.Cut(line, " ")
+			switch strings.ToUpper(verb) {
+			case "EHLO", "HELO":
+				_ = tp.PrintfLine("250-standin")
+				_ = tp.PrintfLine("250 AUTH PLAIN")
+			case "AUTH":
+				_, cred, _ := strings.Cut(arg, " ")
+				raw, _ := base64.StdEncoding.DecodeString(cred)
+				s.auth = string(raw)
+				_ = tp.PrintfLine("235 ok")
+			case "MAIL":
+				s.from = arg
+				_ = tp.PrintfLine("250 ok")
+			case "RCPT":
+				s.rcpt = append(s.rcpt, arg)
+				_ = tp.PrintfLine("250 ok")
+			case "DATA":
+				_ = tp.PrintfLine("354 go ahead")
+				s.data, _ = tp.ReadDotBytes()
+				_ = tp.PrintfLine("250 queued")
+			case "QUIT":
+				_ = tp.PrintfLine("221 bye")
+				return
+			default:
+				_ = tp.PrintfLine("502 unsupported")
+			}
+		}
+	}()
+	return s
+}
+
+func TestSMTPSenderSendsMultipartMail(t *testing.T) {
+	standIn := startSMTPStandIn(t)
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	sender, err := NewSMTPSender(standIn.addr, "mailer", "pw", "Concert Tickets <tickets@concert.local>", func() time.Time { return at })
+	if err != nil {
+		t.Fatalf("new sender: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err = sender.Send(ctx, service.NotificationMessage{
+		To:      "budi@example.com",
+		Subject: "Tiket kamu terkonfirmasi ✓",
+		Text:    "Halo Budi,\nbooking kamu sudah terkonfirmasi.\n",
+		HTML:    "<p>Halo <b>Budi</b></p>",
+	})
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	<-standIn.received
+
+	if standIn.auth != "\x00mailer\x00pw" {
+		t.Fatalf("unexpected auth %q", standIn.auth)
+	}
+	if standIn.from != "FROM:<tickets@concert.local>" || len(standIn.rcpt) != 1 || standIn.rcpt[0] != "TO:<budi@example.com>" {
+		t.Fatalf("unexpected envelope from=%q rcpt=%v", standIn.from, standIn.rcpt)
+	}
+	msg, err := mail.ReadMessage(bufio.NewReader(strings.NewReader(string(standIn.data))))
+	if err != nil {
+		t.Fatalf("parse message: %v", err)
+	}
+	subject, _ := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if subject != "Tiket kamu terkonfirmasi ✓" || msg.Header.Get("To") != "<budi@example.com>" {
+		t.Fatalf("unexpected headers %v", msg.Header)
+	}
+	if date, _ := msg.Header.Date(); !date.Equal(at) {
+		t.Fatalf("unexpected date %v", date)
+	}
+	mediaType, params, _ := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if mediaType != "multipart/alternative" {
+		t.Fatalf("unexpected content type %s", mediaType)
+	}
+	parts := map[string]string{}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("next part: %v", err)
+		}
+		body, _ := io.ReadAll(p)
+		ct, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		parts[ct] = string(body)
+	}
+	if parts["text/plain"] != "Halo Budi,\nbooking kamu sudah terkonfirmasi.\n" || parts["text/html"] != "<p>Halo <b>Budi</b></p>" {
+		t.Fatalf("unexpected parts %q", parts)
+	}
+}
+
+func TestSMTPSenderRejectsBadRecipient(t *testing.T) {
+	sender, err := NewSMTPSender("127.0.0.1:1", "", "", "tickets@concert.local", time.Now)
+	if err != nil {
+		t.Fatalf("new sender: %v", err)
+	}
+	if err := sender.Send(context.Background(), service.NotificationMessage{To: "budi@example.com\r\nBcc: all@example.com"}); err == nil {
+		t.Fatal("expected header injection to be rejected")
+	}
+}
@@ -0,0 +1,156 @@
+package notify
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	texttemplate "text/template"
+
+	"concert-booking/internal/domain/service"
+)
+
+//go:embed templates
+var embedded embed.FS
+
+// Templates renders notifications from <locale>/<kind>.txt, which must
+// define a "subject" block and whose body is the plain text, and an
+// optional <locale>/<kind>.html for the HTML part of emails.
+type Templates struct {
+	fallback string
+	text     map[string]*texttemplate.Template
+	html     map[string]*htmltemplate.Template
+}
+
+// DefaultTemplates returns the templates shipped with the binary.
+func DefaultTemplates(fallback string) (*Templates, error) {
+	sub, err := fs.Sub(embedded, "templates")
+	if err != nil {
+		return nil, err
+	}
+	return ParseTemplates(sub, fallback)
+}
+
+// ParseTemplates loads every locale directory of fsys. The fallback locale
+// must exist; it is used for users whose locale has no template.
+func ParseTemplates(fsys fs.FS, fallback string) (*Templates, error) {
+	t := &Templates{fallback: normalizeLocale(fallback), text: map[string]*texttemplate.Template{}, html: map[string]*htmltemplate.Template{}}
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		locale, file := path.Split(name)
+		locale = normalizeLocale(strings.TrimSuffix(locale, "/"))
+		ext := path.Ext(file)
+		kind := strings.TrimSuffix(file, ext)
+		src, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		switch ext {
+		case ".txt":
+			tmpl, err := texttemplate.New(kind).Option("missingkey=error").Parse(string(src))
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			if tmpl.Lookup("subject") == nil {
+				return fmt.Errorf("%s: no subject block", name)
+			}
+			t.text[locale+"|"+kind] = tmpl
+		case ".html":
+			tmpl, err := htmltemplate.New(kind).Option("missingkey=error").Parse(string(src))
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			t.html[locale+"|"+kind] = tmpl
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for key := range t.html {
+		if _, ok := t.text[key]; !ok {
+			return nil, fmt.Errorf("%s.html has no matching .txt template", strings.Replace(key, "|", "/", 1))
+		}
+	}
+	if !t.hasLocale(t.fallback) {
+		return nil, fmt.Errorf("no templates for fallback locale %q", fallback)
+	}
+	return t, nil
+}
+
+func (t *Templates) Render(kind, locale string, data any) (service.NotificationMessage, error) {
+	key, ok := t.resolve(kind, locale)
+	if !ok {
+		return service.NotificationMessage{}, fmt.Errorf("no template for %s", kind)
+	}
+	var subject, text, html bytes.Buffer
+	tmpl := t.text[key]
+	if err := tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return service.NotificationMessage{}, err
+	}
+	if err := tmpl.Execute(&text, data); err != nil {
+		return service.NotificationMessage{}, err
+	}
+	if h, ok := t.html[key]; ok {
+		if err := h.Execute(&html, data); err != nil {
+			return service.NotificationMessage{}, err
+		}
+	}
+	return service.NotificationMessage{
+		// A subject is one header line whatever the template does.
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
+
+// Supports reports whether locale, or its base language, has templates.
+func (t *Templates) Supports(locale string) bool {
+	locale = normalizeLocale(locale)
+	return t.hasLocale(locale) || t.hasLocale(baseLanguage(locale))
+}
+
+// resolve tries the locale, its base language (id-ID -> id), then the
+// fallback.
+func (t *Templates) resolve(kind, locale string) (string, bool) {
+	locale = normalizeLocale(locale)
+	for _, l := range []string{locale, baseLanguage(locale), t.fallback} {
+		if _, ok := t.text[l+"|"+kind]; ok {
+			return l + "|" + kind, true
+		}
+	}
+	return "", false
+}
+
+func (t *Templates) hasLocale(locale string) bool {
+	for key := range t.text {
+		if strings.HasPrefix(key, locale+"|") {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+func baseLanguage(locale string) string {
+	base, _, _ := strings.Cut(locale, "-")
+	return base
+}
+
+// LoadTemplates reads templates from dir, or uses the embedded ones when dir
+// is empty.
+func LoadTemplates(dir, fallback string) (*Templates, error) {
+	if dir == "" {
+		return DefaultTemplates(fallback)
+	}
+	return ParseTemplates(os.DirFS(dir), fallback)
+}
@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"concert-booking/internal/domain/entity"
+)
+
+type templateData struct {
+	ReservationID string
+	BookingID     string
+	EventName     string
+	EventDate     time.Time
+	Category      string
+	Qty           int
+}
+
+func TestDefaultTemplatesRenderEveryKindAndLocale(t *testing.T) {
+	tmpl, err := DefaultTemplates("en")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	data := templateData{ReservationID: "res-1", BookingID: "bk-1", EventName: "Jazz <Night>", EventDate: time.Date(2026, 5, 1, 19, 0, 0, 0, time.UTC), Category: "VIP", Qty: 2}
+	for _, locale := range []string{"en", "id"} {
+		for _, kind := range []string{entity.NotificationBookingConfirmed, entity.NotificationReservationExpired} {
+			msg, err := tmpl.Render(kind, locale, data)
+			if err != nil {
+				t.Fatalf("%s/%s: %v", locale, kind, err)
+			}
+			if msg.Subject == "" || strings.Contains(msg.Subject, "\n") || !strings.Contains(msg.Text, "Jazz <Night>") {
+				t.Fatalf("%s/%s: unexpected text %+v", locale, kind, msg)
+			}
+			if !strings.Contains(msg.HTML, "Jazz &lt;Night&gt;") {
+				t.Fatalf("%s/%s: html must be escaped, got %s", locale, kind, msg.HTML)
+			}
+		}
+	}
+}
+
+func TestTemplatesFallBackToBaseLanguageThenDefault(t *testing.T) {
+	tmpl, err := ParseTemplates(fstest.MapFS{
+		"en/greet.txt": {Data: []byte(`{{define "subject"}}Hello{{end}}Hello {{.}}`)},
+		"id/greet.txt": {Data: []byte(`{{define "subject"}}Halo{{end}}Halo {{.}}`)},
+	}, "en")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	for locale, want := range map[string]string{"id_ID": "Halo", "id-ID": "Halo", "ID": "Halo", "fr": "Hello", "": "Hello"} {
+		msg, err := tmpl.Render("greet", locale, "Budi")
+		if err != nil || msg.Subject != want || msg.Text != want+" Budi\n" || msg.HTML != "" {
+			t.Fatalf("locale %q: got %+v err=%v", locale, msg, err)
+		}
+	}
+	if !tmpl.Supports("id-ID") || tmpl.Supports("fr") {
+		t.Fatal("unexpected Supports result")
+	}
+	if _, err := tmpl.Render("missing", "en", nil); err == nil {
+		t.Fatal("expected error for unknown kind")
+	}
+}
+
+func TestParseTemplatesValidates(t *testing.T) {
+	if _, err := ParseTemplates(fstest.MapFS{"en/greet.txt": {Data: []byte(`Hello`)}}, "en"); err == nil {
+		t.Fatal("expected error for a template without subject")
+	}
+	if _, err := ParseTemplates(fstest.MapFS{"id/greet.txt": {Data: []byte(`{{define "subject"}}Halo{{end}}`)}}, "en"); err == nil {
+		t.Fatal("expected error for a missing fallback locale")
+	}
+}
@@ -1,21 +1,27 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 
 	"concert-booking/internal/domain/entity"
+	"concert-booking/internal/domain/repository"
 )
 
 type BookingRepository struct {
-	db *sql.DB
+	db       *sql.DB
+	timeouts Timeouts
 }
 
-func NewBookingRepository(db *sql.DB) *BookingRepository {
-	return &BookingRepository{db: db}
+func NewBookingRepository(db *sql.DB, timeouts Timeouts) *BookingRepository {
+	return &BookingRepository{db: db, timeouts: timeouts}
 }
 
-func (r *BookingRepository) CreateIfNotExists(booking entity.Booking) (bool, error) {
-	row := r.db.QueryRow(`
+func (r *BookingRepository) CreateIfNotExists(ctx context.Context, booking entity.Booking) (bool, error) {
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+	row := r.db.QueryRowContext(ctx, `
 	INSERT INTO bookings(id, reservation_id, payment_status, created_at)
 	VALUES ($1,$2,$3,$4)
 	ON CONFLICT (reservation_id) DO NOTHING
@@ -27,14 +33,19 @@ func (r *BookingRepository) CreateIfNotExists(booking entity.Booking) (bool, err
 		return false, nil
 	}
 	if err != nil {
-		return false, err
+		return false, wrapErr(ctx, err)
 	}
 	return true, nil
 }
 
-func (r *BookingRepository) FindByReservationID(reservationID string) (entity.Booking, error) {
+func (r *BookingRepository) FindByReservationID(ctx context.Context, reservationID string) (entity.Booking, error) {
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
 	var b entity.Booking
-	err := r.db.QueryRow(`SELECT id, reservation_id, payment_status, created_at FROM bookings WHERE reservation_id=$1`, reservationID).
+	err := r.db.QueryRowContext(ctx, `SELECT id, reservation_id, payment_status, created_at FROM bookings WHERE reservation_id=$1`, reservationID).
 		Scan(&b.ID, &b.ReservationID, &b.PaymentStatus, &b.CreatedAt)
-	return b, err
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity.Booking{}, repository.ErrNotFound
+	}
+	return b, wrapErr(ctx, err)
 }
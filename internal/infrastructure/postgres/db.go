@@ -1,9 +1,14 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
 	"time"
 
+	"concert-booking/internal/domain/repository"
+
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
@@ -20,3 +25,47 @@ func NewDB(dsn string) (*sql.DB, error) {
 	}
 	return db, nil
 }
+
+// Timeouts bounds each repository call; zero means only the caller's context applies.
+type Timeouts struct {
+	Read  time.Duration
+	Write time.Duration
+}
+
+func (t Timeouts) read(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, t.Read)
+}
+
+func (t Timeouts) write(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, t.Write)
+}
+
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, d)
+}
+
+// wrapErr translates driver errors caused by context cancellation into the
+// repository sentinels so callers can tell them apart from real failures.
+func wrapErr(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	cause := ctx.Err()
+	if cause == nil {
+		switch {
+		case errors.Is(err, context.Canceled):
+			cause = context.Canceled
+		case errors.Is(err, context.DeadlineExceeded):
+			cause = context.DeadlineExceeded
+		default:
+			return err
+		}
+	}
+	if errors.Is(cause, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %v", repository.ErrQueryTimeout, err)
+	}
+	return fmt.Errorf("%w: %v", repository.ErrQueryCanceled, err)
+}
@@ -0,0 +1,29 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"concert-booking/internal/domain/repository"
+)
+
+func TestWrapErr(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := wrapErr(canceled, errors.New("conn closed")); !errors.Is(err, repository.ErrQueryCanceled) {
+		t.Fatalf("expected ErrQueryCanceled, got %v", err)
+	}
+
+	expired, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+	if err := wrapErr(expired, errors.New("conn closed")); !errors.Is(err, repository.ErrQueryTimeout) {
+		t.Fatalf("expected ErrQueryTimeout, got %v", err)
+	}
+
+	if err := wrapErr(context.Background(), sql.ErrNoRows); err != sql.ErrNoRows {
+		t.Fatalf("expected sql.ErrNoRows to pass through, got %v", err)
+	}
+}
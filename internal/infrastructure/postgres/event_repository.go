@@ -1,26 +1,37 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 
 	"concert-booking/internal/domain/entity"
+	"concert-booking/internal/domain/repository"
 )
 
 type EventRepository struct {
-	db *sql.DB
+	db       *sql.DB
+	timeouts Timeouts
 }
 
-func NewEventRepository(db *sql.DB) *EventRepository {
-	return &EventRepository{db: db}
+func NewEventRepository(db *sql.DB, timeouts Timeouts) *EventRepository {
+	return &EventRepository{db: db, timeouts: timeouts}
 }
 
-func (r *EventRepository) Create(event entity.Event) error {
-	_, err := r.db.Exec(`INSERT INTO events(id, name, date, created_at) VALUES ($1,$2,$3,$4)`, event.ID, event.Name, event.Date, event.CreatedAt)
-	return err
+func (r *EventRepository) Create(ctx context.Context, event entity.Event) error {
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+	_, err := r.db.ExecContext(ctx, `INSERT INTO events(id, name, date, created_at) VALUES ($1,$2,$3,$4)`, event.ID, event.Name, event.Date, event.CreatedAt)
+	return wrapErr(ctx, err)
 }
 
-func (r *EventRepository) FindByID(id string) (entity.Event, error) {
+func (r *EventRepository) FindByID(ctx context.Context, id string) (entity.Event, error) {
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
 	var out entity.Event
-	err := r.db.QueryRow(`SELECT id, name, date, created_at FROM events WHERE id=$1`, id).Scan(&out.ID, &out.Name, &out.Date, &out.CreatedAt)
-	return out, err
+	err := r.db.QueryRowContext(ctx, `SELECT id, name, date, created_at FROM events WHERE id=$1`, id).Scan(&out.ID, &out.Name, &out.Date, &out.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity.Event{}, repository.ErrNotFound
+	}
+	return out, wrapErr(ctx, err)
 }
@@ -0,0 +1,361 @@
+package postgres
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// migrationLockID is the pg_advisory_lock key shared by every replica running migrations.
+const migrationLockID int64 = 7_140_026
+
+var (
+	ErrChecksumMismatch = errors.New("migration checksum mismatch")
+	ErrUnknownVersion   = errors.New("unknown migration version")
+	ErrMissingDown      = errors.New("migration has no down script")
+)
+
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([a-zA-Z0-9_]+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	Dirty     bool
+}
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+func NewMigrator(db *sql.DB, files fs.FS) (*Migrator, error) {
+	migrations, err := LoadMigrations(files)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+func LoadMigrations(files fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Migration{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		m := migrationFilePattern.FindStringSubmatch(e.Name())
+		if m == nil {
+			continue
+		}
+		version, _ := strconv.ParseInt(m[1], 10, 64)
+		body, err := fs.ReadFile(files, e.Name())
+		if err != nil {
+			return nil, err
+		}
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mig
+		}
+		if mig.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %q and %q", version, mig.Name, m[2])
+		}
+		if m[3] == "up" {
+			mig.Up = string(body)
+			sum := sha256.Sum256(body)
+			mig.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			mig.Down = string(body)
+		}
+	}
+	out := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migration %d has no up script", mig.Version)
+		}
+		out = append(out, *mig)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	return m.To(ctx, m.Latest())
+}
+
+// Down rolls back the last steps applied migrations. Like To, it refuses to
+// run when an applied migration no longer matches its embedded file.
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	var applied int
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		rows, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.verify(rows); err != nil {
+			return err
+		}
+		versions := sortedVersions(rows)
+		for i := len(versions) - 1; i >= 0 && applied < steps; i-- {
+			if err := m.rollback(ctx, conn, versions[i]); err != nil {
+				return err
+			}
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// To migrates up or down until the schema matches the target version exactly.
+func (m *Migrator) To(ctx context.Context, target int64) (int, error) {
+	if target != 0 {
+		if _, ok := m.find(target); !ok {
+			return 0, fmt.Errorf("%w: %d", ErrUnknownVersion, target)
+		}
+	}
+	var applied int
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		rows, err := m.appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.verify(rows); err != nil {
+			return err
+		}
+		versions := sortedVersions(rows)
+		for i := len(versions) - 1; i >= 0 && versions[i] > target; i-- {
+			if err := m.rollback(ctx, conn, versions[i]); err != nil {
+				return err
+			}
+			applied++
+		}
+		for _, mig := range m.migrations {
+			if mig.Version > target {
+				break
+			}
+			if _, ok := rows[mig.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, mig); err != nil {
+				return err
+			}
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Baseline records every migration up to version as applied without executing it,
+// for databases that were initialised by hand before the runner existed.
+func (m *Migrator) Baseline(ctx context.Context, version int64) (int, error) {
+	if _, ok := m.find(version); !ok {
+		return 0, fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+	var recorded int
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		for _, mig := range m.migrations {
+			if mig.Version > version {
+				break
+			}
+			res, err := conn.ExecContext(ctx, `
+			INSERT INTO schema_migrations(version, name, checksum, applied_at)
+			VALUES ($1,$2,$3,NOW())
+			ON CONFLICT (version) DO NOTHING
+			`, mig.Version, mig.Name, mig.Checksum)
+			if err != nil {
+				return err
+			}
+			if n, _ := res.RowsAffected(); n > 0 {
+				recorded++
+			}
+		}
+		return nil
+	})
+	return recorded, err
+}
+
+// Status reads the applied versions without the migration lock, so it
+// answers while another replica is migrating; a migration in flight shows
+// up once its transaction commits.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var table sql.NullString
+	if err := m.db.QueryRowContext(ctx, `SELECT to_regclass('schema_migrations')::text`).Scan(&table); err != nil {
+		return nil, err
+	}
+	rows := map[int64]appliedMigration{}
+	if table.Valid {
+		var err error
+		if rows, err = m.appliedVersions(ctx, m.db); err != nil {
+			return nil, err
+		}
+	}
+	out := make([]MigrationStatus, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		st := MigrationStatus{Version: mig.Version, Name: mig.Name}
+		if row, ok := rows[mig.Version]; ok {
+			st.Applied = true
+			st.AppliedAt = row.appliedAt
+			st.Dirty = row.checksum != mig.Checksum
+			delete(rows, mig.Version)
+		}
+		out = append(out, st)
+	}
+	for version, row := range rows {
+		out = append(out, MigrationStatus{Version: version, Name: row.name, Applied: true, AppliedAt: row.appliedAt, Dirty: true})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+type appliedMigration struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockID); err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockID)
+	}()
+	if _, err := conn.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+	    version BIGINT PRIMARY KEY,
+	    name TEXT NOT NULL,
+	    checksum TEXT NOT NULL,
+	    applied_at TIMESTAMPTZ NOT NULL
+	)`); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+// queryer is a *sql.DB or a *sql.Conn.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func (m *Migrator) appliedVersions(ctx context.Context, q queryer) (map[int64]appliedMigration, error) {
+	rows, err := q.QueryContext(ctx, `SELECT version, name, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[int64]appliedMigration{}
+	for rows.Next() {
+		var (
+			version int64
+			row     appliedMigration
+		)
+		if err := rows.Scan(&version, &row.name, &row.checksum, &row.appliedAt); err != nil {
+			return nil, err
+		}
+		out[version] = row
+	}
+	return out, rows.Err()
+}
+
+func (m *Migrator) verify(rows map[int64]appliedMigration) error {
+	for version, row := range rows {
+		mig, ok := m.find(version)
+		if !ok {
+			return fmt.Errorf("%w: %d is applied but not embedded", ErrUnknownVersion, version)
+		}
+		if row.checksum != mig.Checksum {
+			return fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, mig.Version, mig.Name)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, mig Migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, mig.Up); err != nil {
+		return fmt.Errorf("apply %d_%s: %w", mig.Version, mig.Name, err)
+	}
+	if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations(version, name, checksum, applied_at) VALUES ($1,$2,$3,NOW())`, mig.Version, mig.Name, mig.Checksum); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *Migrator) rollback(ctx context.Context, conn *sql.Conn, version int64) error {
+	mig, ok := m.find(version)
+	if !ok {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+	if mig.Down == "" {
+		return fmt.Errorf("%w: %d_%s", ErrMissingDown, mig.Version, mig.Name)
+	}
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, mig.Down); err != nil {
+		return fmt.Errorf("rollback %d_%s: %w", mig.Version, mig.Name, err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version=$1`, mig.Version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (m *Migrator) find(version int64) (Migration, bool) {
+	for _, mig := range m.migrations {
+		if mig.Version == version {
+			return mig, true
+		}
+	}
+	return Migration{}, false
+}
+
+func sortedVersions(rows map[int64]appliedMigration) []int64 {
+	out := make([]int64, 0, len(rows))
+	for v := range rows {
+		out = append(out, v)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
@@ -0,0 +1,47 @@
+package postgres
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"concert-booking/migrations"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	files := fstest.MapFS{
+		"002_add_index.up.sql":   {Data: []byte("CREATE INDEX a ON t(x);")},
+		"002_add_index.down.sql": {Data: []byte("DROP INDEX a;")},
+		"001_init.up.sql":        {Data: []byte("CREATE TABLE t(x INT);")},
+		"README.md":              {Data: []byte("ignored")},
+	}
+	items, err := LoadMigrations(files)
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(items) != 2 || items[0].Version != 1 || items[1].Version != 2 {
+		t.Fatalf("unexpected migrations %+v", items)
+	}
+	if items[0].Down != "" || items[1].Down == "" {
+		t.Fatalf("unexpected down scripts %+v", items)
+	}
+	if items[0].Checksum == "" || items[0].Checksum == items[1].Checksum {
+		t.Fatalf("unexpected checksums %q %q", items[0].Checksum, items[1].Checksum)
+	}
+}
+
+func TestLoadMigrationsRequiresUp(t *testing.T) {
+	files := fstest.MapFS{"001_init.down.sql": {Data: []byte("DROP TABLE t;")}}
+	if _, err := LoadMigrations(files); err == nil {
+		t.Fatal("expected error for migration without up script")
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	items, err := LoadMigrations(migrations.FS)
+	if err != nil {
+		t.Fatalf("load embedded: %v", err)
+	}
+	if len(items) == 0 || items[0].Version != 1 {
+		t.Fatalf("expected embedded 001_init, got %+v", items)
+	}
+}
@@ -0,0 +1,120 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"concert-booking/internal/domain/entity"
+	"concert-booking/internal/domain/repository"
+)
+
+type NotificationPreferenceRepository struct {
+	db       *sql.DB
+	timeouts Timeouts
+}
+
+func NewNotificationPreferenceRepository(db *sql.DB, timeouts Timeouts) *NotificationPreferenceRepository {
+	return &NotificationPreferenceRepository{db: db, timeouts: timeouts}
+}
+
+func (r *NotificationPreferenceRepository) Get(ctx context.Context, userID string) (entity.NotificationPreference, error) {
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+	var p entity.NotificationPreference
+	err := r.db.QueryRowContext(ctx, `
+	SELECT user_id, email, phone, locale, email_enabled, sms_enabled, updated_at
+	FROM notification_preferences WHERE user_id=$1
+	`, userID).Scan(&p.UserID, &p.Email, &p.Phone, &p.Locale, &p.EmailEnabled, &p.SMSEnabled, &p.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity.NotificationPreference{}, repository.ErrNotFound
+	}
+	return p, wrapErr(ctx, err)
+}
+
+func (r *NotificationPreferenceRepository) Upsert(ctx context.Context, p entity.NotificationPreference) error {
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+	_, err := r.db.ExecContext(ctx, `
+	INSERT INTO notification_preferences(user_id, email, phone, locale, email_enabled, sms_enabled, updated_at)
+	VALUES ($1,$2,$3,$4,$5,$6,$7)
+	ON CONFLICT (user_id) DO UPDATE
+	SET email=EXCLUDED.email, phone=EXCLUDED.phone, locale=EXCLUDED.locale,
+	    email_enabled=EXCLUDED.email_enabled, sms_enabled=EXCLUDED.sms_enabled, updated_at=EXCLUDED.updated_at
+	`, p.UserID, p.Email, p.Phone, p.Locale, p.EmailEnabled, p.SMSEnabled, p.UpdatedAt)
+	return wrapErr(ctx, err)
+}
+
+type NotificationRepository struct {
+	db       *sql.DB
+	timeouts Timeouts
+}
+
+func NewNotificationRepository(db *sql.DB, timeouts Timeouts) *NotificationRepository {
+	return &NotificationRepository{db: db, timeouts: timeouts}
+}
+
+const notificationColumns = `id, user_id, reservation_id, kind, channel, recipient, status, attempts, last_error, sent_at, created_at, updated_at`
+
+func (r *NotificationRepository) Create(ctx context.Context, n entity.Notification) (entity.Notification, bool, error) {
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+	res, err := r.db.ExecContext(ctx, `
+	INSERT INTO notifications(`+notificationColumns+`)
+	VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)
+	ON CONFLICT (reservation_id, kind, channel) DO NOTHING
+	`, n.ID, n.UserID, n.ReservationID, n.Kind, n.Channel, n.Recipient, n.Status, n.Attempts, n.LastError, n.SentAt, n.CreatedAt, n.UpdatedAt)
+	if err != nil {
+		return entity.Notification{}, false, wrapErr(ctx, err)
+	}
+	if rows, _ := res.RowsAffected(); rows == 1 {
+		return n, true, nil
+	}
+	existing, err := scanNotification(r.db.QueryRowContext(ctx, `
+	SELECT `+notificationColumns+` FROM notifications WHERE reservation_id=$1 AND kind=$2 AND channel=$3
+	`, n.ReservationID, n.Kind, n.Channel))
+	return existing, false, wrapErr(ctx, err)
+}
+
+func (r *NotificationRepository) Update(ctx context.Context, n entity.Notification) error {
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+	res, err := r.db.ExecContext(ctx, `
+	UPDATE notifications SET recipient=$2, status=$3, attempts=$4, last_error=$5, sent_at=$6, updated_at=$7
+	WHERE id=$1
+	`, n.ID, n.Recipient, n.Status, n.Attempts, n.LastError, n.SentAt, n.UpdatedAt)
+	if err != nil {
+		return wrapErr(ctx, err)
+	}
+	if rows, _ := res.RowsAffected(); rows == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *NotificationRepository) ListByUser(ctx context.Context, userID string, limit int) ([]entity.Notification, error) {
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+	rows, err := r.db.QueryContext(ctx, `
+	SELECT `+notificationColumns+` FROM notifications WHERE user_id=$1 ORDER BY created_at DESC, id DESC LIMIT $2
+	`, userID, limit)
+	if err != nil {
+		return nil, wrapErr(ctx, err)
+	}
+	defer rows.Close()
+	out := make([]entity.Notification, 0)
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, wrapErr(ctx, err)
+		}
+		out = append(out, n)
+	}
+	return out, wrapErr(ctx, rows.Err())
+}
+
+func scanNotification(row rowScanner) (entity.Notification, error) {
+	var n entity.Notification
+	err := row.Scan(&n.ID, &n.UserID, &n.ReservationID, &n.Kind, &n.Channel, &n.Recipient, &n.Status, &n.Attempts, &n.LastError, &n.SentAt, &n.CreatedAt, &n.UpdatedAt)
+	return n, err
+}
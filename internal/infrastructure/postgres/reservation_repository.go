@@ -1,46 +1,117 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"time"
 
 	"concert-booking/internal/domain/entity"
+	"concert-booking/internal/domain/repository"
 )
 
+const maxTransitionAttempts = 3
+
 type ReservationRepository struct {
-	db *sql.DB
+	db       *sql.DB
+	timeouts Timeouts
 }
 
-func NewReservationRepository(db *sql.DB) *ReservationRepository {
-	return &ReservationRepository{db: db}
+func NewReservationRepository(db *sql.DB, timeouts Timeouts) *ReservationRepository {
+	return &ReservationRepository{db: db, timeouts: timeouts}
 }
 
-func (r *ReservationRepository) Upsert(reservation entity.Reservation) error {
-	_, err := r.db.Exec(`
-	INSERT INTO reservations(id, user_id, event_id, category, qty, status, expired_at, created_at)
-	VALUES ($1,$2,$3,$4,$5,$6,$7,$8)
+// Upsert never touches the status of an existing row. It only fills in the
+// details of a placeholder row left by the old status updates.
+func (r *ReservationRepository) Upsert(ctx context.Context, reservation entity.Reservation) error {
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+	_, err := r.db.ExecContext(ctx, `
+	INSERT INTO reservations(id, user_id, event_id, category, qty, status, version, expired_at, created_at)
+	VALUES ($1,$2,$3,$4,$5,$6,1,$7,$8)
 	ON CONFLICT (id) DO UPDATE SET
-	status = EXCLUDED.status,
-	expired_at = EXCLUDED.expired_at
+	user_id = EXCLUDED.user_id,
+	event_id = EXCLUDED.event_id,
+	category = EXCLUDED.category,
+	qty = EXCLUDED.qty,
+	expired_at = EXCLUDED.expired_at,
+	created_at = EXCLUDED.created_at
+	WHERE reservations.user_id = '' AND reservations.event_id = '' AND reservations.qty = 0
 	`, reservation.ID, reservation.UserID, reservation.EventID, reservation.Category, reservation.Qty, reservation.Status, reservation.ExpiredAt, reservation.CreatedAt)
-	return err
+	return wrapErr(ctx, err)
 }
 
-func (r *ReservationRepository) FindByID(id string) (entity.Reservation, error) {
-	var out entity.Reservation
-	err := r.db.QueryRow(`SELECT id, user_id, event_id, category, qty, status, expired_at, created_at FROM reservations WHERE id=$1`, id).
-		Scan(&out.ID, &out.UserID, &out.EventID, &out.Category, &out.Qty, &out.Status, &out.ExpiredAt, &out.CreatedAt)
-	return out, err
+func (r *ReservationRepository) FindByID(ctx context.Context, id string) (entity.Reservation, error) {
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+	out, err := r.findByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity.Reservation{}, repository.ErrNotFound
+	}
+	return out, wrapErr(ctx, err)
+}
+
+func (r *ReservationRepository) Transition(ctx context.Context, id, status string) (entity.Reservation, error) {
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+	for attempt := 0; attempt < maxTransitionAttempts; attempt++ {
+		current, err := r.findByID(ctx, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.Reservation{}, repository.ErrNotFound
+		}
+		if err != nil {
+			return entity.Reservation{}, wrapErr(ctx, err)
+		}
+		if current.Status == status {
+			return current, nil
+		}
+		if err := entity.ValidateTransition(current.Status, status); err != nil {
+			return current, err
+		}
+		res, err := r.db.ExecContext(ctx, `UPDATE reservations SET status=$3, version=version+1 WHERE id=$1 AND version=$2`, id, current.Version, status)
+		if err != nil {
+			return entity.Reservation{}, wrapErr(ctx, err)
+		}
+		if n, _ := res.RowsAffected(); n == 1 {
+			current.Status = status
+			current.Version++
+			return current, nil
+		}
+	}
+	return entity.Reservation{}, repository.ErrStaleVersion
 }
 
-func (r *ReservationRepository) UpdateStatus(id, status string) error {
-	_, err := r.db.Exec(`UPDATE reservations SET status=$2 WHERE id=$1`, id, status)
+func (r *ReservationRepository) FindHolding(ctx context.Context, now time.Time) ([]entity.Reservation, error) {
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+	rows, err := r.db.QueryContext(ctx, `
+	SELECT r.id, r.user_id, r.event_id, r.category, r.qty,
+	       CASE WHEN b.id IS NOT NULL THEN 'confirmed' ELSE r.status END,
+	       r.version, r.expired_at, r.created_at
+	FROM reservations r
+	LEFT JOIN bookings b ON b.reservation_id = r.id
+	WHERE r.status = 'confirmed'
+	   OR b.id IS NOT NULL
+	   OR (r.status = 'reserved' AND r.expired_at > $1)
+	`, now)
 	if err != nil {
-		return err
+		return nil, wrapErr(ctx, err)
+	}
+	defer rows.Close()
+	out := make([]entity.Reservation, 0)
+	for rows.Next() {
+		var res entity.Reservation
+		if err := rows.Scan(&res.ID, &res.UserID, &res.EventID, &res.Category, &res.Qty, &res.Status, &res.Version, &res.ExpiredAt, &res.CreatedAt); err != nil {
+			return nil, wrapErr(ctx, err)
+		}
+		out = append(out, res)
 	}
-	_, err = r.db.Exec(`
-	INSERT INTO reservations(id, user_id, event_id, category, qty, status, expired_at, created_at)
-	VALUES ($1,'','', '',0,$2,NOW(),NOW())
-	ON CONFLICT (id) DO NOTHING
-	`, id, status)
-	return err
+	return out, wrapErr(ctx, rows.Err())
+}
+
+func (r *ReservationRepository) findByID(ctx context.Context, id string) (entity.Reservation, error) {
+	var out entity.Reservation
+	err := r.db.QueryRowContext(ctx, `SELECT id, user_id, event_id, category, qty, status, version, expired_at, created_at FROM reservations WHERE id=$1`, id).
+		Scan(&out.ID, &out.UserID, &out.EventID, &out.Category, &out.Qty, &out.Status, &out.Version, &out.ExpiredAt, &out.CreatedAt)
+	return out, err
 }
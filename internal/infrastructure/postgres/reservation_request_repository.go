@@ -0,0 +1,46 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"concert-booking/internal/domain/entity"
+	"concert-booking/internal/domain/repository"
+)
+
+type ReservationRequestRepository struct {
+	db       *sql.DB
+	timeouts Timeouts
+}
+
+func NewReservationRequestRepository(db *sql.DB, timeouts Timeouts) *ReservationRequestRepository {
+	return &ReservationRequestRepository{db: db, timeouts: timeouts}
+}
+
+func (r *ReservationRequestRepository) Upsert(ctx context.Context, req entity.ReservationRequest) error {
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+	_, err := r.db.ExecContext(ctx, `
+	INSERT INTO reservation_requests(id, user_id, event_id, category, qty, status, reservation_id, reason, expired_at, created_at, updated_at)
+	VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
+	ON CONFLICT (id) DO UPDATE
+	SET status=EXCLUDED.status, reservation_id=EXCLUDED.reservation_id, reason=EXCLUDED.reason,
+	    expired_at=EXCLUDED.expired_at, updated_at=EXCLUDED.updated_at
+	`, req.ID, req.UserID, req.EventID, req.Category, req.Qty, req.Status, req.ReservationID, req.Reason, req.ExpiredAt, req.CreatedAt, req.UpdatedAt)
+	return wrapErr(ctx, err)
+}
+
+func (r *ReservationRequestRepository) Get(ctx context.Context, id string) (entity.ReservationRequest, error) {
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+	var req entity.ReservationRequest
+	err := r.db.QueryRowContext(ctx, `
+	SELECT id, user_id, event_id, category, qty, status, reservation_id, reason, expired_at, created_at, updated_at
+	FROM reservation_requests WHERE id=$1
+	`, id).Scan(&req.ID, &req.UserID, &req.EventID, &req.Category, &req.Qty, &req.Status, &req.ReservationID, &req.Reason, &req.ExpiredAt, &req.CreatedAt, &req.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity.ReservationRequest{}, repository.ErrNotFound
+	}
+	return req, wrapErr(ctx, err)
+}
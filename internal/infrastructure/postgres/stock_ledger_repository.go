@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"concert-booking/internal/domain/entity"
+)
+
+type StockLedgerRepository struct {
+	db       *sql.DB
+	timeouts Timeouts
+}
+
+func NewStockLedgerRepository(db *sql.DB, timeouts Timeouts) *StockLedgerRepository {
+	return &StockLedgerRepository{db: db, timeouts: timeouts}
+}
+
+func (r *StockLedgerRepository) Append(ctx context.Context, movements ...entity.StockMovement) error {
+	if len(movements) == 0 {
+		return nil
+	}
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return wrapErr(ctx, err)
+	}
+	defer tx.Rollback()
+	for _, m := range movements {
+		ms, seq := entity.SplitMovementID(m.ID)
+		if _, err := tx.ExecContext(ctx, `
+		INSERT INTO stock_ledger(id, stream_ms, stream_seq, event_id, category, movement, reservation_id, user_id, delta, balance, created_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
+		ON CONFLICT (id) DO NOTHING
+		`, m.ID, ms, seq, m.EventID, m.Category, m.Type, m.ReservationID, m.UserID, m.Delta, m.Balance, m.CreatedAt); err != nil {
+			return wrapErr(ctx, err)
+		}
+	}
+	return wrapErr(ctx, tx.Commit())
+}
+
+func (r *StockLedgerRepository) FindByCategory(ctx context.Context, eventID, category string) ([]entity.StockMovement, error) {
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+	rows, err := r.db.QueryContext(ctx, `
+	SELECT id, event_id, category, movement, reservation_id, user_id, delta, balance, created_at
+	FROM stock_ledger
+	WHERE event_id=$1 AND category=$2
+	ORDER BY stream_ms, stream_seq
+	`, eventID, category)
+	if err != nil {
+		return nil, wrapErr(ctx, err)
+	}
+	defer rows.Close()
+	out := make([]entity.StockMovement, 0)
+	for rows.Next() {
+		var m entity.StockMovement
+		if err := rows.Scan(&m.ID, &m.EventID, &m.Category, &m.Type, &m.ReservationID, &m.UserID, &m.Delta, &m.Balance, &m.CreatedAt); err != nil {
+			return nil, wrapErr(ctx, err)
+		}
+		out = append(out, m)
+	}
+	return out, wrapErr(ctx, rows.Err())
+}
@@ -0,0 +1,109 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"time"
+
+	"concert-booking/internal/domain/service"
+)
+
+const outboxLeaseName = "outbox_relay"
+
+func (s *StockService) Append(ctx context.Context, events ...service.OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	ctx, cancel := s.timeouts.write(ctx)
+	defer cancel()
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		return appendOutbox(ctx, tx, events)
+	})
+	return wrapErr(ctx, err)
+}
+
+func (s *StockService) Pending(ctx context.Context, limit int) ([]service.OutboxEvent, error) {
+	ctx, cancel := s.timeouts.read(ctx)
+	defer cancel()
+	rows, err := s.db.QueryContext(ctx, `SELECT seq, id, topic, key, payload FROM stock_outbox ORDER BY seq LIMIT $1`, limit)
+	if err != nil {
+		return nil, wrapErr(ctx, err)
+	}
+	defer rows.Close()
+	out := make([]service.OutboxEvent, 0, limit)
+	for rows.Next() {
+		var seq int64
+		var e service.OutboxEvent
+		if err := rows.Scan(&seq, &e.ID, &e.Topic, &e.Key, &e.Payload); err != nil {
+			return nil, wrapErr(ctx, err)
+		}
+		e.Ref = strconv.FormatInt(seq, 10)
+		out = append(out, e)
+	}
+	return out, wrapErr(ctx, rows.Err())
+}
+
+func (s *StockService) Ack(ctx context.Context, refs ...string) error {
+	if len(refs) == 0 {
+		return nil
+	}
+	seqs := make([]int64, 0, len(refs))
+	for _, ref := range refs {
+		if n, err := strconv.ParseInt(ref, 10, 64); err == nil {
+			seqs = append(seqs, n)
+		}
+	}
+	ctx, cancel := s.timeouts.write(ctx)
+	defer cancel()
+	_, err := s.db.ExecContext(ctx, `DELETE FROM stock_outbox WHERE seq = ANY($1)`, seqs)
+	return wrapErr(ctx, err)
+}
+
+// Park moves the event into stock_outbox_parked in the same transaction that
+// deletes it from the outbox.
+func (s *StockService) Park(ctx context.Context, e service.ParkedEvent) error {
+	seq, err := strconv.ParseInt(e.Ref, 10, 64)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := s.timeouts.write(ctx)
+	defer cancel()
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `DELETE FROM stock_outbox WHERE seq = $1`, seq)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return nil
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO stock_outbox_parked(seq, id, topic, key, payload, error, parked_at) VALUES ($1,$2,$3,$4,$5,$6,$7)`,
+			seq, e.ID, e.Topic, e.Key, e.Payload, e.Error, e.ParkedAt)
+		return err
+	})
+	return wrapErr(ctx, err)
+}
+
+func (s *StockService) AcquireRelayLease(ctx context.Context, owner string, ttl time.Duration) (bool, error) {
+	ctx, cancel := s.timeouts.write(ctx)
+	defer cancel()
+	res, err := s.db.ExecContext(ctx, `
+	INSERT INTO stock_leases(name, owner, expires_at) VALUES ($1, $2, now() + $3::bigint * interval '1 millisecond')
+	ON CONFLICT (name) DO UPDATE SET owner = EXCLUDED.owner, expires_at = EXCLUDED.expires_at
+	WHERE stock_leases.owner = EXCLUDED.owner OR stock_leases.expires_at < now()
+	`, outboxLeaseName, owner, ttl.Milliseconds())
+	if err != nil {
+		return false, wrapErr(ctx, err)
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+func appendOutbox(ctx context.Context, tx *sql.Tx, events []service.OutboxEvent) error {
+	for _, e := range events {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO stock_outbox(id, topic, key, payload) VALUES ($1,$2,$3,$4)`, e.ID, e.Topic, e.Key, e.Payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,296 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"time"
+
+	"concert-booking/internal/domain/entity"
+	"concert-booking/internal/domain/service"
+)
+
+// StockService keeps stock counters and reservation holds in Postgres for
+// deployments without Redis. Every operation is a single transaction, and the
+// ledger and outbox rows are written in that same transaction.
+type StockService struct {
+	db       *sql.DB
+	timeouts Timeouts
+}
+
+func NewStockService(db *sql.DB, timeouts Timeouts) *StockService {
+	return &StockService{db: db, timeouts: timeouts}
+}
+
+func (s *StockService) InitStock(ctx context.Context, eventID, category string, total int) error {
+	ctx, cancel := s.timeouts.write(ctx)
+	defer cancel()
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `
+		INSERT INTO stock_counters(event_id, category, remaining) VALUES ($1,$2,$3)
+		ON CONFLICT (event_id, category) DO NOTHING
+		`, eventID, category, total)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return nil
+		}
+		return insertMovement(ctx, tx, entity.MovementInit, service.ReservationMeta{EventID: eventID, Category: category}, total, total)
+	})
+	return wrapErr(ctx, err)
+}
+
+func (s *StockService) GetStocks(ctx context.Context, eventID string, categories []string) (map[string]int, error) {
+	out := make(map[string]int, len(categories))
+	if len(categories) == 0 {
+		return out, nil
+	}
+	ctx, cancel := s.timeouts.read(ctx)
+	defer cancel()
+	rows, err := s.db.QueryContext(ctx, `SELECT category, remaining FROM stock_counters WHERE event_id=$1 AND category = ANY($2)`, eventID, categories)
+	if err != nil {
+		return nil, wrapErr(ctx, err)
+	}
+	defer rows.Close()
+	for _, category := range categories {
+		out[category] = 0
+	}
+	for rows.Next() {
+		var category string
+		var remaining int
+		if err := rows.Scan(&category, &remaining); err != nil {
+			return nil, wrapErr(ctx, err)
+		}
+		out[category] = remaining
+	}
+	return out, wrapErr(ctx, rows.Err())
+}
+
+func (s *StockService) Reserve(ctx context.Context, meta service.ReservationMeta, ttl time.Duration, events ...service.OutboxEvent) error {
+	if meta.ExpiredAt.IsZero() {
+		meta.ExpiredAt = time.Now().Add(ttl)
+	}
+	ctx, cancel := s.timeouts.write(ctx)
+	defer cancel()
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		var balance int
+		err := tx.QueryRowContext(ctx, `
+		UPDATE stock_counters SET remaining = remaining - $3, updated_at = now()
+		WHERE event_id=$1 AND category=$2 AND remaining >= $3
+		RETURNING remaining
+		`, meta.EventID, meta.Category, meta.Qty).Scan(&balance)
+		if errors.Is(err, sql.ErrNoRows) {
+			return service.ErrOutOfStock
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `
+		INSERT INTO stock_reservations(id, user_id, event_id, category, qty, status, expired_at)
+		VALUES ($1,$2,$3,$4,$5,'reserved',$6)
+		`, meta.ReservationID, meta.UserID, meta.EventID, meta.Category, meta.Qty, meta.ExpiredAt); err != nil {
+			return err
+		}
+		if err := insertMovement(ctx, tx, entity.MovementReserve, meta, -meta.Qty, balance); err != nil {
+			return err
+		}
+		return appendOutbox(ctx, tx, events)
+	})
+	return wrapErr(ctx, err)
+}
+
+// GetReservation mirrors the Redis store: released holds and holds past their
+// expiry are reported as not found.
+func (s *StockService) GetReservation(ctx context.Context, reservationID string) (service.ReservationMeta, error) {
+	ctx, cancel := s.timeouts.read(ctx)
+	defer cancel()
+	meta := service.ReservationMeta{ReservationID: reservationID}
+	err := s.db.QueryRowContext(ctx, `
+	SELECT user_id, event_id, category, qty, status, expired_at FROM stock_reservations WHERE id=$1
+	`, reservationID).Scan(&meta.UserID, &meta.EventID, &meta.Category, &meta.Qty, &meta.Status, &meta.ExpiredAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return service.ReservationMeta{}, service.ErrReservationNotFound
+	}
+	if err != nil {
+		return service.ReservationMeta{}, wrapErr(ctx, err)
+	}
+	switch {
+	case meta.Status == entity.ReservationStatusConfirmed:
+		return meta, nil
+	case meta.Status == entity.ReservationStatusReserved && meta.ExpiredAt.After(time.Now()):
+		return meta, nil
+	}
+	return service.ReservationMeta{}, service.ErrReservationReleased
+}
+
+// ConfirmReservation and the release paths all flip the row with a
+// status='reserved' guard, so whichever commits first wins and the other
+// sees zero rows once the row lock is released.
+func (s *StockService) ConfirmReservation(ctx context.Context, reservationID string, events ...service.OutboxEvent) error {
+	ctx, cancel := s.timeouts.write(ctx)
+	defer cancel()
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		meta := service.ReservationMeta{ReservationID: reservationID}
+		err := tx.QueryRowContext(ctx, `
+		UPDATE stock_reservations SET status='confirmed'
+		WHERE id=$1 AND status='reserved'
+		RETURNING user_id, event_id, category
+		`, reservationID).Scan(&meta.UserID, &meta.EventID, &meta.Category)
+		if errors.Is(err, sql.ErrNoRows) {
+			return finalizedOrMissing(ctx, tx, reservationID)
+		}
+		if err != nil {
+			return err
+		}
+		var balance int
+		if err := tx.QueryRowContext(ctx, `SELECT remaining FROM stock_counters WHERE event_id=$1 AND category=$2`, meta.EventID, meta.Category).Scan(&balance); err != nil {
+			return err
+		}
+		if err := insertMovement(ctx, tx, entity.MovementConfirm, meta, 0, balance); err != nil {
+			return err
+		}
+		return appendOutbox(ctx, tx, events)
+	})
+	return wrapErr(ctx, err)
+}
+
+func (s *StockService) ReleaseReservation(ctx context.Context, reservationID string, events ...service.OutboxEvent) (service.ReservationMeta, error) {
+	ctx, cancel := s.timeouts.write(ctx)
+	defer cancel()
+	var meta service.ReservationMeta
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		meta = service.ReservationMeta{ReservationID: reservationID}
+		err := tx.QueryRowContext(ctx, `
+		UPDATE stock_reservations SET status='expired'
+		WHERE id=$1 AND status='reserved'
+		RETURNING user_id, event_id, category, qty, expired_at
+		`, reservationID).Scan(&meta.UserID, &meta.EventID, &meta.Category, &meta.Qty, &meta.ExpiredAt)
+		if errors.Is(err, sql.ErrNoRows) {
+			return finalizedOrMissing(ctx, tx, reservationID)
+		}
+		if err != nil {
+			return err
+		}
+		if err := restoreStock(ctx, tx, meta, entity.MovementRelease); err != nil {
+			return err
+		}
+		return appendOutbox(ctx, tx, events)
+	})
+	if err != nil {
+		return service.ReservationMeta{}, wrapErr(ctx, err)
+	}
+	meta.Status = entity.ReservationStatusExpired
+	return meta, nil
+}
+
+// ReleaseExpired claims due holds with SKIP LOCKED so several API instances
+// can reap concurrently without blocking on each other's batches.
+func (s *StockService) ReleaseExpired(ctx context.Context, now time.Time, limit int, event service.ReleaseEvent) ([]service.ReservationMeta, error) {
+	ctx, cancel := s.timeouts.write(ctx)
+	defer cancel()
+	var out []service.ReservationMeta
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `
+		WITH due AS (
+			SELECT id FROM stock_reservations
+			WHERE status='reserved' AND expired_at <= $1
+			ORDER BY expired_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE stock_reservations r SET status='expired'
+		FROM due WHERE r.id = due.id
+		RETURNING r.id, r.user_id, r.event_id, r.category, r.qty, r.expired_at
+		`, now, limit)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			meta := service.ReservationMeta{Status: entity.ReservationStatusExpired}
+			if err := rows.Scan(&meta.ReservationID, &meta.UserID, &meta.EventID, &meta.Category, &meta.Qty, &meta.ExpiredAt); err != nil {
+				rows.Close()
+				return err
+			}
+			out = append(out, meta)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		// Counters are always locked in the same order so concurrent reapers cannot deadlock.
+		sort.Slice(out, func(i, j int) bool {
+			if out[i].EventID != out[j].EventID {
+				return out[i].EventID < out[j].EventID
+			}
+			return out[i].Category < out[j].Category
+		})
+		for _, meta := range out {
+			if err := restoreStock(ctx, tx, meta, entity.MovementExpire); err != nil {
+				return err
+			}
+			if event == nil {
+				continue
+			}
+			e, err := event(meta)
+			if err != nil {
+				return err
+			}
+			if err := appendOutbox(ctx, tx, []service.OutboxEvent{e}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, wrapErr(ctx, err)
+	}
+	return out, nil
+}
+
+func (s *StockService) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func restoreStock(ctx context.Context, tx *sql.Tx, meta service.ReservationMeta, movement string) error {
+	var balance int
+	if err := tx.QueryRowContext(ctx, `
+	UPDATE stock_counters SET remaining = remaining + $3, updated_at = now()
+	WHERE event_id=$1 AND category=$2
+	RETURNING remaining
+	`, meta.EventID, meta.Category, meta.Qty).Scan(&balance); err != nil {
+		return err
+	}
+	return insertMovement(ctx, tx, movement, meta, meta.Qty, balance)
+}
+
+func finalizedOrMissing(ctx context.Context, tx *sql.Tx, reservationID string) error {
+	var exists bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM stock_reservations WHERE id=$1)`, reservationID).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return service.ErrReservationNotFound
+	}
+	return service.ErrReservationFinalized
+}
+
+// insertMovement writes a ledger row with an ID shaped like the Redis stream
+// IDs ("ms-seq") so both backends share the stock_ledger ordering.
+func insertMovement(ctx context.Context, tx *sql.Tx, movement string, meta service.ReservationMeta, delta, balance int) error {
+	_, err := tx.ExecContext(ctx, `
+	WITH s AS (SELECT (extract(epoch FROM clock_timestamp()) * 1000)::bigint AS ms, nextval('stock_ledger_seq') AS seq)
+	INSERT INTO stock_ledger(id, stream_ms, stream_seq, event_id, category, movement, reservation_id, user_id, delta, balance, created_at)
+	SELECT s.ms || '-' || s.seq, s.ms, s.seq, $1, $2, $3, $4, $5, $6, $7, now() FROM s
+	`, meta.EventID, meta.Category, movement, meta.ReservationID, meta.UserID, delta, balance)
+	return err
+}
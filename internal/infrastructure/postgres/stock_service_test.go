@@ -0,0 +1,37 @@
+package postgres
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"concert-booking/internal/domain/service"
+	"concert-booking/internal/infrastructure/stocktest"
+	"concert-booking/migrations"
+)
+
+// TEST_POSTGRES_DSN must point at a disposable database: the stock tables are truncated per subtest.
+func TestStockServiceBehavior(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN not set")
+	}
+	db, err := NewDB(dsn)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer db.Close()
+	migrator, err := NewMigrator(db, migrations.FS)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	stocktest.Run(t, func(t *testing.T) service.StockService {
+		if _, err := db.Exec(`TRUNCATE stock_counters, stock_reservations, stock_outbox, stock_leases`); err != nil {
+			t.Fatalf("truncate: %v", err)
+		}
+		return NewStockService(db, Timeouts{})
+	})
+}
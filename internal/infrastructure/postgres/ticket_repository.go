@@ -1,43 +1,61 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
 
 	"concert-booking/internal/domain/entity"
 )
 
 type TicketCategoryRepository struct {
-	db *sql.DB
+	db       *sql.DB
+	timeouts Timeouts
 }
 
-func NewTicketCategoryRepository(db *sql.DB) *TicketCategoryRepository {
-	return &TicketCategoryRepository{db: db}
+func NewTicketCategoryRepository(db *sql.DB, timeouts Timeouts) *TicketCategoryRepository {
+	return &TicketCategoryRepository{db: db, timeouts: timeouts}
 }
 
-func (r *TicketCategoryRepository) Create(category entity.TicketCategory) error {
-	_, err := r.db.Exec(`INSERT INTO ticket_categories(id, event_id, name, total_stock, price) VALUES ($1,$2,$3,$4,$5)`, category.ID, category.EventID, category.Name, category.TotalStock, category.Price)
-	return err
+func (r *TicketCategoryRepository) Create(ctx context.Context, category entity.TicketCategory) error {
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+	_, err := r.db.ExecContext(ctx, `INSERT INTO ticket_categories(id, event_id, name, total_stock, price) VALUES ($1,$2,$3,$4,$5)`, category.ID, category.EventID, category.Name, category.TotalStock, category.Price)
+	return wrapErr(ctx, err)
 }
 
-func (r *TicketCategoryRepository) FindByEventID(eventID string) ([]entity.TicketCategory, error) {
-	rows, err := r.db.Query(`SELECT id, event_id, name, total_stock, price FROM ticket_categories WHERE event_id=$1`, eventID)
+func (r *TicketCategoryRepository) FindByEventID(ctx context.Context, eventID string) ([]entity.TicketCategory, error) {
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+	return r.query(ctx, `SELECT id, event_id, name, total_stock, price FROM ticket_categories WHERE event_id=$1`, eventID)
+}
+
+func (r *TicketCategoryRepository) List(ctx context.Context) ([]entity.TicketCategory, error) {
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+	return r.query(ctx, `SELECT id, event_id, name, total_stock, price FROM ticket_categories ORDER BY event_id, name`)
+}
+
+func (r *TicketCategoryRepository) query(ctx context.Context, query string, args ...any) ([]entity.TicketCategory, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return nil, err
+		return nil, wrapErr(ctx, err)
 	}
 	defer rows.Close()
 	out := make([]entity.TicketCategory, 0)
 	for rows.Next() {
 		var c entity.TicketCategory
 		if err := rows.Scan(&c.ID, &c.EventID, &c.Name, &c.TotalStock, &c.Price); err != nil {
-			return nil, err
+			return nil, wrapErr(ctx, err)
 		}
 		out = append(out, c)
 	}
-	return out, rows.Err()
+	return out, wrapErr(ctx, rows.Err())
 }
 
-func (r *TicketCategoryRepository) FindByEventAndName(eventID, name string) (entity.TicketCategory, error) {
+func (r *TicketCategoryRepository) FindByEventAndName(ctx context.Context, eventID, name string) (entity.TicketCategory, error) {
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
 	var c entity.TicketCategory
-	err := r.db.QueryRow(`SELECT id, event_id, name, total_stock, price FROM ticket_categories WHERE event_id=$1 AND name=$2`, eventID, name).Scan(&c.ID, &c.EventID, &c.Name, &c.TotalStock, &c.Price)
-	return c, err
+	err := r.db.QueryRowContext(ctx, `SELECT id, event_id, name, total_stock, price FROM ticket_categories WHERE event_id=$1 AND name=$2`, eventID, name).Scan(&c.ID, &c.EventID, &c.Name, &c.TotalStock, &c.Price)
+	return c, wrapErr(ctx, err)
 }
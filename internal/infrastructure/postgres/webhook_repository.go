@@ -0,0 +1,247 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"concert-booking/internal/domain/entity"
+	"concert-booking/internal/domain/repository"
+)
+
+type WebhookRepository struct {
+	db       *sql.DB
+	timeouts Timeouts
+}
+
+func NewWebhookRepository(db *sql.DB, timeouts Timeouts) *WebhookRepository {
+	return &WebhookRepository{db: db, timeouts: timeouts}
+}
+
+const webhookColumns = `id, url, secret, event_types, active, disabled_reason, consecutive_failures, created_at, updated_at`
+
+func (r *WebhookRepository) Create(ctx context.Context, w entity.Webhook) error {
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+	types, err := json.Marshal(eventTypes(w.EventTypes))
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `
+	INSERT INTO webhooks(`+webhookColumns+`)
+	VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+	`, w.ID, w.URL, w.Secret, types, w.Active, w.DisabledReason, w.ConsecutiveFailures, w.CreatedAt, w.UpdatedAt)
+	return wrapErr(ctx, err)
+}
+
+func (r *WebhookRepository) FindByID(ctx context.Context, id string) (entity.Webhook, error) {
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+	w, err := scanWebhook(r.db.QueryRowContext(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE id=$1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity.Webhook{}, repository.ErrNotFound
+	}
+	return w, wrapErr(ctx, err)
+}
+
+func (r *WebhookRepository) List(ctx context.Context) ([]entity.Webhook, error) {
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+	rows, err := r.db.QueryContext(ctx, `SELECT `+webhookColumns+` FROM webhooks ORDER BY created_at, id`)
+	if err != nil {
+		return nil, wrapErr(ctx, err)
+	}
+	defer rows.Close()
+	out := make([]entity.Webhook, 0)
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, wrapErr(ctx, err)
+		}
+		out = append(out, w)
+	}
+	return out, wrapErr(ctx, rows.Err())
+}
+
+func (r *WebhookRepository) Update(ctx context.Context, w entity.Webhook) error {
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+	types, err := json.Marshal(eventTypes(w.EventTypes))
+	if err != nil {
+		return err
+	}
+	res, err := r.db.ExecContext(ctx, `
+	UPDATE webhooks SET url=$2, event_types=$3, active=$4, updated_at=$5,
+	       consecutive_failures = CASE WHEN $4 AND NOT active THEN 0 ELSE consecutive_failures END,
+	       disabled_reason = CASE WHEN $4 THEN '' ELSE disabled_reason END
+	WHERE id=$1
+	`, w.ID, w.URL, types, w.Active, w.UpdatedAt)
+	if err != nil {
+		return wrapErr(ctx, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *WebhookRepository) Delete(ctx context.Context, id string) error {
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+	res, err := r.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id=$1`, id)
+	if err != nil {
+		return wrapErr(ctx, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *WebhookRepository) RecordAttempt(ctx context.Context, id string, ok bool, disableAfter int, reason string, at time.Time) (entity.Webhook, error) {
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+	var row *sql.Row
+	if ok {
+		row = r.db.QueryRowContext(ctx, `
+		UPDATE webhooks SET consecutive_failures=0, updated_at=$2 WHERE id=$1
+		RETURNING `+webhookColumns, id, at)
+	} else {
+		row = r.db.QueryRowContext(ctx, `
+		UPDATE webhooks SET consecutive_failures = consecutive_failures + 1, updated_at=$2,
+		       active = active AND consecutive_failures + 1 < $3,
+		       disabled_reason = CASE WHEN active AND consecutive_failures + 1 >= $3 THEN $4 ELSE disabled_reason END
+		WHERE id=$1
+		RETURNING `+webhookColumns, id, at, disableAfter, reason)
+	}
+	w, err := scanWebhook(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity.Webhook{}, repository.ErrNotFound
+	}
+	return w, wrapErr(ctx, err)
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanWebhook(row rowScanner) (entity.Webhook, error) {
+	var (
+		w     entity.Webhook
+		types []byte
+	)
+	if err := row.Scan(&w.ID, &w.URL, &w.Secret, &types, &w.Active, &w.DisabledReason, &w.ConsecutiveFailures, &w.CreatedAt, &w.UpdatedAt); err != nil {
+		return entity.Webhook{}, err
+	}
+	if err := json.Unmarshal(types, &w.EventTypes); err != nil {
+		return entity.Webhook{}, err
+	}
+	return w, nil
+}
+
+// eventTypes keeps an empty filter as [] rather than null.
+func eventTypes(types []string) []string {
+	if types == nil {
+		return []string{}
+	}
+	return types
+}
+
+type WebhookDeliveryRepository struct {
+	db       *sql.DB
+	timeouts Timeouts
+}
+
+func NewWebhookDeliveryRepository(db *sql.DB, timeouts Timeouts) *WebhookDeliveryRepository {
+	return &WebhookDeliveryRepository{db: db, timeouts: timeouts}
+}
+
+const deliveryColumns = `id, webhook_id, event_id, event_type, payload, status, attempts, response_status, last_error, next_attempt_at, delivered_at, created_at, updated_at`
+
+func (r *WebhookDeliveryRepository) Create(ctx context.Context, d entity.WebhookDelivery) (bool, error) {
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+	res, err := r.db.ExecContext(ctx, `
+	INSERT INTO webhook_deliveries(`+deliveryColumns+`)
+	VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13)
+	ON CONFLICT (webhook_id, event_id) DO NOTHING
+	`, d.ID, d.WebhookID, d.EventID, d.EventType, d.Payload, d.Status, d.Attempts, d.ResponseStatus, d.LastError, d.NextAttemptAt, d.DeliveredAt, d.CreatedAt, d.UpdatedAt)
+	if err != nil {
+		return false, wrapErr(ctx, err)
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+func (r *WebhookDeliveryRepository) FindByID(ctx context.Context, id string) (entity.WebhookDelivery, error) {
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+	d, err := scanDelivery(r.db.QueryRowContext(ctx, `SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE id=$1`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return entity.WebhookDelivery{}, repository.ErrNotFound
+	}
+	return d, wrapErr(ctx, err)
+}
+
+func (r *WebhookDeliveryRepository) ListByWebhook(ctx context.Context, webhookID string, limit int) ([]entity.WebhookDelivery, error) {
+	ctx, cancel := r.timeouts.read(ctx)
+	defer cancel()
+	return r.query(ctx, `SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE webhook_id=$1 ORDER BY created_at DESC, id DESC LIMIT $2`, webhookID, limit)
+}
+
+func (r *WebhookDeliveryRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]entity.WebhookDelivery, error) {
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+	return r.query(ctx, `
+	UPDATE webhook_deliveries SET next_attempt_at=$2
+	WHERE id IN (
+		SELECT id FROM webhook_deliveries
+		WHERE status='pending' AND next_attempt_at <= $1
+		ORDER BY next_attempt_at
+		LIMIT $3
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING `+deliveryColumns, now, now.Add(lease), limit)
+}
+
+func (r *WebhookDeliveryRepository) Update(ctx context.Context, d entity.WebhookDelivery) error {
+	ctx, cancel := r.timeouts.write(ctx)
+	defer cancel()
+	res, err := r.db.ExecContext(ctx, `
+	UPDATE webhook_deliveries
+	SET status=$2, attempts=$3, response_status=$4, last_error=$5, next_attempt_at=$6, delivered_at=$7, updated_at=$8
+	WHERE id=$1
+	`, d.ID, d.Status, d.Attempts, d.ResponseStatus, d.LastError, d.NextAttemptAt, d.DeliveredAt, d.UpdatedAt)
+	if err != nil {
+		return wrapErr(ctx, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *WebhookDeliveryRepository) query(ctx context.Context, query string, args ...any) ([]entity.WebhookDelivery, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, wrapErr(ctx, err)
+	}
+	defer rows.Close()
+	out := make([]entity.WebhookDelivery, 0)
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, wrapErr(ctx, err)
+		}
+		out = append(out, d)
+	}
+	return out, wrapErr(ctx, rows.Err())
+}
+
+func scanDelivery(row rowScanner) (entity.WebhookDelivery, error) {
+	var d entity.WebhookDelivery
+	err := row.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Payload, &d.Status, &d.Attempts, &d.ResponseStatus, &d.LastError, &d.NextAttemptAt, &d.DeliveredAt, &d.CreatedAt, &d.UpdatedAt)
+	return d, err
+}
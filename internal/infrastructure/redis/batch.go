@@ -0,0 +1,220 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	"concert-booking/internal/domain/service"
+	"concert-booking/internal/observability/metrics"
+)
+
+// ReserveBatcher coalesces concurrent reserves for the same partition into a
+// single script call. A batch is flushed after window or once it holds
+// maxItems requests; the script applies them in arrival order, so each caller
+// gets the result it would have had on its own. Sharded categories already
+// spread their load and bypass the batcher.
+type ReserveBatcher struct {
+	*StockService
+	window   time.Duration
+	maxItems int
+
+	mu      sync.Mutex
+	pending map[string]*reserveBatch
+}
+
+type reserveBatch struct {
+	partition string
+	started   time.Time
+	items     []batchItem
+	timer     *time.Timer
+}
+
+type batchItem struct {
+	meta   service.ReservationMeta
+	ttl    time.Duration
+	events []service.OutboxEvent
+	done   chan error
+}
+
+func NewReserveBatcher(stock *StockService, maxItems int, window time.Duration) *ReserveBatcher {
+	if maxItems <= 0 {
+		maxItems = 1
+	}
+	metrics.SetReserveBatchBudget(maxItems, window)
+	return &ReserveBatcher{StockService: stock, window: window, maxItems: maxItems, pending: map[string]*reserveBatch{}}
+}
+
+// Reserve queues the request and waits for its batch. If ctx ends first the
+// request may still be applied; the hold then expires like any other.
+func (b *ReserveBatcher) Reserve(ctx context.Context, meta service.ReservationMeta, ttl time.Duration, events ...service.OutboxEvent) error {
+	n, err := b.shardCount(ctx, meta.EventID, meta.Category)
+	if err != nil {
+		return err
+	}
+	if n > 1 {
+		return b.StockService.Reserve(ctx, meta, ttl, events...)
+	}
+	item := batchItem{meta: meta, ttl: ttl, events: events, done: make(chan error, 1)}
+	b.enqueue(meta.EventID, item)
+	select {
+	case err := <-item.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *ReserveBatcher) enqueue(partition string, item batchItem) {
+	b.mu.Lock()
+	batch, ok := b.pending[partition]
+	if !ok {
+		batch = &reserveBatch{partition: partition, started: time.Now()}
+		b.pending[partition] = batch
+		batch.timer = time.AfterFunc(b.window, func() { b.flush(batch) })
+	}
+	batch.items = append(batch.items, item)
+	full := len(batch.items) >= b.maxItems
+	b.mu.Unlock()
+	if full {
+		b.flush(batch)
+	}
+}
+
+// flush runs a batch once; whichever of the timer and the size trigger gets
+// here first takes it out of pending.
+func (b *ReserveBatcher) flush(batch *reserveBatch) {
+	b.mu.Lock()
+	if b.pending[batch.partition] != batch {
+		b.mu.Unlock()
+		return
+	}
+	delete(b.pending, batch.partition)
+	batch.timer.Stop()
+	b.mu.Unlock()
+
+	metrics.ObserveReserveBatch(len(batch.items), time.Since(batch.started))
+	results, err := b.reserveBatch(context.Background(), batch.partition, batch.items)
+	for i, item := range batch.items {
+		if err != nil {
+			item.done <- err
+			continue
+		}
+		item.done <- results[i]
+	}
+}
+
+type batchArg struct {
+	ID        string       `json:"id"`
+	Qty       int          `json:"qty"`
+	Payload   string       `json:"payload"`
+	TTL       int64        `json:"ttl"`
+	EventID   string       `json:"event_id"`
+	Category  string       `json:"category"`
+	UserID    string       `json:"user_id"`
+	ExpAt     string       `json:"exp_at"`
+	Partition string       `json:"partition"`
+	Events    []batchEvent `json:"events"`
+}
+
+// batchEvent points at its payload's own ARGV entry: payloads may be
+// binary (protobuf), which JSON would not carry intact.
+type batchEvent struct {
+	ID      string `json:"id"`
+	Topic   string `json:"topic"`
+	Key     string `json:"key"`
+	Payload int    `json:"payload"`
+}
+
+// reserveBatch is the batched form of reserveIn: KEYS[1..4] are the shared
+// expiry set, outbox and ledger streams and pruned marker, followed by the
+// stock, hold and metadata keys of every item; ARGV holds one JSON document
+// per item, then the raw outbox payloads.
+func (s *StockService) reserveBatch(ctx context.Context, partition string, items []batchItem) ([]error, error) {
+	keys := []string{expirySetKey(partition), outboxStreamKey(partition), ledgerStreamKey(partition), prunedKey(partition)}
+	args := make([]any, len(items))
+	ids := make([]string, len(items))
+	pipe := s.client.Pipeline()
+	for i, item := range items {
+		meta := item.meta
+		payload, _ := json.Marshal(meta)
+		arg := batchArg{
+			ID:        meta.ReservationID,
+			Qty:       meta.Qty,
+			Payload:   string(payload),
+			TTL:       int64(item.ttl / time.Second),
+			EventID:   meta.EventID,
+			Category:  meta.Category,
+			UserID:    meta.UserID,
+			ExpAt:     strconv.FormatInt(meta.ExpiredAt.Unix(), 10),
+			Partition: partition,
+			Events:    make([]batchEvent, 0, len(item.events)),
+		}
+		for _, e := range item.events {
+			args = append(args, string(e.Payload))
+			arg.Events = append(arg.Events, batchEvent{ID: e.ID, Topic: e.Topic, Key: e.Key, Payload: len(args)})
+		}
+		encoded, _ := json.Marshal(arg)
+		args[i] = string(encoded)
+		ids[i] = meta.ReservationID
+		keys = append(keys, stockKey(partition, meta.Category), reservationKey(partition, meta.ReservationID), reservationMetaKey(partition, meta.ReservationID))
+		pipe.Set(ctx, reservationIndexKey(meta.ReservationID), partition+"\n"+meta.Category, 24*time.Hour)
+	}
+	eval := pipe.Eval(ctx, `
+local out = {}
+for i = 1, (#KEYS - 4) / 3 do
+  local item = cjson.decode(ARGV[i])
+  local base = 4 + (i - 1) * 3
+  local stock = tonumber(redis.call('GET', KEYS[base + 1]) or '0')
+  if stock < item.qty then
+    if stock <= 0 then
+      redis.call('PUBLISH', 'stock:soldout', item.event_id .. '\n' .. item.partition .. '\n' .. item.category .. '\n' .. '0')
+    end
+    out[i] = 0
+  else
+    local balance = redis.call('DECRBY', KEYS[base + 1], item.qty)
+    if balance == 0 then
+      redis.call('PUBLISH', 'stock:soldout', item.event_id .. '\n' .. item.partition .. '\n' .. item.category .. '\n' .. '0')
+    end
+    redis.call('XADD', KEYS[3], '*', 'type', 'reserve', 'event_id', item.event_id, 'category', item.category, 'reservation_id', item.id, 'user_id', item.user_id, 'delta', -item.qty, 'balance', balance)
+    redis.call('SET', KEYS[base + 2], item.payload, 'EX', item.ttl)
+    redis.call('HSET', KEYS[base + 3], 'event_id', item.event_id, 'category', item.category, 'qty', item.qty, 'user_id', item.user_id, 'status', 'reserved', 'expired_at', item.exp_at)
+    redis.call('EXPIRE', KEYS[base + 3], 86400)
+    redis.call('ZADD', KEYS[1], item.exp_at, item.id)
+    for _, e in ipairs(item.events) do
+      redis.call('XADD', KEYS[2], '*', 'id', e.id, 'topic', e.topic, 'key', e.key, 'payload', ARGV[e.payload])
+    end
+    out[i] = 1
+  end
+end
+table.insert(out, redis.call('EXISTS', KEYS[4]))
+return out
+`, keys, args...)
+	if _, err := pipe.Exec(ctx); err != nil {
+		s.dropIndexes(ctx, partition, ids...)
+		return nil, err
+	}
+	flags, err := eval.Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]error, len(items))
+	var rejected []string
+	for i, item := range items {
+		if i >= len(flags) || flags[i] != 1 {
+			out[i] = service.ErrOutOfStock
+			rejected = append(rejected, reservationIndexKey(item.meta.ReservationID))
+		}
+	}
+	for _, key := range rejected {
+		_ = s.client.Del(ctx, key).Err()
+	}
+	if len(rejected) < len(items) && len(flags) > len(items) && flags[len(items)] == 1 {
+		if err := s.listPartitions(ctx, partition); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,23 @@
+package redis
+
+import (
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// ClientOptions selects the deployment: MasterName set means Sentinel,
+// Cluster (or several addresses) means Redis Cluster, otherwise standalone.
+type ClientOptions struct {
+	Addrs      []string
+	Password   string
+	MasterName string
+	Cluster    bool
+}
+
+func NewClient(opts ClientOptions) goredis.UniversalClient {
+	return goredis.NewUniversalClient(&goredis.UniversalOptions{
+		Addrs:         opts.Addrs,
+		Password:      opts.Password,
+		MasterName:    opts.MasterName,
+		IsClusterMode: opts.Cluster,
+	})
+}
@@ -0,0 +1,118 @@
+package redis
+
+import (
+	"context"
+	"log"
+	"strings"
+
+	"concert-booking/internal/domain/entity"
+	"concert-booking/internal/domain/service"
+	"concert-booking/internal/observability/metrics"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+const expiredChannel = "__keyevent@*__:expired"
+
+// WatchExpired releases a hold as soon as Redis expires its
+// {ev:<partition>}:reservation:<id> key. Notifications are fire-and-forget
+// (lost while disconnected), so the reaper stays on as a safety net; both go
+// through the same status guard, so whichever runs second is a no-op.
+//
+// Keyspace notifications must include expired events (notify-keyspace-events
+// "Ex"); WatchExpired tries to enable them and only logs when it cannot, e.g.
+// on managed Redis where CONFIG is disabled. On Cluster every master publishes
+// its own events, so each one is subscribed to.
+func (s *StockService) WatchExpired(ctx context.Context, event service.ReleaseEvent, fn func(service.ReservationMeta)) error {
+	if cluster, ok := s.client.(*goredis.ClusterClient); ok {
+		return cluster.ForEachMaster(ctx, func(ctx context.Context, node *goredis.Client) error {
+			return s.watchNode(ctx, node, event, fn)
+		})
+	}
+	return s.watchNode(ctx, s.client, event, fn)
+}
+
+func (s *StockService) watchNode(ctx context.Context, client goredis.UniversalClient, event service.ReleaseEvent, fn func(service.ReservationMeta)) error {
+	if err := client.ConfigSet(ctx, "notify-keyspace-events", "Ex").Err(); err != nil {
+		log.Printf("enable keyspace notifications: %v (expects notify-keyspace-events to include Ex)", err)
+	}
+	sub := client.PSubscribe(ctx, expiredChannel)
+	defer sub.Close()
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			partition, id, ok := parseReservationKey(msg.Payload)
+			if !ok {
+				continue
+			}
+			meta, released, err := s.expireReservation(ctx, partition, id, event)
+			if err != nil {
+				log.Printf("expire reservation %s: %v", id, err)
+				continue
+			}
+			metrics.IncExpiryNotification(released)
+			if released {
+				fn(meta)
+			}
+		}
+	}
+}
+
+// expireReservation releases one hold whose TTL key is gone. It is the
+// single-ID form of the reaper script, guarded by the same status check.
+// The metadata is read first so the script can declare the stock key and
+// carry the expired event.
+func (s *StockService) expireReservation(ctx context.Context, partition, id string, event service.ReleaseEvent) (service.ReservationMeta, bool, error) {
+	hash, err := s.client.HGetAll(ctx, reservationMetaKey(partition, id)).Result()
+	if err != nil {
+		return service.ReservationMeta{}, false, err
+	}
+	if len(hash) == 0 {
+		return service.ReservationMeta{}, false, nil
+	}
+	current := metaFromHash(id, hash)
+	e, err := releaseEvent(event, current)
+	if err != nil {
+		return service.ReservationMeta{}, false, err
+	}
+	res, err := s.client.Eval(ctx, `
+local meta = redis.call('HMGET', KEYS[1], 'status', 'event_id', 'category', 'qty', 'user_id', 'expired_at')
+if meta[1] ~= 'reserved' then
+  return {}
+end
+redis.call('HSET', KEYS[1], 'status', 'expired')
+local balance = redis.call('INCRBY', KEYS[4], meta[4])
+redis.call('ZREM', KEYS[2], ARGV[1])
+redis.call('XADD', KEYS[3], '*', 'type', ARGV[2], 'event_id', meta[2], 'category', meta[3], 'reservation_id', ARGV[1], 'user_id', meta[5], 'delta', meta[4], 'balance', balance)
+if balance == tonumber(meta[4]) then
+  redis.call('PUBLISH', 'stock:soldout', meta[2] .. '\n' .. ARGV[3] .. '\n' .. meta[3] .. '\n' .. '1')
+end
+if ARGV[4] ~= '' then
+  redis.call('XADD', KEYS[5], '*', 'id', ARGV[4], 'topic', ARGV[5], 'key', ARGV[6], 'payload', ARGV[7])
+end
+return {meta[2], meta[3], meta[4], meta[5], meta[6]}
+`, []string{reservationMetaKey(partition, id), expirySetKey(partition), ledgerStreamKey(partition), stockKey(partition, current.Category), outboxStreamKey(partition)},
+		id, entity.MovementExpire, partition, e.ID, e.Topic, e.Key, string(e.Payload)).StringSlice()
+	if err != nil || len(res) < 5 {
+		return service.ReservationMeta{}, false, err
+	}
+	meta := metaFromHash(id, map[string]string{"event_id": res[0], "category": res[1], "qty": res[2], "user_id": res[3], "expired_at": res[4]})
+	meta.Status = "expired"
+	return meta, true, nil
+}
+
+// parseReservationKey splits "{ev:<partition>}:reservation:<id>".
+func parseReservationKey(key string) (partition, id string, ok bool) {
+	rest, found := strings.CutPrefix(key, "{ev:")
+	if !found {
+		return "", "", false
+	}
+	partition, id, ok = strings.Cut(rest, "}:reservation:")
+	return partition, id, ok && partition != "" && id != ""
+}
@@ -0,0 +1,105 @@
+package redis
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Every key a script touches carries its partition's hash tag, so a single
+// EVAL never spans slots on Redis Cluster. A partition is an event ID, or
+// "event#n" for shard n > 0 of a sharded category. Only the partition
+// registry, shard layout, reservation index and leases live outside them.
+
+func partitionTag(partition string) string { return "{ev:" + partition + "}" }
+
+func stockKey(partition, category string) string {
+	return partitionTag(partition) + ":stock:" + category
+}
+func reservationKey(partition, id string) string {
+	return partitionTag(partition) + ":reservation:" + id
+}
+func reservationMetaKey(partition, id string) string {
+	return partitionTag(partition) + ":reservation_meta:" + id
+}
+func expirySetKey(partition string) string    { return partitionTag(partition) + ":expiries" }
+func outboxStreamKey(partition string) string { return partitionTag(partition) + ":outbox" }
+func ledgerStreamKey(partition string) string { return partitionTag(partition) + ":ledger" }
+func parkedStreamKey(partition string) string { return partitionTag(partition) + ":outbox_parked" }
+func reaperLeaseKey(partition string) string  { return partitionTag(partition) + ":reaper_lease" }
+func prunedKey(partition string) string       { return partitionTag(partition) + ":pruned" }
+
+// reservationIndexKey maps a reservation ID to "partition\ncategory" so
+// callers that only know the ID can find the slot its keys live in.
+func reservationIndexKey(id string) string { return "reservation_event:" + id }
+func partitionsKey() string                { return "stock:partitions" }
+func shardLayoutKey() string               { return "stock:shards" }
+func outboxLeaseKey() string               { return "outbox:relay_lease" }
+
+// streamRef identifies an entry in one partition's stream as "partition:streamID".
+func streamRef(partition, id string) string { return partition + ":" + id }
+
+func splitStreamRef(ref string) (partition, id string) {
+	i := strings.LastIndex(ref, ":")
+	if i < 0 {
+		return "", ref
+	}
+	return ref[:i], ref[i+1:]
+}
+
+// groupRefs buckets stream refs by partition so each stream is touched once.
+func groupRefs(refs []string) map[string][]string {
+	out := map[string][]string{}
+	for _, ref := range refs {
+		partition, id := splitStreamRef(ref)
+		out[partition] = append(out[partition], id)
+	}
+	return out
+}
+
+// scanKeys walks keys matching pattern on every master, since SCAN on a
+// cluster client only visits a single node. fn may be called concurrently.
+func scanKeys(ctx context.Context, client goredis.UniversalClient, pattern string, fn func(keys []string) error) error {
+	scan := func(ctx context.Context, c goredis.Cmdable) error {
+		var cursor uint64
+		for {
+			keys, next, err := c.Scan(ctx, cursor, pattern, 1000).Result()
+			if err != nil {
+				return err
+			}
+			if len(keys) > 0 {
+				if err := fn(keys); err != nil {
+					return err
+				}
+			}
+			if next == 0 {
+				return nil
+			}
+			cursor = next
+		}
+	}
+	if cluster, ok := client.(*goredis.ClusterClient); ok {
+		return cluster.ForEachMaster(ctx, func(ctx context.Context, node *goredis.Client) error {
+			return scan(ctx, node)
+		})
+	}
+	return scan(ctx, client)
+}
+
+func (s *StockService) partitions(ctx context.Context) ([]string, error) {
+	return s.client.SMembers(ctx, partitionsKey()).Result()
+}
+
+// lockedAppend collects results from concurrent scanKeys callbacks.
+type lockedAppend[T any] struct {
+	mu    sync.Mutex
+	items []T
+}
+
+func (l *lockedAppend[T]) add(items ...T) {
+	l.mu.Lock()
+	l.items = append(l.items, items...)
+	l.mu.Unlock()
+}
@@ -0,0 +1,50 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// reaperLeaseTTL outlives several reaper ticks, so a replica keeps the
+// partitions it reaps and a dead one is replaced after at most this long.
+const reaperLeaseTTL = 10 * time.Second
+
+func (s *StockService) AcquireLock(ctx context.Context, name, owner string, ttl time.Duration) (bool, error) {
+	return s.acquireLease(ctx, lockKey(name), owner, ttl)
+}
+
+func (s *StockService) ReleaseLock(ctx context.Context, name, owner string) error {
+	return s.client.Eval(ctx, `
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('DEL', KEYS[1])
+end
+return 0
+`, []string{lockKey(name)}, owner).Err()
+}
+
+// acquireLease takes or renews a lease held by owner; other owners are refused until it lapses.
+func (s *StockService) acquireLease(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	res, err := s.client.Eval(ctx, `
+local current = redis.call('GET', KEYS[1])
+if current == false or current == ARGV[1] then
+  redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+  return 1
+end
+return 0
+`, []string{key}, owner, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return res == 1, nil
+}
+
+func lockKey(name string) string { return "lock:" + name }
+
+// newOwner identifies this process in the leases it takes.
+func newOwner() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,50 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"concert-booking/internal/domain/entity"
+)
+
+// Ledger entries are appended to the partition's stream by the same Lua
+// scripts that move stock and removed once the worker has stored them in
+// Postgres. Movement IDs are "partition:streamID" because stream IDs repeat
+// across partitions.
+
+func (s *StockService) ReadLedger(ctx context.Context, limit int) ([]entity.StockMovement, error) {
+	msgs, err := s.readStreams(ctx, ledgerStreamKey, limit)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]entity.StockMovement, 0, len(msgs))
+	for _, m := range msgs {
+		delta, _ := strconv.Atoi(streamString(m.Values, "delta"))
+		balance, _ := strconv.Atoi(streamString(m.Values, "balance"))
+		out = append(out, entity.StockMovement{
+			ID:            streamRef(m.partition, m.ID),
+			EventID:       streamString(m.Values, "event_id"),
+			Category:      streamString(m.Values, "category"),
+			Type:          streamString(m.Values, "type"),
+			ReservationID: streamString(m.Values, "reservation_id"),
+			UserID:        streamString(m.Values, "user_id"),
+			Delta:         delta,
+			Balance:       balance,
+			CreatedAt:     streamIDTime(m.ID),
+		})
+	}
+	return out, nil
+}
+
+func (s *StockService) AckLedger(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return s.deleteRefs(ctx, ledgerStreamKey, ids)
+}
+
+func streamIDTime(id string) time.Time {
+	ms, _ := entity.SplitMovementID(id)
+	return time.UnixMilli(ms).UTC()
+}
@@ -0,0 +1,144 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"concert-booking/internal/domain/entity"
+	"concert-booking/internal/domain/service"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// The outbox lives in one Redis stream per partition, in that slot, so
+// Reserve can append to it inside the same Lua script that decrements stock.
+// Acked entries are deleted, so every entry left in a stream is still pending.
+
+// Append is for events that belong to no reservation, e.g. writes a closing
+// producer hands back; they go to the stream of the partition named by their
+// key. Reservation events are appended by the stock scripts instead, to the
+// stream of the partition holding the reservation.
+func (s *StockService) Append(ctx context.Context, events ...service.OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	pipe := s.client.Pipeline()
+	partitions := make([]string, 0, len(events))
+	for _, e := range events {
+		partitions = append(partitions, e.Key)
+		pipe.XAdd(ctx, &goredis.XAddArgs{
+			Stream: outboxStreamKey(e.Key),
+			Values: []any{"id", e.ID, "topic", e.Topic, "key", e.Key, "payload", string(e.Payload)},
+		})
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+	return s.listPartitions(ctx, partitions...)
+}
+
+func (s *StockService) Pending(ctx context.Context, limit int) ([]service.OutboxEvent, error) {
+	msgs, err := s.readStreams(ctx, outboxStreamKey, limit)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]service.OutboxEvent, 0, len(msgs))
+	for _, m := range msgs {
+		out = append(out, service.OutboxEvent{
+			ID:      streamString(m.Values, "id"),
+			Topic:   streamString(m.Values, "topic"),
+			Key:     streamString(m.Values, "key"),
+			Payload: []byte(streamString(m.Values, "payload")),
+			Ref:     streamRef(m.partition, m.ID),
+		})
+	}
+	return out, nil
+}
+
+func (s *StockService) Ack(ctx context.Context, refs ...string) error {
+	if len(refs) == 0 {
+		return nil
+	}
+	return s.deleteRefs(ctx, outboxStreamKey, refs)
+}
+
+// Park moves the entry to the partition's parked stream in one script, so
+// the event is never in both streams or in neither.
+func (s *StockService) Park(ctx context.Context, e service.ParkedEvent) error {
+	partition, id := splitStreamRef(e.Ref)
+	return s.client.Eval(ctx, `
+if redis.call('XDEL', KEYS[1], ARGV[1]) == 0 then
+  return 0
+end
+redis.call('XADD', KEYS[2], '*', 'id', ARGV[2], 'topic', ARGV[3], 'key', ARGV[4], 'payload', ARGV[5], 'error', ARGV[6], 'parked_at', ARGV[7])
+return 1
+`, []string{outboxStreamKey(partition), parkedStreamKey(partition)},
+		id, e.ID, e.Topic, e.Key, string(e.Payload), e.Error, e.ParkedAt.UTC().Format(time.RFC3339)).Err()
+}
+
+// AcquireRelayLease lets a single API replica relay the outbox at a time, which
+// keeps per-key publish order intact. The owner renews by calling it again.
+func (s *StockService) AcquireRelayLease(ctx context.Context, owner string, ttl time.Duration) (bool, error) {
+	return s.acquireLease(ctx, outboxLeaseKey(), owner, ttl)
+}
+
+type partitionMessage struct {
+	goredis.XMessage
+	partition string
+}
+
+// readStreams returns up to limit entries across every partition's stream.
+// Entries of one partition keep their stream order. Partitions are merged by
+// stream ID, which is the clock of the node holding each stream, so the
+// merge only roughly favours older entries and is no order across
+// partitions.
+func (s *StockService) readStreams(ctx context.Context, key func(string) string, limit int) ([]partitionMessage, error) {
+	partitions, err := s.partitions(ctx)
+	if err != nil || len(partitions) == 0 {
+		return nil, err
+	}
+	pipe := s.client.Pipeline()
+	cmds := make([]*goredis.XMessageSliceCmd, len(partitions))
+	for i, partition := range partitions {
+		cmds[i] = pipe.XRangeN(ctx, key(partition), "-", "+", int64(limit))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, goredis.Nil) {
+		return nil, err
+	}
+	out := make([]partitionMessage, 0)
+	for i, cmd := range cmds {
+		for _, m := range cmd.Val() {
+			out = append(out, partitionMessage{XMessage: m, partition: partitions[i]})
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return streamIDLess(out[i].ID, out[j].ID) })
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (s *StockService) deleteRefs(ctx context.Context, key func(string) string, refs []string) error {
+	pipe := s.client.Pipeline()
+	for partition, ids := range groupRefs(refs) {
+		pipe.XDel(ctx, key(partition), ids...)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func streamIDLess(a, b string) bool {
+	am, as := entity.SplitMovementID(a)
+	bm, bs := entity.SplitMovementID(b)
+	if am != bm {
+		return am < bm
+	}
+	return as < bs
+}
+
+func streamString(values map[string]any, field string) string {
+	v, _ := values[field].(string)
+	return v
+}
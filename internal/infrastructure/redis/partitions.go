@@ -0,0 +1,61 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+// stock:partitions lists the partitions the reaper and the relay walk. A
+// partition with no pending holds, outbox or ledger entries is dropped from
+// it, so idle events stop costing a round trip on every sweep and flush.
+//
+// The list lives in another slot than the partition, so the two cannot
+// change together. Pruning removes the partition first and then checks it
+// in its own slot, leaving a pruned marker when it is empty; a reserve that
+// finds the marker lists the partition again once its hold is written.
+// Every other writer lists the partition after writing. Whichever order the
+// steps run in, a partition holding entries ends up listed.
+
+const pruneInterval = time.Minute
+
+// listPartitions adds partitions to stock:partitions.
+func (s *StockService) listPartitions(ctx context.Context, partitions ...string) error {
+	if len(partitions) == 0 {
+		return nil
+	}
+	return s.client.SAdd(ctx, partitionsKey(), toAny(partitions)...).Err()
+}
+
+// prunePartitions drops empty partitions, at most once per pruneInterval.
+func (s *StockService) prunePartitions(ctx context.Context, partitions []string) {
+	now := time.Now()
+	next := s.pruneAt.Load()
+	if now.UnixNano() < next || !s.pruneAt.CompareAndSwap(next, now.Add(pruneInterval).UnixNano()) {
+		return
+	}
+	for _, partition := range partitions {
+		if err := s.prunePartition(ctx, partition); err != nil {
+			log.Printf("prune partition %s: %v", partition, err)
+		}
+	}
+}
+
+func (s *StockService) prunePartition(ctx context.Context, partition string) error {
+	if err := s.client.SRem(ctx, partitionsKey(), partition).Err(); err != nil {
+		return err
+	}
+	empty, err := s.client.Eval(ctx, `
+if redis.call('ZCARD', KEYS[1]) > 0 or redis.call('XLEN', KEYS[2]) > 0 or redis.call('XLEN', KEYS[3]) > 0 then
+  redis.call('DEL', KEYS[4])
+  return 0
+end
+redis.call('SET', KEYS[4], 1)
+return 1
+`, []string{expirySetKey(partition), outboxStreamKey(partition), ledgerStreamKey(partition), prunedKey(partition)}).Int()
+	if err != nil || empty == 0 {
+		return errors.Join(err, s.listPartitions(context.WithoutCancel(ctx), partition))
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"concert-booking/internal/domain/service"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func (s *StockService) ReservationSnapshot(ctx context.Context) ([]service.ReservationMeta, error) {
+	var out lockedAppend[service.ReservationMeta]
+	err := scanKeys(ctx, s.client, partitionTag("*")+":reservation_meta:*", func(keys []string) error {
+		pipe := s.client.Pipeline()
+		cmds := make([]*goredis.MapStringStringCmd, len(keys))
+		for i, k := range keys {
+			cmds[i] = pipe.HGetAll(ctx, k)
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			return err
+		}
+		for i, cmd := range cmds {
+			m := cmd.Val()
+			if len(m) == 0 {
+				continue
+			}
+			_, id, _ := strings.Cut(keys[i], ":reservation_meta:")
+			out.add(metaFromHash(id, m))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if out.items == nil {
+		return []service.ReservationMeta{}, nil
+	}
+	return out.items, nil
+}
+
+// CompareAndSetStock sets a single counter atomically. For a sharded
+// category current is compared with the sum of the shards, see
+// compareAndSetShards.
+func (s *StockService) CompareAndSetStock(ctx context.Context, eventID, category string, current, value int) (bool, error) {
+	n, err := s.shardCount(ctx, eventID, category)
+	if err != nil {
+		return false, err
+	}
+	if n > 1 {
+		return s.compareAndSetShards(ctx, eventID, category, n, current, value)
+	}
+	return s.compareAndSet(ctx, eventID, eventID, category, current, value)
+}
+
+// compareAndSetShards spreads value-current over the shards of a category.
+// The shards sit on different slots, so no one script can cover them all:
+// the sum is checked first, then every shard moves to its new value with its
+// own compare-and-set and never below zero. A shard that changed in between
+// stops the update and reports false; the shards already set are consistent
+// on their own, and the next reconciliation run corrects the rest.
+func (s *StockService) compareAndSetShards(ctx context.Context, eventID, category string, n, current, value int) (bool, error) {
+	partitions := shardPartitions(eventID, n)
+	pipe := s.client.Pipeline()
+	cmds := make([]*goredis.StringCmd, n)
+	for i, partition := range partitions {
+		cmds[i] = pipe.Get(ctx, stockKey(partition, category))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, goredis.Nil) {
+		return false, err
+	}
+	shares := make([]int, n)
+	sum := 0
+	for i, cmd := range cmds {
+		shares[i], _ = strconv.Atoi(cmd.Val())
+		sum += shares[i]
+	}
+	if sum != current {
+		return false, nil
+	}
+	targets := spreadDelta(shares, max(value, 0)-current)
+	for i, partition := range partitions {
+		if targets[i] == shares[i] {
+			continue
+		}
+		if ok, err := s.compareAndSet(ctx, eventID, partition, category, shares[i], targets[i]); err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+func (s *StockService) compareAndSet(ctx context.Context, eventID, partition, category string, current, value int) (bool, error) {
+	res, err := s.client.Eval(ctx, `
+local stock = tonumber(redis.call('GET', KEYS[1]) or '0')
+if stock ~= tonumber(ARGV[1]) then
+  return 0
+end
+redis.call('SET', KEYS[1], ARGV[2])
+return 1
+`, []string{stockKey(partition, category)}, current, value).Int()
+	if err != nil {
+		return false, err
+	}
+	if res == 1 && value > 0 {
+		s.publishAvailable(ctx, eventID, partition, category)
+	}
+	return res == 1, nil
+}
+
+// spreadDelta returns the shard values after adding delta: an increase is
+// split like splitStock, a decrease is taken from the shards in order, none
+// going below zero.
+func spreadDelta(shares []int, delta int) []int {
+	out := make([]int, len(shares))
+	copy(out, shares)
+	if delta >= 0 {
+		for i, v := range splitStock(delta, len(out)) {
+			out[i] += v
+		}
+		return out
+	}
+	for i := range out {
+		take := min(out[i], -delta)
+		out[i] -= take
+		delta += take
+	}
+	return out
+}
+
+func metaFromHash(id string, m map[string]string) service.ReservationMeta {
+	expUnix, _ := strconv.ParseInt(m["expired_at"], 10, 64)
+	qty, _ := strconv.Atoi(m["qty"])
+	return service.ReservationMeta{
+		ReservationID: id,
+		UserID:        m["user_id"],
+		EventID:       m["event_id"],
+		Category:      m["category"],
+		Qty:           qty,
+		Status:        m["status"],
+		ExpiredAt:     time.Unix(expUnix, 0),
+	}
+}
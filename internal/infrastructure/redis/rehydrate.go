@@ -0,0 +1,78 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"concert-booking/internal/domain/service"
+)
+
+// MissingStock reports a category as missing when any of its shard counters is gone.
+func (s *StockService) MissingStock(ctx context.Context, eventID string, categories []string) ([]string, error) {
+	out := make([]string, 0)
+	for _, category := range categories {
+		n, err := s.shardCount(ctx, eventID, category)
+		if err != nil {
+			return nil, err
+		}
+		missing, err := s.missingShards(ctx, category, shardPartitions(eventID, n))
+		if err != nil {
+			return nil, err
+		}
+		if len(missing) > 0 {
+			out = append(out, category)
+		}
+	}
+	return out, nil
+}
+
+func (s *StockService) PrepareStock(ctx context.Context, eventID, category string, total int) error {
+	_, err := s.ensureLayout(ctx, eventID, category, total)
+	return err
+}
+
+// RestoreReservations puts each hold back on the shard Reserve starts at for
+// its ID, so releases return the stock to the shard it most likely came from.
+func (s *StockService) RestoreReservations(ctx context.Context, reservations []service.ReservationMeta) error {
+	if len(reservations) == 0 {
+		return nil
+	}
+	partitions := make([]string, len(reservations))
+	for i, meta := range reservations {
+		n, err := s.shardCount(ctx, meta.EventID, meta.Category)
+		if err != nil {
+			return err
+		}
+		partitions[i] = meta.EventID
+		if n > 1 {
+			partitions[i] = shardPartition(meta.EventID, shardFor(meta.ReservationID, n))
+		}
+	}
+	now := time.Now()
+	pipe := s.client.Pipeline()
+	for i, meta := range reservations {
+		partition := partitions[i]
+		pipe.SetNX(ctx, reservationIndexKey(meta.ReservationID), partition+"\n"+meta.Category, 24*time.Hour)
+		payload, _ := json.Marshal(meta)
+		ttlSec := int64(meta.ExpiredAt.Sub(now) / time.Second)
+		pipe.Eval(ctx, `
+if redis.call('EXISTS', KEYS[1]) == 1 then
+  return 0
+end
+redis.call('HSET', KEYS[1], 'event_id', ARGV[1], 'category', ARGV[2], 'qty', ARGV[3], 'user_id', ARGV[4], 'status', ARGV[5], 'expired_at', ARGV[6])
+redis.call('EXPIRE', KEYS[1], 86400)
+if ARGV[5] == 'reserved' and tonumber(ARGV[8]) > 0 then
+  redis.call('SET', KEYS[2], ARGV[7], 'EX', ARGV[8])
+  redis.call('ZADD', KEYS[3], ARGV[6], ARGV[9])
+end
+return 1
+`, []string{reservationMetaKey(partition, meta.ReservationID), reservationKey(partition, meta.ReservationID), expirySetKey(partition)},
+			meta.EventID, meta.Category, meta.Qty, meta.UserID, meta.Status, strconv.FormatInt(meta.ExpiredAt.Unix(), 10), string(payload), ttlSec, meta.ReservationID)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return err
+	}
+	return s.listPartitions(ctx, partitions...)
+}
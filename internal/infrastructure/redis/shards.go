@@ -0,0 +1,195 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"concert-booking/internal/domain/entity"
+	"concert-booking/internal/observability/metrics"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// A sharded category keeps its stock in N counters, one per partition
+// ("event" for shard 0, "event#n" otherwise), so concurrent reserves spread
+// over several keys and, on Redis Cluster, several nodes. The shard count is
+// fixed when the category is initialised and recorded in stock:shards.
+
+const layoutTTL = 30 * time.Second
+
+type shardConfig struct {
+	count    int
+	minTotal int
+}
+
+func (c shardConfig) countFor(total int) int {
+	if c.count > 1 && total >= c.minTotal {
+		return c.count
+	}
+	return 1
+}
+
+type layoutEntry struct {
+	shards int
+	at     time.Time
+}
+
+type layoutCache struct {
+	mu    sync.RWMutex
+	items map[string]layoutEntry
+}
+
+// EnableSharding splits categories with at least minTotal tickets across
+// shards counters when they are initialised. Existing categories keep their layout.
+func (s *StockService) EnableSharding(shards, minTotal int) {
+	s.shards = shardConfig{count: max(shards, 1), minTotal: minTotal}
+}
+
+// shardCount returns the recorded shard count, or 0 for a category that was
+// never initialised with a layout (treated as a single counter).
+func (s *StockService) shardCount(ctx context.Context, eventID, category string) (int, error) {
+	field := layoutField(eventID, category)
+	s.layout.mu.RLock()
+	e, ok := s.layout.items[field]
+	s.layout.mu.RUnlock()
+	if ok && time.Since(e.at) < layoutTTL {
+		return e.shards, nil
+	}
+	return s.loadShardCount(ctx, eventID, category)
+}
+
+func (s *StockService) loadShardCount(ctx context.Context, eventID, category string) (int, error) {
+	field := layoutField(eventID, category)
+	v, err := s.client.HGet(ctx, shardLayoutKey(), field).Result()
+	if err != nil && !errors.Is(err, goredis.Nil) {
+		return 0, err
+	}
+	n, _ := strconv.Atoi(v)
+	s.layout.mu.Lock()
+	s.layout.items[field] = layoutEntry{shards: n, at: time.Now()}
+	s.layout.mu.Unlock()
+	return n, nil
+}
+
+// ensureLayout records the shard count for a category of total tickets
+// unless one is recorded already, and returns the recorded count.
+func (s *StockService) ensureLayout(ctx context.Context, eventID, category string, total int) (int, error) {
+	n, err := s.loadShardCount(ctx, eventID, category)
+	if err != nil || n > 0 {
+		return n, err
+	}
+	if err := s.client.HSetNX(ctx, shardLayoutKey(), layoutField(eventID, category), s.shards.countFor(total)).Err(); err != nil {
+		return 0, err
+	}
+	return s.loadShardCount(ctx, eventID, category)
+}
+
+func (s *StockService) missingShards(ctx context.Context, category string, partitions []string) ([]string, error) {
+	pipe := s.client.Pipeline()
+	cmds := make([]*goredis.IntCmd, len(partitions))
+	for i, partition := range partitions {
+		cmds[i] = pipe.Exists(ctx, stockKey(partition, category))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, err
+	}
+	out := make([]string, 0)
+	for i, cmd := range cmds {
+		if cmd.Val() == 0 {
+			out = append(out, partitions[i])
+		}
+	}
+	return out, nil
+}
+
+// rebalance moves the leftover stock of every other shard into target. It
+// only runs near sell-out, when no single shard can cover a request that the
+// category as a whole still can. The two halves of a move are separate
+// scripts on different slots; if the second fails the stock is missing until
+// reconciliation restores it, which can undersell but never oversell.
+func (s *StockService) rebalance(ctx context.Context, eventID, category string, shards, target int) error {
+	targetPartition := shardPartition(eventID, target)
+	for i := 0; i < shards; i++ {
+		if i == target {
+			continue
+		}
+		donor := shardPartition(eventID, i)
+		moved, err := s.client.Eval(ctx, `
+local v = tonumber(redis.call('GET', KEYS[1]) or '0')
+if v <= 0 then
+  return 0
+end
+redis.call('SET', KEYS[1], 0)
+redis.call('XADD', KEYS[2], '*', 'type', ARGV[3], 'event_id', ARGV[1], 'category', ARGV[2], 'reservation_id', '', 'user_id', '', 'delta', -v, 'balance', 0)
+redis.call('PUBLISH', 'stock:soldout', ARGV[1] .. '\n' .. ARGV[4] .. '\n' .. ARGV[2] .. '\n' .. '0')
+return v
+`, []string{stockKey(donor, category), ledgerStreamKey(donor)}, eventID, category, entity.MovementRebalance, donor).Int()
+		if err != nil {
+			return err
+		}
+		if moved == 0 {
+			continue
+		}
+		if err := s.client.Eval(ctx, `
+local balance = redis.call('INCRBY', KEYS[1], ARGV[4])
+redis.call('XADD', KEYS[2], '*', 'type', ARGV[3], 'event_id', ARGV[1], 'category', ARGV[2], 'reservation_id', '', 'user_id', '', 'delta', ARGV[4], 'balance', balance)
+if balance == tonumber(ARGV[4]) then
+  redis.call('PUBLISH', 'stock:soldout', ARGV[1] .. '\n' .. ARGV[5] .. '\n' .. ARGV[2] .. '\n' .. '1')
+end
+return balance
+`, []string{stockKey(targetPartition, category), ledgerStreamKey(targetPartition)}, eventID, category, entity.MovementRebalance, moved, targetPartition).Err(); err != nil {
+			log.Printf("stock rebalance %s/%s lost %d tickets from %s: %v", eventID, category, moved, donor, err)
+			return err
+		}
+		metrics.IncStockRebalance()
+	}
+	return s.listPartitions(ctx, shardPartitions(eventID, shards)...)
+}
+
+func shardPartition(eventID string, shard int) string {
+	if shard == 0 {
+		return eventID
+	}
+	return eventID + "#" + strconv.Itoa(shard)
+}
+
+func shardPartitions(eventID string, shards int) []string {
+	out := make([]string, 0, shards)
+	for i := 0; i < max(shards, 1); i++ {
+		out = append(out, shardPartition(eventID, i))
+	}
+	return out
+}
+
+func shardFor(reservationID string, shards int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(reservationID))
+	return int(h.Sum32() % uint32(shards))
+}
+
+// splitStock spreads total over n shards, the remainder going to the first ones.
+func splitStock(total, n int) []int {
+	out := make([]int, n)
+	for i := range out {
+		out[i] = total / n
+		if i < total%n {
+			out[i]++
+		}
+	}
+	return out
+}
+
+func layoutField(eventID, category string) string { return eventID + "\n" + category }
+
+func toAny(items []string) []any {
+	out := make([]any, len(items))
+	for i, v := range items {
+		out[i] = v
+	}
+	return out
+}
@@ -0,0 +1,102 @@
+package redis
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// soldOutChannel carries "event\npartition\ncategory\nstate" messages from
+// the stock scripts: state 0 when a counter reaches zero (or a reserve finds
+// it empty), 1 when stock returns to an empty counter.
+const soldOutChannel = "stock:soldout"
+
+type soldOutCache struct {
+	mu       sync.RWMutex
+	watching bool
+	// zero holds the empty partitions per layoutField(event, category).
+	zero map[string]map[string]bool
+}
+
+// SoldOut reports whether every shard of the category was empty at the last
+// message. It only answers true while WatchSoldOut is running and once this
+// replica knows the category's layout; until a reserve has loaded it, the
+// shard count is unknown and the reserve itself decides.
+func (s *StockService) SoldOut(eventID, category string) bool {
+	field := layoutField(eventID, category)
+	s.layout.mu.RLock()
+	entry, known := s.layout.items[field]
+	s.layout.mu.RUnlock()
+	if !known {
+		return false
+	}
+	shards := max(entry.shards, 1)
+	s.soldOut.mu.RLock()
+	defer s.soldOut.mu.RUnlock()
+	return s.soldOut.watching && len(s.soldOut.zero[field]) >= shards
+}
+
+// WatchSoldOut feeds the sold-out cache until ctx ends. Messages sent while
+// the subscription is down are lost, so the cache is cleared on every
+// (re)subscribe and refilled by the next reserve that finds a counter empty.
+func (s *StockService) WatchSoldOut(ctx context.Context) error {
+	sub := s.client.Subscribe(ctx, soldOutChannel)
+	defer sub.Close()
+	defer s.resetSoldOut(false)
+	for {
+		msg, err := sub.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			s.resetSoldOut(false)
+			log.Printf("sold-out subscription: %v", err)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(100 * time.Millisecond):
+			}
+			continue
+		}
+		switch m := msg.(type) {
+		case *goredis.Subscription:
+			s.resetSoldOut(true)
+		case *goredis.Message:
+			s.applySoldOut(m.Payload)
+		}
+	}
+}
+
+func (s *StockService) resetSoldOut(watching bool) {
+	s.soldOut.mu.Lock()
+	s.soldOut.watching = watching
+	s.soldOut.zero = map[string]map[string]bool{}
+	s.soldOut.mu.Unlock()
+}
+
+func (s *StockService) applySoldOut(payload string) {
+	parts := strings.Split(payload, "\n")
+	if len(parts) != 4 {
+		return
+	}
+	field := layoutField(parts[0], parts[2])
+	s.soldOut.mu.Lock()
+	defer s.soldOut.mu.Unlock()
+	if parts[3] == "0" {
+		if s.soldOut.zero[field] == nil {
+			s.soldOut.zero[field] = map[string]bool{}
+		}
+		s.soldOut.zero[field][parts[1]] = true
+		return
+	}
+	delete(s.soldOut.zero[field], parts[1])
+}
+
+// publishAvailable announces stock added outside the scripts, e.g. by repair.
+func (s *StockService) publishAvailable(ctx context.Context, eventID, partition, category string) {
+	_ = s.client.Publish(ctx, soldOutChannel, eventID+"\n"+partition+"\n"+category+"\n1").Err()
+}
@@ -4,24 +4,33 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
 	"strconv"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"concert-booking/internal/domain/entity"
 	"concert-booking/internal/domain/service"
+	"concert-booking/internal/observability/metrics"
 
 	goredis "github.com/redis/go-redis/v9"
 )
 
 type StockService struct {
-	client *goredis.Client
+	client  goredis.UniversalClient
+	owner   string
+	shards  shardConfig
+	layout  layoutCache
+	soldOut soldOutCache
+	// pruneAt is when prunePartitions may run next, in unix nanoseconds.
+	pruneAt atomic.Int64
 }
 
-func NewStockService(addr, password string) *StockService {
-	return &StockService{client: goredis.NewClient(&goredis.Options{Addr: addr, Password: password})}
+func NewStockService(client goredis.UniversalClient) *StockService {
+	return &StockService{client: client, owner: newOwner(), shards: shardConfig{count: 1}, layout: layoutCache{items: map[string]layoutEntry{}}}
 }
 
-func (s *StockService) Client() *goredis.Client {
+func (s *StockService) Client() goredis.UniversalClient {
 	return s.client
 }
 
@@ -29,69 +38,192 @@ func (s *StockService) Ping(ctx context.Context) error {
 	return s.client.Ping(ctx).Err()
 }
 
+// InitStock never overwrites an existing counter. Categories of at least
+// the configured size are split across shard counters.
 func (s *StockService) InitStock(ctx context.Context, eventID, category string, total int) error {
-	return s.client.SetNX(ctx, stockKey(eventID, category), total, 0).Err()
+	n, err := s.ensureLayout(ctx, eventID, category, total)
+	if err != nil {
+		return err
+	}
+	partitions := shardPartitions(eventID, n)
+	shares := splitStock(total, n)
+	if n > 1 {
+		// When some shards survived (e.g. a lost cluster node), the missing
+		// ones start empty instead of taking a share: reconciliation can add
+		// stock back later, but an extra share could oversell.
+		missing, err := s.missingShards(ctx, category, partitions)
+		if err != nil {
+			return err
+		}
+		if len(missing) < n {
+			shares = make([]int, n)
+		}
+	}
+	for i, partition := range partitions {
+		if err := s.client.Eval(ctx, `
+if redis.call('SETNX', KEYS[1], ARGV[1]) == 0 then
+  return 0
+end
+redis.call('XADD', KEYS[2], '*', 'type', 'init', 'event_id', ARGV[2], 'category', ARGV[3], 'reservation_id', '', 'user_id', '', 'delta', ARGV[1], 'balance', ARGV[1])
+if tonumber(ARGV[1]) > 0 then
+  redis.call('PUBLISH', 'stock:soldout', ARGV[2] .. '\n' .. ARGV[4] .. '\n' .. ARGV[3] .. '\n' .. '1')
+end
+return 1
+`, []string{stockKey(partition, category), ledgerStreamKey(partition)}, shares[i], eventID, category, partition).Err(); err != nil {
+			return err
+		}
+	}
+	return s.listPartitions(ctx, partitions...)
 }
 
+// GetStocks sums every shard of each category.
 func (s *StockService) GetStocks(ctx context.Context, eventID string, categories []string) (map[string]int, error) {
+	out := make(map[string]int, len(categories))
 	if len(categories) == 0 {
-		return map[string]int{}, nil
+		return out, nil
 	}
-	keys := make([]string, 0, len(categories))
+	pipe := s.client.Pipeline()
+	cmds := make(map[string][]*goredis.StringCmd, len(categories))
 	for _, category := range categories {
-		keys = append(keys, stockKey(eventID, category))
+		n, err := s.shardCount(ctx, eventID, category)
+		if err != nil {
+			return nil, err
+		}
+		for _, partition := range shardPartitions(eventID, max(n, 1)) {
+			cmds[category] = append(cmds[category], pipe.Get(ctx, stockKey(partition, category)))
+		}
 	}
-	values, err := s.client.MGet(ctx, keys...).Result()
-	if err != nil {
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, goredis.Nil) {
 		return nil, err
 	}
-	out := make(map[string]int, len(categories))
-	for i, category := range categories {
-		switch v := values[i].(type) {
-		case string:
-			n, _ := strconv.Atoi(v)
-			out[category] = n
-		case int64:
-			out[category] = int(v)
-		case nil:
-			out[category] = 0
-		default:
-			n, _ := strconv.Atoi(fmt.Sprint(v))
-			out[category] = n
+	for category, list := range cmds {
+		for _, cmd := range list {
+			n, _ := strconv.Atoi(cmd.Val())
+			out[category] += n
 		}
 	}
 	return out, nil
 }
 
-func (s *StockService) Reserve(ctx context.Context, meta service.ReservationMeta, ttl time.Duration) error {
+// Reserve starts at the shard picked by hashing the reservation ID and falls
+// back to the others. When every shard is short only because the remaining
+// stock is scattered, it is consolidated into the first shard and retried,
+// so ErrOutOfStock means the category as a whole cannot cover the request.
+func (s *StockService) Reserve(ctx context.Context, meta service.ReservationMeta, ttl time.Duration, events ...service.OutboxEvent) error {
+	n, err := s.shardCount(ctx, meta.EventID, meta.Category)
+	if err != nil {
+		return err
+	}
+	if n <= 1 {
+		return s.reserveIn(ctx, meta.EventID, meta, ttl, events)
+	}
+	start := shardFor(meta.ReservationID, n)
+	for i := 0; i < n; i++ {
+		partition := shardPartition(meta.EventID, (start+i)%n)
+		if err := s.reserveIn(ctx, partition, meta, ttl, events); !errors.Is(err, service.ErrOutOfStock) {
+			return err
+		}
+	}
+	stocks, err := s.GetStocks(ctx, meta.EventID, []string{meta.Category})
+	if err != nil {
+		return err
+	}
+	if stocks[meta.Category] < meta.Qty {
+		return service.ErrOutOfStock
+	}
+	if err := s.rebalance(ctx, meta.EventID, meta.Category, n, start); err != nil {
+		return err
+	}
+	return s.reserveIn(ctx, shardPartition(meta.EventID, start), meta, ttl, events)
+}
+
+// reserveIn runs the reserve script against one partition's keys.
+func (s *StockService) reserveIn(ctx context.Context, partition string, meta service.ReservationMeta, ttl time.Duration, events []service.OutboxEvent) error {
 	payload, _ := json.Marshal(meta)
 	expAt := strconv.FormatInt(meta.ExpiredAt.Unix(), 10)
 	ttlSec := strconv.FormatInt(int64(ttl/time.Second), 10)
-	res, err := s.client.Eval(ctx, `
+	args := []any{meta.Qty, string(payload), ttlSec, meta.EventID, meta.Category, meta.UserID, expAt, meta.ReservationID, len(events), partition}
+	for _, e := range events {
+		args = append(args, e.ID, e.Topic, e.Key, string(e.Payload))
+	}
+	// The index is written first (in the same round trip) so the reservation
+	// can be found by ID as soon as the script succeeds.
+	pipe := s.client.Pipeline()
+	pipe.Set(ctx, reservationIndexKey(meta.ReservationID), partition+"\n"+meta.Category, 24*time.Hour)
+	eval := pipe.Eval(ctx, `
 local stock = tonumber(redis.call('GET', KEYS[1]) or '0')
 local qty = tonumber(ARGV[1])
 if stock < qty then
+  if stock <= 0 then
+    redis.call('PUBLISH', 'stock:soldout', ARGV[4] .. '\n' .. ARGV[10] .. '\n' .. ARGV[5] .. '\n' .. '0')
+  end
   return 0
 end
-redis.call('DECRBY', KEYS[1], qty)
+local balance = redis.call('DECRBY', KEYS[1], qty)
+redis.call('XADD', KEYS[6], '*', 'type', 'reserve', 'event_id', ARGV[4], 'category', ARGV[5], 'reservation_id', ARGV[8], 'user_id', ARGV[6], 'delta', -qty, 'balance', balance)
+if balance == 0 then
+  redis.call('PUBLISH', 'stock:soldout', ARGV[4] .. '\n' .. ARGV[10] .. '\n' .. ARGV[5] .. '\n' .. '0')
+end
 redis.call('SET', KEYS[2], ARGV[2], 'EX', ARGV[3])
 redis.call('HSET', KEYS[3], 'event_id', ARGV[4], 'category', ARGV[5], 'qty', ARGV[1], 'user_id', ARGV[6], 'status', 'reserved', 'expired_at', ARGV[7])
 redis.call('EXPIRE', KEYS[3], 86400)
 redis.call('ZADD', KEYS[4], ARGV[7], ARGV[8])
+for i = 0, tonumber(ARGV[9]) - 1 do
+  local base = 11 + i * 4
+  redis.call('XADD', KEYS[5], '*', 'id', ARGV[base], 'topic', ARGV[base + 1], 'key', ARGV[base + 2], 'payload', ARGV[base + 3])
+end
+if redis.call('EXISTS', KEYS[7]) == 1 then
+  return 2
+end
 return 1
-`, []string{stockKey(meta.EventID, meta.Category), reservationKey(meta.ReservationID), reservationMetaKey(meta.ReservationID), expirySetKey()},
-		meta.Qty, string(payload), ttlSec, meta.EventID, meta.Category, meta.UserID, expAt, meta.ReservationID).Int()
-	if err != nil {
+`, []string{stockKey(partition, meta.Category), reservationKey(partition, meta.ReservationID), reservationMetaKey(partition, meta.ReservationID), expirySetKey(partition), outboxStreamKey(partition), ledgerStreamKey(partition), prunedKey(partition)},
+		args...)
+	if _, err := pipe.Exec(ctx); err != nil {
+		s.dropIndexes(ctx, partition, meta.ReservationID)
 		return err
 	}
-	if res != 1 {
+	switch res, _ := eval.Int(); res {
+	case 0:
+		_ = s.client.Del(ctx, reservationIndexKey(meta.ReservationID)).Err()
 		return service.ErrOutOfStock
+	case 2:
+		return s.listPartitions(ctx, partition)
 	}
 	return nil
 }
 
+// dropIndexes removes the index entries written ahead of a reserve script
+// that failed. The script may still have run if only its reply was lost, so
+// an entry is kept when the reservation exists in the partition.
+func (s *StockService) dropIndexes(ctx context.Context, partition string, ids ...string) {
+	ctx = context.WithoutCancel(ctx)
+	for _, id := range ids {
+		n, err := s.client.Exists(ctx, reservationMetaKey(partition, id)).Result()
+		if err == nil && n == 0 {
+			_ = s.client.Del(ctx, reservationIndexKey(id)).Err()
+		}
+	}
+}
+
+// locate resolves the partition and category a reservation's keys are tagged with.
+func (s *StockService) locate(ctx context.Context, reservationID string) (string, string, error) {
+	v, err := s.client.Get(ctx, reservationIndexKey(reservationID)).Result()
+	if errors.Is(err, goredis.Nil) {
+		return "", "", service.ErrReservationNotFound
+	}
+	if err != nil {
+		return "", "", err
+	}
+	partition, category, _ := strings.Cut(v, "\n")
+	return partition, category, nil
+}
+
 func (s *StockService) GetReservation(ctx context.Context, reservationID string) (service.ReservationMeta, error) {
-	metaMap, err := s.client.HGetAll(ctx, reservationMetaKey(reservationID)).Result()
+	partition, _, err := s.locate(ctx, reservationID)
+	if err != nil {
+		return service.ReservationMeta{}, err
+	}
+	metaMap, err := s.client.HGetAll(ctx, reservationMetaKey(partition, reservationID)).Result()
 	if err != nil {
 		return service.ReservationMeta{}, err
 	}
@@ -100,7 +232,7 @@ func (s *StockService) GetReservation(ctx context.Context, reservationID string)
 	}
 	status := metaMap["status"]
 	if status != "reserved" && status != "confirmed" {
-		return service.ReservationMeta{}, service.ErrReservationNotFound
+		return service.ReservationMeta{}, service.ErrReservationReleased
 	}
 	expUnix, _ := strconv.ParseInt(metaMap["expired_at"], 10, 64)
 	meta := service.ReservationMeta{
@@ -113,12 +245,19 @@ func (s *StockService) GetReservation(ctx context.Context, reservationID string)
 	}
 	meta.Qty, _ = strconv.Atoi(metaMap["qty"])
 	if time.Now().After(meta.ExpiredAt) && meta.Status == "reserved" {
-		return service.ReservationMeta{}, service.ErrReservationNotFound
+		return service.ReservationMeta{}, service.ErrReservationReleased
 	}
 	return meta, nil
 }
 
-func (s *StockService) ConfirmReservation(ctx context.Context, reservationID string) error {
+// ConfirmReservation, release and the reaper append their outbox events to
+// the stream of the partition the reservation was taken from, so every event
+// of one reservation lands in one stream, in order.
+func (s *StockService) ConfirmReservation(ctx context.Context, reservationID string, events ...service.OutboxEvent) error {
+	partition, category, err := s.locate(ctx, reservationID)
+	if err != nil {
+		return err
+	}
 	res, err := s.client.Eval(ctx, `
 local status = redis.call('HGET', KEYS[1], 'status')
 if not status then
@@ -130,8 +269,16 @@ end
 redis.call('HSET', KEYS[1], 'status', 'confirmed')
 redis.call('DEL', KEYS[2])
 redis.call('ZREM', KEYS[3], ARGV[1])
+local meta = redis.call('HMGET', KEYS[1], 'event_id', 'category', 'user_id')
+local balance = tonumber(redis.call('GET', KEYS[4]) or '0')
+redis.call('XADD', KEYS[5], '*', 'type', 'confirm', 'event_id', meta[1], 'category', meta[2], 'reservation_id', ARGV[1], 'user_id', meta[3], 'delta', 0, 'balance', balance)
+for i = 0, tonumber(ARGV[2]) - 1 do
+  local base = 3 + i * 4
+  redis.call('XADD', KEYS[6], '*', 'id', ARGV[base], 'topic', ARGV[base + 1], 'key', ARGV[base + 2], 'payload', ARGV[base + 3])
+end
 return 1
-`, []string{reservationMetaKey(reservationID), reservationKey(reservationID), expirySetKey()}, reservationID).Int()
+`, []string{reservationMetaKey(partition, reservationID), reservationKey(partition, reservationID), expirySetKey(partition), stockKey(partition, category), ledgerStreamKey(partition), outboxStreamKey(partition)},
+		eventArgs([]any{reservationID}, events)...).Int()
 	if err != nil {
 		return err
 	}
@@ -144,65 +291,196 @@ return 1
 	return nil
 }
 
-func (s *StockService) ReleaseReservation(ctx context.Context, reservationID string) (service.ReservationMeta, error) {
+func (s *StockService) ReleaseReservation(ctx context.Context, reservationID string, events ...service.OutboxEvent) (service.ReservationMeta, error) {
 	meta, err := s.GetReservation(ctx, reservationID)
 	if err != nil {
 		return service.ReservationMeta{}, err
 	}
-	res, err := s.client.Eval(ctx, `
-local status = redis.call('HGET', KEYS[1], 'status')
-if not status then
-  return -1
-end
-if status ~= 'reserved' then
-  return 0
-end
-redis.call('HSET', KEYS[1], 'status', 'expired')
-redis.call('INCRBY', KEYS[2], ARGV[1])
-redis.call('DEL', KEYS[3])
-redis.call('ZREM', KEYS[4], ARGV[2])
-return 1
-`, []string{reservationMetaKey(reservationID), stockKey(meta.EventID, meta.Category), reservationKey(reservationID), expirySetKey()}, meta.Qty, reservationID).Int()
+	partition, _, err := s.locate(ctx, reservationID)
 	if err != nil {
 		return service.ReservationMeta{}, err
 	}
-	if res != 1 {
-		return service.ReservationMeta{}, service.ErrReservationFinalized
+	if err := s.release(ctx, partition, meta, entity.MovementRelease, events); err != nil {
+		return service.ReservationMeta{}, err
 	}
 	meta.Status = "expired"
 	return meta, nil
 }
 
-func (s *StockService) ReleaseExpired(ctx context.Context, now time.Time, limit int) ([]service.ReservationMeta, error) {
-	ids, err := s.client.ZRangeByScore(ctx, expirySetKey(), &goredis.ZRangeBy{Min: "-inf", Max: strconv.FormatInt(now.Unix(), 10), Offset: 0, Count: int64(limit)}).Result()
+// ReleaseExpired walks the per-partition expiry sets until limit
+// reservations have been released. Each partition is reaped by one script
+// call, and only by the replica holding that partition's reaper lease.
+func (s *StockService) ReleaseExpired(ctx context.Context, now time.Time, limit int, event service.ReleaseEvent) ([]service.ReservationMeta, error) {
+	partitions, err := s.partitions(ctx)
 	if err != nil {
 		return nil, err
 	}
-	out := make([]service.ReservationMeta, 0, len(ids))
-	for _, id := range ids {
-		metaMap, err := s.client.HGetAll(ctx, reservationMetaKey(id)).Result()
-		if err != nil || len(metaMap) == 0 {
-			continue
+	var out []service.ReservationMeta
+	for _, partition := range partitions {
+		if len(out) >= limit {
+			break
+		}
+		items, held, err := s.reapPartition(ctx, partition, now, limit-len(out), event)
+		if err != nil {
+			return out, err
 		}
-		if metaMap["status"] != "reserved" {
-			_ = s.client.ZRem(ctx, expirySetKey(), id).Err()
+		if !held {
+			metrics.IncReaperLeaseSkipped()
 			continue
 		}
-		qty, _ := strconv.Atoi(metaMap["qty"])
-		expUnix, _ := strconv.ParseInt(metaMap["expired_at"], 10, 64)
-		meta := service.ReservationMeta{ReservationID: id, UserID: metaMap["user_id"], EventID: metaMap["event_id"], Category: metaMap["category"], Qty: qty, Status: "reserved", ExpiredAt: time.Unix(expUnix, 0)}
-		if _, err := s.ReleaseReservation(ctx, id); err != nil {
-			if !errors.Is(err, service.ErrReservationFinalized) {
-				continue
-			}
+		out = append(out, items...)
+	}
+	s.prunePartitions(ctx, partitions)
+	return out, nil
+}
+
+// reapPartition releases up to limit due reservations and returns their
+// metadata. The due IDs and their metadata are read first so the script can
+// declare every metadata, hold and stock key it touches and carry each
+// hold's expired event; it then rechecks each one, since a reservation may
+// be confirmed or released in between. held is false when another replica
+// owns the lease.
+func (s *StockService) reapPartition(ctx context.Context, partition string, now time.Time, limit int, event service.ReleaseEvent) ([]service.ReservationMeta, bool, error) {
+	ids, err := s.client.ZRangeArgs(ctx, goredis.ZRangeArgs{
+		Key:     expirySetKey(partition),
+		Start:   "-inf",
+		Stop:    strconv.FormatInt(now.Unix(), 10),
+		ByScore: true,
+		Count:   int64(limit),
+	}).Result()
+	if err != nil {
+		return nil, false, err
+	}
+	// Nothing is due, so there is no need to take the lease either.
+	if len(ids) == 0 {
+		return nil, true, nil
+	}
+	pipe := s.client.Pipeline()
+	hashes := make([]*goredis.MapStringStringCmd, len(ids))
+	for i, id := range ids {
+		hashes[i] = pipe.HGetAll(ctx, reservationMetaKey(partition, id))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, false, err
+	}
+	keys := []string{expirySetKey(partition), reaperLeaseKey(partition), ledgerStreamKey(partition), outboxStreamKey(partition)}
+	args := []any{now.Unix(), s.owner, reaperLeaseTTL.Milliseconds(), entity.MovementExpire, partition}
+	for i, id := range ids {
+		meta := metaFromHash(id, hashes[i].Val())
+		keys = append(keys, reservationMetaKey(partition, id), reservationKey(partition, id), stockKey(partition, meta.Category))
+		e, err := releaseEvent(event, meta)
+		if err != nil {
+			return nil, false, err
 		}
+		args = append(args, id, e.ID, e.Topic, e.Key, string(e.Payload))
+	}
+	res, err := s.client.Eval(ctx, `
+local lease = redis.call('GET', KEYS[2])
+if lease and lease ~= ARGV[2] then
+  return {0}
+end
+redis.call('SET', KEYS[2], ARGV[2], 'PX', ARGV[3])
+local out = {1}
+for i = 6, #ARGV, 5 do
+  local id = ARGV[i]
+  local base = 4 + (i - 6) / 5 * 3
+  local score = redis.call('ZSCORE', KEYS[1], id)
+  local meta = {}
+  if score and tonumber(score) <= tonumber(ARGV[1]) then
+    redis.call('ZREM', KEYS[1], id)
+    meta = redis.call('HMGET', KEYS[base + 1], 'status', 'event_id', 'category', 'qty', 'user_id', 'expired_at')
+  end
+  if meta[1] == 'reserved' then
+    redis.call('HSET', KEYS[base + 1], 'status', 'expired')
+    local balance = redis.call('INCRBY', KEYS[base + 3], meta[4])
+    redis.call('DEL', KEYS[base + 2])
+    redis.call('XADD', KEYS[3], '*', 'type', ARGV[4], 'event_id', meta[2], 'category', meta[3], 'reservation_id', id, 'user_id', meta[5], 'delta', meta[4], 'balance', balance)
+    if balance == tonumber(meta[4]) then
+      redis.call('PUBLISH', 'stock:soldout', meta[2] .. '\n' .. ARGV[5] .. '\n' .. meta[3] .. '\n' .. '1')
+    end
+    if ARGV[i + 1] ~= '' then
+      redis.call('XADD', KEYS[4], '*', 'id', ARGV[i + 1], 'topic', ARGV[i + 2], 'key', ARGV[i + 3], 'payload', ARGV[i + 4])
+    end
+    table.insert(out, id)
+    table.insert(out, meta[2])
+    table.insert(out, meta[3])
+    table.insert(out, meta[4])
+    table.insert(out, meta[5])
+    table.insert(out, meta[6])
+  end
+end
+return out
+`, keys, args...).StringSlice()
+	if err != nil {
+		return nil, false, err
+	}
+	if len(res) == 0 || res[0] != "1" {
+		return nil, false, nil
+	}
+	out := make([]service.ReservationMeta, 0, (len(res)-1)/6)
+	for i := 1; i+5 < len(res); i += 6 {
+		meta := metaFromHash(res[i], map[string]string{"event_id": res[i+1], "category": res[i+2], "qty": res[i+3], "user_id": res[i+4], "expired_at": res[i+5]})
 		meta.Status = "expired"
 		out = append(out, meta)
 	}
-	return out, nil
+	return out, true, nil
 }
 
-func stockKey(eventID, category string) string { return fmt.Sprintf("stock:%s:%s", eventID, category) }
-func reservationKey(id string) string          { return "reservation:" + id }
-func reservationMetaKey(id string) string      { return "reservation_meta:" + id }
-func expirySetKey() string                     { return "reservation_expiries" }
+// release returns a reserved reservation's stock to the shard it was taken
+// from and records the movement kind in the ledger.
+func (s *StockService) release(ctx context.Context, partition string, meta service.ReservationMeta, movement string, events []service.OutboxEvent) error {
+	res, err := s.client.Eval(ctx, `
+local status = redis.call('HGET', KEYS[1], 'status')
+if not status then
+  return -1
+end
+if status ~= 'reserved' then
+  return 0
+end
+redis.call('HSET', KEYS[1], 'status', 'expired')
+local balance = redis.call('INCRBY', KEYS[2], ARGV[1])
+redis.call('DEL', KEYS[3])
+redis.call('ZREM', KEYS[4], ARGV[2])
+redis.call('XADD', KEYS[5], '*', 'type', ARGV[3], 'event_id', ARGV[4], 'category', ARGV[5], 'reservation_id', ARGV[2], 'user_id', ARGV[6], 'delta', ARGV[1], 'balance', balance)
+if balance == tonumber(ARGV[1]) then
+  redis.call('PUBLISH', 'stock:soldout', ARGV[4] .. '\n' .. ARGV[7] .. '\n' .. ARGV[5] .. '\n' .. '1')
+end
+for i = 0, tonumber(ARGV[8]) - 1 do
+  local base = 9 + i * 4
+  redis.call('XADD', KEYS[6], '*', 'id', ARGV[base], 'topic', ARGV[base + 1], 'key', ARGV[base + 2], 'payload', ARGV[base + 3])
+end
+return 1
+`, []string{reservationMetaKey(partition, meta.ReservationID), stockKey(partition, meta.Category), reservationKey(partition, meta.ReservationID), expirySetKey(partition), ledgerStreamKey(partition), outboxStreamKey(partition)},
+		eventArgs([]any{meta.Qty, meta.ReservationID, movement, meta.EventID, meta.Category, meta.UserID, partition}, events)...).Int()
+	if err != nil {
+		return err
+	}
+	switch res {
+	case -1:
+		return service.ErrReservationNotFound
+	case 0:
+		return service.ErrReservationFinalized
+	}
+	return nil
+}
+
+// releaseEvent builds the expired event a release script carries for meta,
+// or an empty one, which the scripts skip, when meta is no longer reserved
+// or there is no builder.
+func releaseEvent(event service.ReleaseEvent, meta service.ReservationMeta) (service.OutboxEvent, error) {
+	if event == nil || meta.Status != "reserved" {
+		return service.OutboxEvent{}, nil
+	}
+	meta.Status = "expired"
+	return event(meta)
+}
+
+// eventArgs appends the event count and then the id, topic, key and payload
+// of each event, the layout the scripts' outbox loops read.
+func eventArgs(args []any, events []service.OutboxEvent) []any {
+	args = append(args, len(events))
+	for _, e := range events {
+		args = append(args, e.ID, e.Topic, e.Key, string(e.Payload))
+	}
+	return args
+}
@@ -0,0 +1,366 @@
+package redis
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"concert-booking/internal/domain/entity"
+	"concert-booking/internal/domain/service"
+	"concert-booking/internal/domain/ticketevent"
+	"concert-booking/internal/infrastructure/stocktest"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// TEST_REDIS_ADDR must point at a disposable Redis: every subtest flushes it.
+func TestStockServiceBehavior(t *testing.T) {
+	runSuite(t, func(s *StockService) service.StockService { return s })
+}
+
+// The sharded variant splits every category so reserves fall back across
+// shards and rebalance near sell-out.
+func TestShardedStockServiceBehavior(t *testing.T) {
+	runSuite(t, func(s *StockService) service.StockService {
+		s.EnableSharding(4, 1)
+		return s
+	})
+}
+
+// The batched variant coalesces the concurrent reserves of the suite.
+func TestBatchedStockServiceBehavior(t *testing.T) {
+	runSuite(t, func(s *StockService) service.StockService {
+		return NewReserveBatcher(s, 16, 2*time.Millisecond)
+	})
+}
+
+func runSuite(t *testing.T, configure func(*StockService) service.StockService) {
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("TEST_REDIS_ADDR not set")
+	}
+	stocktest.Run(t, func(t *testing.T) service.StockService {
+		s := NewStockService(NewClient(ClientOptions{Addrs: strings.Split(addr, ","), Password: os.Getenv("TEST_REDIS_PASSWORD"), Cluster: os.Getenv("TEST_REDIS_CLUSTER") == "true"}))
+		t.Cleanup(func() { _ = s.Client().Close() })
+		if err := flushAll(context.Background(), s.Client()); err != nil {
+			t.Fatalf("flush: %v", err)
+		}
+		return configure(s)
+	})
+}
+
+func TestSplitStock(t *testing.T) {
+	got := splitStock(10, 4)
+	want := []int{3, 3, 2, 2}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+	if n := shardFor("res-1", 4); n < 0 || n >= 4 || n != shardFor("res-1", 4) {
+		t.Fatalf("unstable shard %d", n)
+	}
+}
+
+func TestSpreadDelta(t *testing.T) {
+	tests := []struct {
+		shares []int
+		delta  int
+		want   []int
+	}{
+		{[]int{1, 0, 5}, 4, []int{3, 1, 6}},
+		{[]int{1, 0, 5}, -3, []int{0, 0, 3}},
+		{[]int{1, 0, 5}, -6, []int{0, 0, 0}},
+	}
+	for _, tc := range tests {
+		got := spreadDelta(tc.shares, tc.delta)
+		for i := range tc.want {
+			if got[i] != tc.want[i] {
+				t.Fatalf("spreadDelta(%v, %d): expected %v, got %v", tc.shares, tc.delta, tc.want, got)
+			}
+		}
+	}
+}
+
+func flushAll(ctx context.Context, client goredis.UniversalClient) error {
+	if cluster, ok := client.(*goredis.ClusterClient); ok {
+		return cluster.ForEachMaster(ctx, func(ctx context.Context, node *goredis.Client) error {
+			return node.FlushDB(ctx).Err()
+		})
+	}
+	return client.FlushDB(ctx).Err()
+}
+
+func TestReaperLeaseIsExclusive(t *testing.T) {
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("TEST_REDIS_ADDR not set")
+	}
+	ctx := context.Background()
+	opts := ClientOptions{Addrs: strings.Split(addr, ","), Password: os.Getenv("TEST_REDIS_PASSWORD"), Cluster: os.Getenv("TEST_REDIS_CLUSTER") == "true"}
+	a, b := NewStockService(NewClient(opts)), NewStockService(NewClient(opts))
+	t.Cleanup(func() { _ = a.Client().Close(); _ = b.Client().Close() })
+	if err := flushAll(ctx, a.Client()); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	_ = a.InitStock(ctx, "event-1", "VIP", 10)
+	for _, id := range []string{"res-1", "res-2"} {
+		meta := service.ReservationMeta{ReservationID: id, EventID: "event-1", Category: "VIP", Qty: 1, ExpiredAt: time.Now()}
+		if err := a.Reserve(ctx, meta, time.Minute); err != nil {
+			t.Fatalf("reserve: %v", err)
+		}
+	}
+	if items, err := a.ReleaseExpired(ctx, time.Now().Add(time.Hour), 1, nil); err != nil || len(items) != 1 {
+		t.Fatalf("expected one release by the lease holder, got %+v (%v)", items, err)
+	}
+	if items, err := b.ReleaseExpired(ctx, time.Now().Add(time.Hour), 10, nil); err != nil || len(items) != 0 {
+		t.Fatalf("expected the other replica to skip the leased partition, got %+v (%v)", items, err)
+	}
+	if items, err := a.ReleaseExpired(ctx, time.Now().Add(time.Hour), 10, nil); err != nil || len(items) != 1 || items[0].Qty != 1 {
+		t.Fatalf("expected the holder to release the rest, got %+v (%v)", items, err)
+	}
+}
+
+func TestParseReservationKey(t *testing.T) {
+	partition, id, ok := parseReservationKey(reservationKey("event-1#2", "res-1"))
+	if !ok || partition != "event-1#2" || id != "res-1" {
+		t.Fatalf("unexpected parse %q %q %v", partition, id, ok)
+	}
+	if _, _, ok := parseReservationKey(reservationMetaKey("event-1", "res-1")); ok {
+		t.Fatal("expected metadata key to be ignored")
+	}
+}
+
+func TestWatchExpiredReleasesHold(t *testing.T) {
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("TEST_REDIS_ADDR not set")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	s := NewStockService(NewClient(ClientOptions{Addrs: strings.Split(addr, ","), Password: os.Getenv("TEST_REDIS_PASSWORD"), Cluster: os.Getenv("TEST_REDIS_CLUSTER") == "true"}))
+	t.Cleanup(func() { _ = s.Client().Close() })
+	if err := flushAll(ctx, s.Client()); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	_ = s.InitStock(ctx, "event-1", "VIP", 10)
+	released := make(chan service.ReservationMeta, 1)
+	go func() { _ = s.WatchExpired(ctx, nil, func(m service.ReservationMeta) { released <- m }) }()
+	time.Sleep(200 * time.Millisecond)
+	meta := service.ReservationMeta{ReservationID: "res-1", EventID: "event-1", Category: "VIP", Qty: 2, ExpiredAt: time.Now().Add(time.Second)}
+	if err := s.Reserve(ctx, meta, time.Second); err != nil {
+		t.Fatalf("reserve: %v", err)
+	}
+	select {
+	case m := <-released:
+		if m.ReservationID != "res-1" || m.Qty != 2 {
+			t.Fatalf("unexpected release %+v", m)
+		}
+	case <-ctx.Done():
+		t.Fatal("no expiry notification")
+	}
+	if stocks, _ := s.GetStocks(ctx, "event-1", []string{"VIP"}); stocks["VIP"] != 10 {
+		t.Fatalf("expected stock restored, got %d", stocks["VIP"])
+	}
+	if items, err := s.ReleaseExpired(ctx, time.Now().Add(time.Hour), 10, nil); err != nil || len(items) != 0 {
+		t.Fatalf("expected the reaper to find nothing left, got %+v (%v)", items, err)
+	}
+}
+
+func TestSoldOutCacheTracksShards(t *testing.T) {
+	s := NewStockService(nil)
+	if s.SoldOut("event-1", "VIP") {
+		t.Fatal("expected no answer before watching")
+	}
+	s.resetSoldOut(true)
+	s.applySoldOut("event-1\nevent-1\nVIP\n0")
+	if s.SoldOut("event-1", "VIP") {
+		t.Fatal("expected no answer before the layout is known")
+	}
+	s.layout.items[layoutField("event-1", "VIP")] = layoutEntry{shards: 1, at: time.Now()}
+	if !s.SoldOut("event-1", "VIP") {
+		t.Fatal("expected single-counter category sold out")
+	}
+	s.applySoldOut("event-1\nevent-1\nVIP\n1")
+	if s.SoldOut("event-1", "VIP") {
+		t.Fatal("expected release to clear the entry")
+	}
+
+	s.layout.items[layoutField("event-1", "CAT1")] = layoutEntry{shards: 2, at: time.Now()}
+	s.applySoldOut("event-1\nevent-1\nCAT1\n0")
+	if s.SoldOut("event-1", "CAT1") {
+		t.Fatal("expected category with a non-empty shard to stay open")
+	}
+	s.applySoldOut("event-1\nevent-1#1\nCAT1\n0")
+	if !s.SoldOut("event-1", "CAT1") {
+		t.Fatal("expected category sold out once every shard is empty")
+	}
+	// A sharded category whose layout this replica has not loaded yet: one
+	// empty shard must not read as sold out.
+	s.applySoldOut("event-1\nevent-1\nCAT2\n0")
+	if s.SoldOut("event-1", "CAT2") {
+		t.Fatal("expected uncached layout to stay open")
+	}
+}
+
+func TestRestoreReservationsKeepsShard(t *testing.T) {
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("TEST_REDIS_ADDR not set")
+	}
+	ctx := context.Background()
+	s := NewStockService(NewClient(ClientOptions{Addrs: strings.Split(addr, ","), Password: os.Getenv("TEST_REDIS_PASSWORD"), Cluster: os.Getenv("TEST_REDIS_CLUSTER") == "true"}))
+	t.Cleanup(func() { _ = s.Client().Close() })
+	if err := flushAll(ctx, s.Client()); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	s.EnableSharding(4, 1)
+	if err := s.PrepareStock(ctx, "event-1", "VIP", 98); err != nil {
+		t.Fatalf("prepare: %v", err)
+	}
+	meta := service.ReservationMeta{ReservationID: "res-1", EventID: "event-1", Category: "VIP", Qty: 2, Status: "reserved", ExpiredAt: time.Now().Add(time.Minute)}
+	if err := s.RestoreReservations(ctx, []service.ReservationMeta{meta}); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if err := s.InitStock(ctx, "event-1", "VIP", 98); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	want := shardPartition("event-1", shardFor("res-1", 4))
+	if partition, _, err := s.locate(ctx, "res-1"); err != nil || partition != want {
+		t.Fatalf("expected hold on %s, got %q (%v)", want, partition, err)
+	}
+	if _, err := s.ReleaseReservation(ctx, "res-1"); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if stocks, _ := s.GetStocks(ctx, "event-1", []string{"VIP"}); stocks["VIP"] != 100 {
+		t.Fatalf("expected the hold returned, got %d", stocks["VIP"])
+	}
+}
+
+func TestBatchedReserveKeepsBinaryPayloads(t *testing.T) {
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("TEST_REDIS_ADDR not set")
+	}
+	ctx := context.Background()
+	s := NewStockService(NewClient(ClientOptions{Addrs: strings.Split(addr, ","), Password: os.Getenv("TEST_REDIS_PASSWORD"), Cluster: os.Getenv("TEST_REDIS_CLUSTER") == "true"}))
+	t.Cleanup(func() { _ = s.Client().Close() })
+	if err := flushAll(ctx, s.Client()); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	_ = s.InitStock(ctx, "event-1", "VIP", 10)
+	b := NewReserveBatcher(s, 16, 2*time.Millisecond)
+
+	res := entity.Reservation{ID: "res-1", UserID: "user-1", EventID: "event-1", Category: "VIP", Qty: 1, Status: entity.ReservationStatusReserved, ExpiredAt: time.Now().Add(time.Minute)}
+	payload, err := ticketevent.EncodingProtobuf.New(ctx, "ticket.reserved:res-1", "ticket.reserved", "event-1", ticketevent.ReservedFrom(res), time.Now())
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	meta := service.ReservationMeta{ReservationID: "res-1", UserID: "user-1", EventID: "event-1", Category: "VIP", Qty: 1, ExpiredAt: res.ExpiredAt}
+	if err := b.Reserve(ctx, meta, time.Minute, service.OutboxEvent{ID: "ticket.reserved:res-1", Topic: "ticket.reserved", Key: "event-1", Payload: payload}); err != nil {
+		t.Fatalf("reserve: %v", err)
+	}
+	pending, err := s.Pending(ctx, 10)
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("expected one outbox entry, got %v (%v)", pending, err)
+	}
+	if !bytes.Equal(pending[0].Payload, payload) {
+		t.Fatalf("payload changed in the stream: %x, want %x", pending[0].Payload, payload)
+	}
+	env, err := ticketevent.Decode("ticket.reserved", pending[0].Payload)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got, err := env.Reserved(); err != nil || got.Reservation().ID != "res-1" {
+		t.Fatalf("unexpected event %+v (%v)", got, err)
+	}
+}
+
+func TestReservationEventsShareStream(t *testing.T) {
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("TEST_REDIS_ADDR not set")
+	}
+	ctx := context.Background()
+	s := NewStockService(NewClient(ClientOptions{Addrs: strings.Split(addr, ","), Password: os.Getenv("TEST_REDIS_PASSWORD"), Cluster: os.Getenv("TEST_REDIS_CLUSTER") == "true"}))
+	t.Cleanup(func() { _ = s.Client().Close() })
+	if err := flushAll(ctx, s.Client()); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	s.EnableSharding(4, 1)
+	if err := s.InitStock(ctx, "event-1", "VIP", 100); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	// Pick a reservation outside shard 0, the partition its event key names.
+	id := "res-1"
+	for n := 2; shardFor(id, 4) == 0; n++ {
+		id = "res-" + strconv.Itoa(n)
+	}
+	meta := service.ReservationMeta{ReservationID: id, EventID: "event-1", Category: "VIP", Qty: 1, ExpiredAt: time.Now().Add(time.Minute)}
+	event := func(topic string) service.OutboxEvent {
+		return service.OutboxEvent{ID: topic + ":" + id, Topic: topic, Key: "event-1", Payload: []byte(topic)}
+	}
+	if err := s.Reserve(ctx, meta, time.Minute, event(ticketevent.TopicReserved)); err != nil {
+		t.Fatalf("reserve: %v", err)
+	}
+	if err := s.ConfirmReservation(ctx, id, event(ticketevent.TopicConfirmed)); err != nil {
+		t.Fatalf("confirm: %v", err)
+	}
+	pending, err := s.Pending(ctx, 10)
+	if err != nil || len(pending) != 2 {
+		t.Fatalf("expected 2 pending events, got %+v (%v)", pending, err)
+	}
+	want := shardPartition("event-1", shardFor(id, 4))
+	for _, e := range pending {
+		if partition, _ := splitStreamRef(e.Ref); partition != want {
+			t.Fatalf("expected %s in %s's stream, got %s", e.ID, want, partition)
+		}
+	}
+	if pending[0].Topic != ticketevent.TopicReserved {
+		t.Fatalf("expected reserved first, got %s", pending[0].Topic)
+	}
+}
+
+func TestIdlePartitionsArePruned(t *testing.T) {
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("TEST_REDIS_ADDR not set")
+	}
+	ctx := context.Background()
+	s := NewStockService(NewClient(ClientOptions{Addrs: strings.Split(addr, ","), Password: os.Getenv("TEST_REDIS_PASSWORD"), Cluster: os.Getenv("TEST_REDIS_CLUSTER") == "true"}))
+	t.Cleanup(func() { _ = s.Client().Close() })
+	if err := flushAll(ctx, s.Client()); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	if err := s.InitStock(ctx, "event-1", "VIP", 10); err != nil {
+		t.Fatalf("init: %v", err)
+	}
+	// The init movement keeps the partition listed until the ledger is drained.
+	if _, err := s.ReleaseExpired(ctx, time.Now(), 10, nil); err != nil {
+		t.Fatalf("sweep: %v", err)
+	}
+	if partitions, _ := s.partitions(ctx); len(partitions) != 1 {
+		t.Fatalf("expected partition kept, got %v", partitions)
+	}
+	if err := s.Client().Del(ctx, ledgerStreamKey("event-1")).Err(); err != nil {
+		t.Fatalf("drain ledger: %v", err)
+	}
+	s.pruneAt.Store(0)
+	if _, err := s.ReleaseExpired(ctx, time.Now(), 10, nil); err != nil {
+		t.Fatalf("sweep: %v", err)
+	}
+	if partitions, _ := s.partitions(ctx); len(partitions) != 0 {
+		t.Fatalf("expected partition pruned, got %v", partitions)
+	}
+	meta := service.ReservationMeta{ReservationID: "res-1", EventID: "event-1", Category: "VIP", Qty: 1, ExpiredAt: time.Now().Add(time.Minute)}
+	if err := s.Reserve(ctx, meta, time.Minute); err != nil {
+		t.Fatalf("reserve: %v", err)
+	}
+	if partitions, _ := s.partitions(ctx); len(partitions) != 1 {
+		t.Fatalf("expected reserve to list the partition again, got %v", partitions)
+	}
+}
@@ -0,0 +1,171 @@
+package schemaregistry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const schemaFile = "schema.proto"
+
+// compile parses a single .proto source; only the well-known types may be
+// imported.
+func compile(schema string) (protoreflect.FileDescriptor, error) {
+	c := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			Accessor: func(path string) (io.ReadCloser, error) {
+				if path != schemaFile {
+					return nil, fmt.Errorf("import %s is not available", path)
+				}
+				return io.NopCloser(strings.NewReader(schema)), nil
+			},
+		}),
+	}
+	files, err := c.Compile(context.Background(), schemaFile)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	return files[0], nil
+}
+
+// IncompatibleError lists why a schema cannot replace the latest version.
+type IncompatibleError struct {
+	Problems []string
+}
+
+func (e *IncompatibleError) Error() string {
+	return "schema is not backward compatible: " + strings.Join(e.Problems, "; ")
+}
+
+func (e *IncompatibleError) Is(target error) bool {
+	return target == ErrIncompatible
+}
+
+var ErrIncompatible = errors.New("schema is not backward compatible")
+
+// checkBackward reports the changes in next that would stop data written
+// with prev from being read, or stop consumers reading next-encoded data by
+// field name: removed or renamed messages, enums and fields, changed field
+// types or cardinality, and field numbers or names reused after being
+// removed. Adding messages, fields and enum values is allowed.
+func checkBackward(prev, next protoreflect.FileDescriptor) []string {
+	var problems []string
+	if prev.Package() != next.Package() {
+		problems = append(problems, fmt.Sprintf("package changed from %s to %s", prev.Package(), next.Package()))
+		return problems
+	}
+	walkMessages(prev.Messages(), func(old protoreflect.MessageDescriptor) {
+		cur := findMessage(next, old.FullName())
+		if cur == nil {
+			problems = append(problems, fmt.Sprintf("message %s removed", old.FullName()))
+			return
+		}
+		problems = append(problems, checkMessage(old, cur)...)
+	})
+	walkEnums(prev, func(old protoreflect.EnumDescriptor) {
+		cur := findEnum(next, old.FullName())
+		if cur == nil {
+			problems = append(problems, fmt.Sprintf("enum %s removed", old.FullName()))
+			return
+		}
+		for i := 0; i < old.Values().Len(); i++ {
+			v := old.Values().Get(i)
+			if cur.Values().ByNumber(v.Number()) == nil && !cur.ReservedRanges().Has(v.Number()) {
+				problems = append(problems, fmt.Sprintf("enum value %s (%d) removed without reserving its number", v.FullName(), v.Number()))
+			}
+		}
+	})
+	return problems
+}
+
+func checkMessage(old, cur protoreflect.MessageDescriptor) []string {
+	var problems []string
+	for i := 0; i < old.Fields().Len(); i++ {
+		f := old.Fields().Get(i)
+		nf := cur.Fields().ByNumber(f.Number())
+		if nf == nil {
+			if !cur.ReservedRanges().Has(f.Number()) || !cur.ReservedNames().Has(f.Name()) {
+				problems = append(problems, fmt.Sprintf("field %s (%d) removed without reserving its number and name", f.FullName(), f.Number()))
+			}
+			continue
+		}
+		if nf.Name() != f.Name() {
+			problems = append(problems, fmt.Sprintf("field %d of %s renamed from %s to %s", f.Number(), old.FullName(), f.Name(), nf.Name()))
+		}
+		if typeName(nf) != typeName(f) {
+			problems = append(problems, fmt.Sprintf("field %s changed type from %s to %s", f.FullName(), typeName(f), typeName(nf)))
+		}
+		if nf.Cardinality() != f.Cardinality() || nf.IsMap() != f.IsMap() {
+			problems = append(problems, fmt.Sprintf("field %s changed cardinality", f.FullName()))
+		}
+	}
+	for i := 0; i < cur.Fields().Len(); i++ {
+		nf := cur.Fields().Get(i)
+		if old.Fields().ByNumber(nf.Number()) != nil {
+			continue
+		}
+		if old.ReservedRanges().Has(nf.Number()) {
+			problems = append(problems, fmt.Sprintf("field %s reuses reserved number %d", nf.FullName(), nf.Number()))
+		}
+		if old.ReservedNames().Has(nf.Name()) {
+			problems = append(problems, fmt.Sprintf("field %s reuses a reserved name", nf.FullName()))
+		}
+	}
+	return problems
+}
+
+func typeName(f protoreflect.FieldDescriptor) string {
+	switch f.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return string(f.Message().FullName())
+	case protoreflect.EnumKind:
+		return string(f.Enum().FullName())
+	}
+	return f.Kind().String()
+}
+
+func walkMessages(msgs protoreflect.MessageDescriptors, fn func(protoreflect.MessageDescriptor)) {
+	for i := 0; i < msgs.Len(); i++ {
+		m := msgs.Get(i)
+		if m.IsMapEntry() {
+			continue
+		}
+		fn(m)
+		walkMessages(m.Messages(), fn)
+	}
+}
+
+func walkEnums(file protoreflect.FileDescriptor, fn func(protoreflect.EnumDescriptor)) {
+	each := func(enums protoreflect.EnumDescriptors) {
+		for i := 0; i < enums.Len(); i++ {
+			fn(enums.Get(i))
+		}
+	}
+	each(file.Enums())
+	walkMessages(file.Messages(), func(m protoreflect.MessageDescriptor) { each(m.Enums()) })
+}
+
+func findMessage(file protoreflect.FileDescriptor, name protoreflect.FullName) protoreflect.MessageDescriptor {
+	var found protoreflect.MessageDescriptor
+	walkMessages(file.Messages(), func(m protoreflect.MessageDescriptor) {
+		if m.FullName() == name {
+			found = m
+		}
+	})
+	return found
+}
+
+func findEnum(file protoreflect.FileDescriptor, name protoreflect.FullName) protoreflect.EnumDescriptor {
+	var found protoreflect.EnumDescriptor
+	walkEnums(file, func(e protoreflect.EnumDescriptor) {
+		if e.FullName() == name {
+			found = e
+		}
+	})
+	return found
+}
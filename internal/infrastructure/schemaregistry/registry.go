@@ -0,0 +1,177 @@
+// Package schemaregistry is a minimal protobuf schema registry kept in a
+// single JSON file. Subjects hold numbered versions of a .proto source and a
+// new version is only accepted when it is backward compatible with the
+// latest one.
+package schemaregistry
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrSubjectNotFound = errors.New("subject not found")
+	ErrVersionNotFound = errors.New("version not found")
+	ErrInvalidSchema   = errors.New("invalid schema")
+)
+
+type Version struct {
+	Subject      string    `json:"subject"`
+	Version      int       `json:"version"`
+	Schema       string    `json:"schema"`
+	RegisteredAt time.Time `json:"registered_at"`
+}
+
+type Registry struct {
+	path string
+	now  func() time.Time
+
+	mu       sync.RWMutex
+	subjects map[string][]Version
+}
+
+// Open loads the registry stored at path, starting empty when the file does
+// not exist yet.
+func Open(path string, now func() time.Time) (*Registry, error) {
+	r := &Registry{path: path, now: now, subjects: map[string][]Version{}}
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &r.subjects); err != nil {
+		return nil, fmt.Errorf("read schema registry %s: %w", path, err)
+	}
+	return r, nil
+}
+
+func (r *Registry) Subjects() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]string, 0, len(r.subjects))
+	for s := range r.subjects {
+		out = append(out, s)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (r *Registry) Versions(subject string) ([]int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	versions, ok := r.subjects[subject]
+	if !ok {
+		return nil, ErrSubjectNotFound
+	}
+	out := make([]int, len(versions))
+	for i, v := range versions {
+		out[i] = v.Version
+	}
+	return out, nil
+}
+
+// Get returns a version of subject; version 0 means the latest.
+func (r *Registry) Get(subject string, version int) (Version, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	versions, ok := r.subjects[subject]
+	if !ok {
+		return Version{}, ErrSubjectNotFound
+	}
+	if version == 0 {
+		return versions[len(versions)-1], nil
+	}
+	if version < 0 || version > len(versions) {
+		return Version{}, ErrVersionNotFound
+	}
+	return versions[version-1], nil
+}
+
+// Check validates schema against the latest version of subject without
+// registering it.
+func (r *Registry) Check(subject, schema string) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, err := r.check(subject, schema)
+	return err
+}
+
+// Register adds schema as the next version of subject. Registering the
+// latest schema again returns that version instead of adding a new one.
+func (r *Registry) Register(subject, schema string) (Version, error) {
+	if subject == "" {
+		return Version{}, fmt.Errorf("%w: empty subject", ErrInvalidSchema)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	latest, err := r.check(subject, schema)
+	if err != nil {
+		return Version{}, err
+	}
+	if latest != nil && latest.Schema == schema {
+		return *latest, nil
+	}
+	v := Version{Subject: subject, Version: len(r.subjects[subject]) + 1, Schema: schema, RegisteredAt: r.now().UTC()}
+	r.subjects[subject] = append(r.subjects[subject], v)
+	if err := r.save(); err != nil {
+		r.subjects[subject] = r.subjects[subject][:v.Version-1]
+		if len(r.subjects[subject]) == 0 {
+			delete(r.subjects, subject)
+		}
+		return Version{}, err
+	}
+	return v, nil
+}
+
+func (r *Registry) check(subject, schema string) (*Version, error) {
+	next, err := compile(schema)
+	if err != nil {
+		return nil, err
+	}
+	versions := r.subjects[subject]
+	if len(versions) == 0 {
+		return nil, nil
+	}
+	latest := versions[len(versions)-1]
+	prev, err := compile(latest.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("stored version %d of %s: %w", latest.Version, subject, err)
+	}
+	if problems := checkBackward(prev, next); len(problems) > 0 {
+		return nil, &IncompatibleError{Problems: problems}
+	}
+	return &latest, nil
+}
+
+// save writes the whole registry to a temporary file and renames it over
+// the old one, so a crash never leaves a partial file behind.
+func (r *Registry) save() error {
+	raw, err := json.MarshalIndent(r.subjects, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(r.path), filepath.Base(r.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(raw); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), r.path)
+}
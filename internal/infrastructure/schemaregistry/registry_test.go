@@ -0,0 +1,107 @@
+package schemaregistry
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"concert-booking/internal/domain/ticketevent/ticketeventpb"
+)
+
+const v1 = `syntax = "proto3";
+package shop.v1;
+message Order {
+  string id = 1;
+  int32 qty = 2;
+  string note = 3;
+}
+`
+
+func openTemp(t *testing.T) (*Registry, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "schemas.json")
+	r, err := Open(path, func() time.Time { return time.Unix(0, 0) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r, path
+}
+
+func TestRegisterAcceptsCompatibleChanges(t *testing.T) {
+	r, path := openTemp(t)
+	if v, err := r.Register("orders", v1); err != nil || v.Version != 1 {
+		t.Fatalf("register v1: %+v %v", v, err)
+	}
+	if v, err := r.Register("orders", v1); err != nil || v.Version != 1 {
+		t.Fatalf("re-registering the latest schema should return it: %+v %v", v, err)
+	}
+	v2 := strings.Replace(v1, "  string note = 3;\n", "  reserved 3;\n  reserved \"note\";\n  string customer = 4;\n", 1)
+	if v, err := r.Register("orders", v2); err != nil || v.Version != 2 {
+		t.Fatalf("register v2: %+v %v", v, err)
+	}
+
+	reopened, err := Open(path, time.Now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if versions, _ := reopened.Versions("orders"); len(versions) != 2 {
+		t.Fatalf("expected 2 persisted versions, got %v", versions)
+	}
+	latest, err := reopened.Get("orders", 0)
+	if err != nil || latest.Schema != v2 {
+		t.Fatalf("expected latest to be v2, got %+v %v", latest, err)
+	}
+}
+
+func TestRegisterRejectsBreakingChanges(t *testing.T) {
+	cases := map[string]string{
+		"type change":        strings.Replace(v1, "int32 qty = 2", "string qty = 2", 1),
+		"rename":             strings.Replace(v1, "int32 qty = 2", "int32 quantity = 2", 1),
+		"unreserved removal": strings.Replace(v1, "  string note = 3;\n", "", 1),
+		"repeated":           strings.Replace(v1, "int32 qty = 2", "repeated int32 qty = 2", 1),
+		"message removed":    "syntax = \"proto3\";\npackage shop.v1;\nmessage Invoice { string id = 1; }\n",
+		"package changed":    strings.Replace(v1, "shop.v1", "shop.v2", 1),
+	}
+	for name, schema := range cases {
+		t.Run(name, func(t *testing.T) {
+			r, _ := openTemp(t)
+			if _, err := r.Register("orders", v1); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := r.Register("orders", schema); !errors.Is(err, ErrIncompatible) {
+				t.Fatalf("expected ErrIncompatible, got %v", err)
+			}
+			if versions, _ := r.Versions("orders"); len(versions) != 1 {
+				t.Fatalf("rejected schema must not be stored, got %v", versions)
+			}
+		})
+	}
+}
+
+func TestRegisterRejectsReusedReservedField(t *testing.T) {
+	r, _ := openTemp(t)
+	reserved := strings.Replace(v1, "  string note = 3;\n", "  reserved 3;\n  reserved \"note\";\n", 1)
+	if _, err := r.Register("orders", reserved); err != nil {
+		t.Fatal(err)
+	}
+	reused := strings.Replace(reserved, "  reserved 3;\n  reserved \"note\";\n", "  bytes note = 3;\n", 1)
+	if _, err := r.Register("orders", reused); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("expected ErrIncompatible, got %v", err)
+	}
+}
+
+func TestRegisterRejectsInvalidSchema(t *testing.T) {
+	r, _ := openTemp(t)
+	if _, err := r.Register("orders", "message {"); !errors.Is(err, ErrInvalidSchema) {
+		t.Fatalf("expected ErrInvalidSchema, got %v", err)
+	}
+}
+
+func TestTicketEventSchemaCompiles(t *testing.T) {
+	r, _ := openTemp(t)
+	if _, err := r.Register(ticketeventpb.Subject, ticketeventpb.Schema); err != nil {
+		t.Fatal(err)
+	}
+}
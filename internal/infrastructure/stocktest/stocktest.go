@@ -0,0 +1,302 @@
+// Package stocktest holds the behavior tests every service.StockService
+// implementation must pass.
+package stocktest
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"concert-booking/internal/domain/service"
+)
+
+// Run executes the suite; newStock must return an empty store for each subtest.
+func Run(t *testing.T, newStock func(t *testing.T) service.StockService) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s service.StockService)
+	}{
+		{"InitAndGet", testInitAndGet},
+		{"ReserveDecrements", testReserveDecrements},
+		{"ReserveOutOfStock", testReserveOutOfStock},
+		{"ConfirmFinalizes", testConfirmFinalizes},
+		{"ReleaseRestoresStock", testReleaseRestoresStock},
+		{"ReleaseExpired", testReleaseExpired},
+		{"FinalizeRecordsEvents", testFinalizeRecordsEvents},
+		{"UnknownReservation", testUnknownReservation},
+		{"ConcurrentReserveNeverOversells", testConcurrentReserve},
+		{"ConfirmReleaseRace", testConfirmReleaseRace},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.fn(t, newStock(t))
+		})
+	}
+}
+
+const (
+	eventID  = "event-1"
+	category = "VIP"
+)
+
+func hold(id string, qty int) service.ReservationMeta {
+	return service.ReservationMeta{
+		ReservationID: id,
+		UserID:        "user-" + id,
+		EventID:       eventID,
+		Category:      category,
+		Qty:           qty,
+		Status:        "reserved",
+		ExpiredAt:     time.Now().Add(time.Minute),
+	}
+}
+
+func mustInit(t *testing.T, s service.StockService, total int) {
+	t.Helper()
+	if err := s.InitStock(context.Background(), eventID, category, total); err != nil {
+		t.Fatalf("init stock: %v", err)
+	}
+}
+
+func mustReserve(t *testing.T, s service.StockService, meta service.ReservationMeta) {
+	t.Helper()
+	if err := s.Reserve(context.Background(), meta, time.Minute); err != nil {
+		t.Fatalf("reserve %s: %v", meta.ReservationID, err)
+	}
+}
+
+func expectStock(t *testing.T, s service.StockService, want int) {
+	t.Helper()
+	stocks, err := s.GetStocks(context.Background(), eventID, []string{category})
+	if err != nil {
+		t.Fatalf("get stocks: %v", err)
+	}
+	if stocks[category] != want {
+		t.Fatalf("expected stock %d, got %d", want, stocks[category])
+	}
+}
+
+func testInitAndGet(t *testing.T, s service.StockService) {
+	mustInit(t, s, 10)
+	stocks, err := s.GetStocks(context.Background(), eventID, []string{category, "MISSING"})
+	if err != nil {
+		t.Fatalf("get stocks: %v", err)
+	}
+	if stocks[category] != 10 || stocks["MISSING"] != 0 {
+		t.Fatalf("unexpected stocks %+v", stocks)
+	}
+}
+
+func testReserveDecrements(t *testing.T, s service.StockService) {
+	mustInit(t, s, 10)
+	mustReserve(t, s, hold("res-1", 3))
+	expectStock(t, s, 7)
+	meta, err := s.GetReservation(context.Background(), "res-1")
+	if err != nil {
+		t.Fatalf("get reservation: %v", err)
+	}
+	if meta.Status != "reserved" || meta.Qty != 3 || meta.UserID != "user-res-1" {
+		t.Fatalf("unexpected reservation %+v", meta)
+	}
+}
+
+func testReserveOutOfStock(t *testing.T, s service.StockService) {
+	mustInit(t, s, 2)
+	if err := s.Reserve(context.Background(), hold("res-1", 3), time.Minute); !errors.Is(err, service.ErrOutOfStock) {
+		t.Fatalf("expected out of stock, got %v", err)
+	}
+	expectStock(t, s, 2)
+	if _, err := s.GetReservation(context.Background(), "res-1"); !errors.Is(err, service.ErrReservationNotFound) {
+		t.Fatalf("expected no reservation, got %v", err)
+	}
+}
+
+func testConfirmFinalizes(t *testing.T, s service.StockService) {
+	ctx := context.Background()
+	mustInit(t, s, 10)
+	mustReserve(t, s, hold("res-1", 2))
+	if err := s.ConfirmReservation(ctx, "res-1"); err != nil {
+		t.Fatalf("confirm: %v", err)
+	}
+	if err := s.ConfirmReservation(ctx, "res-1"); !errors.Is(err, service.ErrReservationFinalized) {
+		t.Fatalf("expected finalized on second confirm, got %v", err)
+	}
+	if _, err := s.ReleaseReservation(ctx, "res-1"); !errors.Is(err, service.ErrReservationFinalized) {
+		t.Fatalf("expected finalized on release after confirm, got %v", err)
+	}
+	if meta, err := s.GetReservation(ctx, "res-1"); err != nil || meta.Status != "confirmed" {
+		t.Fatalf("expected confirmed reservation, got %+v (%v)", meta, err)
+	}
+	expectStock(t, s, 8)
+}
+
+func testReleaseRestoresStock(t *testing.T, s service.StockService) {
+	ctx := context.Background()
+	mustInit(t, s, 10)
+	mustReserve(t, s, hold("res-1", 4))
+	meta, err := s.ReleaseReservation(ctx, "res-1")
+	if err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if meta.Qty != 4 || meta.Status != "expired" {
+		t.Fatalf("unexpected released meta %+v", meta)
+	}
+	expectStock(t, s, 10)
+	if _, err := s.ReleaseReservation(ctx, "res-1"); err == nil {
+		t.Fatal("expected second release to fail")
+	}
+	if err := s.ConfirmReservation(ctx, "res-1"); !errors.Is(err, service.ErrReservationFinalized) {
+		t.Fatalf("expected finalized on confirm after release, got %v", err)
+	}
+	if _, err := s.GetReservation(ctx, "res-1"); !errors.Is(err, service.ErrReservationReleased) {
+		t.Fatalf("expected released hold reported, got %v", err)
+	}
+	expectStock(t, s, 10)
+}
+
+func testReleaseExpired(t *testing.T, s service.StockService) {
+	ctx := context.Background()
+	mustInit(t, s, 10)
+	mustReserve(t, s, hold("res-1", 2))
+	mustReserve(t, s, hold("res-2", 3))
+	if err := s.ConfirmReservation(ctx, "res-2"); err != nil {
+		t.Fatalf("confirm: %v", err)
+	}
+	if items, err := s.ReleaseExpired(ctx, time.Now(), 10, nil); err != nil || len(items) != 0 {
+		t.Fatalf("expected nothing due yet, got %+v (%v)", items, err)
+	}
+	items, err := s.ReleaseExpired(ctx, time.Now().Add(time.Hour), 10, nil)
+	if err != nil {
+		t.Fatalf("release expired: %v", err)
+	}
+	if len(items) != 1 || items[0].ReservationID != "res-1" || items[0].Qty != 2 {
+		t.Fatalf("expected only res-1 released, got %+v", items)
+	}
+	expectStock(t, s, 7)
+	if items, err := s.ReleaseExpired(ctx, time.Now().Add(time.Hour), 10, nil); err != nil || len(items) != 0 {
+		t.Fatalf("expected second sweep to be empty, got %+v (%v)", items, err)
+	}
+}
+
+// testFinalizeRecordsEvents checks that confirm and both release paths
+// record their events only when they flip the hold.
+func testFinalizeRecordsEvents(t *testing.T, s service.StockService) {
+	outbox, ok := s.(service.OutboxStore)
+	if !ok {
+		t.Skip("store keeps no outbox")
+	}
+	ctx := context.Background()
+	event := func(id string) service.OutboxEvent {
+		return service.OutboxEvent{ID: id, Topic: "ticket.test", Key: eventID, Payload: []byte(id)}
+	}
+	mustInit(t, s, 10)
+	mustReserve(t, s, hold("res-1", 1))
+	mustReserve(t, s, hold("res-2", 1))
+	mustReserve(t, s, hold("res-3", 1))
+	if err := s.ConfirmReservation(ctx, "res-1", event("confirmed:res-1")); err != nil {
+		t.Fatalf("confirm: %v", err)
+	}
+	if err := s.ConfirmReservation(ctx, "res-1", event("confirmed:again")); !errors.Is(err, service.ErrReservationFinalized) {
+		t.Fatalf("expected finalized, got %v", err)
+	}
+	if _, err := s.ReleaseReservation(ctx, "res-2", event("released:res-2")); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if _, err := s.ReleaseReservation(ctx, "res-1", event("released:res-1")); !errors.Is(err, service.ErrReservationFinalized) {
+		t.Fatalf("expected finalized, got %v", err)
+	}
+	items, err := s.ReleaseExpired(ctx, time.Now().Add(time.Hour), 10, func(meta service.ReservationMeta) (service.OutboxEvent, error) {
+		return event("expired:" + meta.ReservationID), nil
+	})
+	if err != nil || len(items) != 1 {
+		t.Fatalf("release expired: %+v (%v)", items, err)
+	}
+	pending, err := outbox.Pending(ctx, 10)
+	if err != nil {
+		t.Fatalf("pending: %v", err)
+	}
+	// Sharded stores keep each reservation's events in its own partition, so
+	// only the set is compared.
+	got := make([]string, 0, len(pending))
+	for _, e := range pending {
+		got = append(got, e.ID)
+	}
+	sort.Strings(got)
+	want := []string{"confirmed:res-1", "expired:res-3", "released:res-2"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected events %v, got %v", want, got)
+	}
+}
+
+func testUnknownReservation(t *testing.T, s service.StockService) {
+	ctx := context.Background()
+	if _, err := s.GetReservation(ctx, "nope"); !errors.Is(err, service.ErrReservationNotFound) {
+		t.Fatalf("get: expected not found, got %v", err)
+	}
+	if err := s.ConfirmReservation(ctx, "nope"); !errors.Is(err, service.ErrReservationNotFound) {
+		t.Fatalf("confirm: expected not found, got %v", err)
+	}
+	if _, err := s.ReleaseReservation(ctx, "nope"); !errors.Is(err, service.ErrReservationNotFound) {
+		t.Fatalf("release: expected not found, got %v", err)
+	}
+}
+
+func testConcurrentReserve(t *testing.T, s service.StockService) {
+	const total, buyers = 20, 60
+	mustInit(t, s, total)
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+		ok int
+	)
+	for i := 0; i < buyers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := s.Reserve(context.Background(), hold("res-"+strconv.Itoa(i), 1), time.Minute)
+			if err == nil {
+				mu.Lock()
+				ok++
+				mu.Unlock()
+			} else if !errors.Is(err, service.ErrOutOfStock) {
+				t.Errorf("reserve %d: %v", i, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if ok != total {
+		t.Fatalf("expected %d successful reservations, got %d", total, ok)
+	}
+	expectStock(t, s, 0)
+}
+
+func testConfirmReleaseRace(t *testing.T, s service.StockService) {
+	const rounds = 20
+	mustInit(t, s, rounds)
+	confirmed := 0
+	for i := 0; i < rounds; i++ {
+		id := "race-" + strconv.Itoa(i)
+		mustReserve(t, s, hold(id, 1))
+		var (
+			wg         sync.WaitGroup
+			confirmErr error
+			releaseErr error
+		)
+		wg.Add(2)
+		go func() { defer wg.Done(); confirmErr = s.ConfirmReservation(context.Background(), id) }()
+		go func() { defer wg.Done(); _, releaseErr = s.ReleaseReservation(context.Background(), id) }()
+		wg.Wait()
+		if (confirmErr == nil) == (releaseErr == nil) {
+			t.Fatalf("round %d: expected exactly one winner, confirm=%v release=%v", i, confirmErr, releaseErr)
+		}
+		if confirmErr == nil {
+			confirmed++
+		}
+	}
+	expectStock(t, s, rounds-confirmed)
+}
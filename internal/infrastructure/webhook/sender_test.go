@@ -0,0 +1,58 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"concert-booking/internal/domain/service"
+)
+
+func TestSendSignsPayload(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	body := []byte(`{"id":"evt-1"}`)
+	var got http.Header
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	s := NewSender(time.Second, func() time.Time { return now })
+	status, err := s.Send(context.Background(), service.WebhookRequest{URL: srv.URL, Secret: "s3cret", DeliveryID: "d1", EventType: "concert.ticket.confirmed", Body: body})
+	if err != nil || status != http.StatusNoContent {
+		t.Fatalf("send: %d %v", status, err)
+	}
+	if got.Get(HeaderID) != "d1" || got.Get(HeaderEvent) != "concert.ticket.confirmed" {
+		t.Fatalf("unexpected headers %v", got)
+	}
+	if err := Verify("s3cret", got.Get(HeaderSignature), gotBody, 5*time.Minute, now); err != nil {
+		t.Fatalf("signature should verify: %v", err)
+	}
+	if err := Verify("other", got.Get(HeaderSignature), gotBody, 5*time.Minute, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected a wrong secret to fail, got %v", err)
+	}
+	if err := Verify("s3cret", got.Get(HeaderSignature), []byte(`{"id":"evt-2"}`), 5*time.Minute, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected a tampered body to fail, got %v", err)
+	}
+	if err := Verify("s3cret", got.Get(HeaderSignature), gotBody, 5*time.Minute, now.Add(time.Hour)); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("expected a stale signature to fail, got %v", err)
+	}
+}
+
+func TestSendReportsErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+	status, err := NewSender(time.Second, time.Now).Send(context.Background(), service.WebhookRequest{URL: srv.URL, Body: []byte(`{}`)})
+	if err == nil || status != http.StatusBadGateway {
+		t.Fatalf("expected a failed attempt with status 502, got %d %v", status, err)
+	}
+}
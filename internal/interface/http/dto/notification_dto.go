@@ -0,0 +1,60 @@
+package dto
+
+import (
+	"time"
+
+	"concert-booking/internal/domain/entity"
+)
+
+type NotificationPreferenceRequest struct {
+	Email        string `json:"email"`
+	Phone        string `json:"phone"`
+	Locale       string `json:"locale"`
+	EmailEnabled bool   `json:"email_enabled"`
+	SMSEnabled   bool   `json:"sms_enabled"`
+}
+
+type NotificationPreferenceResponse struct {
+	Email        string     `json:"email"`
+	Phone        string     `json:"phone"`
+	Locale       string     `json:"locale"`
+	EmailEnabled bool       `json:"email_enabled"`
+	SMSEnabled   bool       `json:"sms_enabled"`
+	UpdatedAt    *time.Time `json:"updated_at,omitempty"`
+}
+
+type NotificationResponse struct {
+	ID            string     `json:"id"`
+	ReservationID string     `json:"reservation_id"`
+	Kind          string     `json:"kind"`
+	Channel       string     `json:"channel"`
+	Recipient     string     `json:"recipient"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error,omitempty"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+func NewNotificationPreferenceResponse(p entity.NotificationPreference) NotificationPreferenceResponse {
+	out := NotificationPreferenceResponse{Email: p.Email, Phone: p.Phone, Locale: p.Locale, EmailEnabled: p.EmailEnabled, SMSEnabled: p.SMSEnabled}
+	if !p.UpdatedAt.IsZero() {
+		out.UpdatedAt = &p.UpdatedAt
+	}
+	return out
+}
+
+func NewNotificationResponse(n entity.Notification) NotificationResponse {
+	return NotificationResponse{
+		ID:            n.ID,
+		ReservationID: n.ReservationID,
+		Kind:          n.Kind,
+		Channel:       n.Channel,
+		Recipient:     n.Recipient,
+		Status:        n.Status,
+		Attempts:      n.Attempts,
+		LastError:     n.LastError,
+		SentAt:        n.SentAt,
+		CreatedAt:     n.CreatedAt,
+	}
+}
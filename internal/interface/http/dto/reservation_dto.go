@@ -1,5 +1,11 @@
 package dto
 
+import (
+	"time"
+
+	"concert-booking/internal/domain/entity"
+)
+
 type ReserveRequest struct {
 	EventID  string `json:"event_id"`
 	Category string `json:"category"`
@@ -10,3 +16,31 @@ type ConfirmRequest struct {
 	ReservationID string `json:"reservation_id"`
 	PaymentOK     bool   `json:"payment_ok"`
 }
+
+type ReservationRequestResponse struct {
+	RequestID     string     `json:"request_id"`
+	Status        string     `json:"status"`
+	EventID       string     `json:"event_id"`
+	Category      string     `json:"category"`
+	Qty           int        `json:"qty"`
+	ReservationID string     `json:"reservation_id,omitempty"`
+	ExpiredAt     *time.Time `json:"expired_at,omitempty"`
+	Reason        string     `json:"reason,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+func NewReservationRequestResponse(r entity.ReservationRequest) ReservationRequestResponse {
+	return ReservationRequestResponse{
+		RequestID:     r.ID,
+		Status:        r.Status,
+		EventID:       r.EventID,
+		Category:      r.Category,
+		Qty:           r.Qty,
+		ReservationID: r.ReservationID,
+		ExpiredAt:     r.ExpiredAt,
+		Reason:        r.Reason,
+		CreatedAt:     r.CreatedAt,
+		UpdatedAt:     r.UpdatedAt,
+	}
+}
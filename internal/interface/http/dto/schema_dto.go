@@ -0,0 +1,10 @@
+package dto
+
+type RegisterSchemaRequest struct {
+	Schema string `json:"schema"`
+}
+
+type SchemaCompatibilityResponse struct {
+	Compatible bool     `json:"compatible"`
+	Problems   []string `json:"problems,omitempty"`
+}
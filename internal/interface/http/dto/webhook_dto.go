@@ -0,0 +1,89 @@
+package dto
+
+import (
+	"encoding/json"
+	"time"
+
+	"concert-booking/internal/domain/entity"
+)
+
+type CreateWebhookRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	Secret     string   `json:"secret,omitempty"`
+}
+
+type UpdateWebhookRequest struct {
+	URL        *string   `json:"url,omitempty"`
+	EventTypes *[]string `json:"event_types,omitempty"`
+	Active     *bool     `json:"active,omitempty"`
+}
+
+// WebhookResponse never carries the secret; CreateWebhookResponse returns
+// it once.
+type WebhookResponse struct {
+	ID                  string    `json:"id"`
+	URL                 string    `json:"url"`
+	EventTypes          []string  `json:"event_types"`
+	Active              bool      `json:"active"`
+	DisabledReason      string    `json:"disabled_reason,omitempty"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+type CreateWebhookResponse struct {
+	WebhookResponse
+	Secret string `json:"secret"`
+}
+
+type WebhookDeliveryResponse struct {
+	ID             string          `json:"id"`
+	EventID        string          `json:"event_id"`
+	EventType      string          `json:"event_type"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	ResponseStatus int             `json:"response_status,omitempty"`
+	LastError      string          `json:"last_error,omitempty"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	Payload        json.RawMessage `json:"payload" swaggertype:"object"`
+}
+
+func NewWebhookResponse(w entity.Webhook) WebhookResponse {
+	types := w.EventTypes
+	if types == nil {
+		types = []string{}
+	}
+	return WebhookResponse{
+		ID:                  w.ID,
+		URL:                 w.URL,
+		EventTypes:          types,
+		Active:              w.Active,
+		DisabledReason:      w.DisabledReason,
+		ConsecutiveFailures: w.ConsecutiveFailures,
+		CreatedAt:           w.CreatedAt,
+		UpdatedAt:           w.UpdatedAt,
+	}
+}
+
+func NewWebhookDeliveryResponse(d entity.WebhookDelivery) WebhookDeliveryResponse {
+	out := WebhookDeliveryResponse{
+		ID:             d.ID,
+		EventID:        d.EventID,
+		EventType:      d.EventType,
+		Status:         d.Status,
+		Attempts:       d.Attempts,
+		ResponseStatus: d.ResponseStatus,
+		LastError:      d.LastError,
+		DeliveredAt:    d.DeliveredAt,
+		CreatedAt:      d.CreatedAt,
+		Payload:        json.RawMessage(d.Payload),
+	}
+	if d.Status == entity.DeliveryPending {
+		next := d.NextAttemptAt
+		out.NextAttemptAt = &next
+	}
+	return out
+}
@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"net/http"
+
+	"concert-booking/internal/infrastructure/memory"
+)
+
+// BusHandler exposes the in-process event bus of memory mode.
+type BusHandler struct {
+	bus *memory.EventBus
+}
+
+func NewBusHandler(bus *memory.EventBus) *BusHandler {
+	return &BusHandler{bus: bus}
+}
+
+// Topics godoc
+// @Summary List event bus topics (memory mode)
+// @Description Published and rejected counts per topic, and queue depth and outcomes per subscriber group.
+// @Tags bus
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} memory.TopicStats
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Router /bus/topics [get]
+func (h *BusHandler) Topics(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, h.bus.Topics())
+}
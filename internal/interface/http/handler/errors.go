@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"concert-booking/internal/domain/repository"
+)
+
+// statusClientClosedRequest is the nginx convention for a client that
+// disconnected before the response was written.
+const statusClientClosedRequest = 499
+
+func contextErrorStatus(err error) (int, bool) {
+	switch {
+	case errors.Is(err, repository.ErrQueryTimeout), errors.Is(err, context.DeadlineExceeded):
+		return http.StatusServiceUnavailable, true
+	case errors.Is(err, repository.ErrQueryCanceled), errors.Is(err, context.Canceled):
+		return statusClientClosedRequest, true
+	}
+	return 0, false
+}
@@ -31,6 +31,7 @@ func NewEventHandler(usecase *usecase.EventUsecase) *EventHandler {
 // @Failure 401 {object} dto.ErrorResponse
 // @Failure 403 {object} dto.ErrorResponse
 // @Failure 500 {object} dto.ErrorResponse
+// @Failure 503 {object} dto.ErrorResponse
 // @Router /events [post]
 func (h *EventHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
 	var req dto.CreateEventRequest
@@ -43,12 +44,15 @@ func (h *EventHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "invalid date format", http.StatusBadRequest)
 		return
 	}
-	e, err := h.usecase.CreateEvent(req.Name, date)
+	e, err := h.usecase.CreateEvent(r.Context(), req.Name, date)
 	if err != nil {
 		status := http.StatusInternalServerError
 		if errors.Is(err, usecase.ErrInvalidInput) {
 			status = http.StatusBadRequest
 		}
+		if s, ok := contextErrorStatus(err); ok {
+			status = s
+		}
 		http.Error(w, err.Error(), status)
 		return
 	}
@@ -71,6 +75,7 @@ func (h *EventHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
 // @Failure 403 {object} dto.ErrorResponse
 // @Failure 404 {object} dto.ErrorResponse
 // @Failure 500 {object} dto.ErrorResponse
+// @Failure 503 {object} dto.ErrorResponse
 // @Router /events/{id}/ticket-category [post]
 func (h *EventHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
 	eventID := strings.TrimSpace(r.PathValue("id"))
@@ -79,7 +84,7 @@ func (h *EventHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "bad request", http.StatusBadRequest)
 		return
 	}
-	c, err := h.usecase.CreateCategory(eventID, req.Name, req.TotalStock, req.Price)
+	c, err := h.usecase.CreateCategory(r.Context(), eventID, req.Name, req.TotalStock, req.Price)
 	if err != nil {
 		status := http.StatusInternalServerError
 		if errors.Is(err, usecase.ErrInvalidInput) {
@@ -88,6 +93,9 @@ func (h *EventHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
 		if errors.Is(err, usecase.ErrNotFound) {
 			status = http.StatusNotFound
 		}
+		if s, ok := contextErrorStatus(err); ok {
+			status = s
+		}
 		http.Error(w, err.Error(), status)
 		return
 	}
@@ -105,10 +113,11 @@ func (h *EventHandler) CreateCategory(w http.ResponseWriter, r *http.Request) {
 // @Failure 400 {object} dto.ErrorResponse
 // @Failure 404 {object} dto.ErrorResponse
 // @Failure 500 {object} dto.ErrorResponse
+// @Failure 503 {object} dto.ErrorResponse
 // @Router /events/{id}/availability [get]
 func (h *EventHandler) Availability(w http.ResponseWriter, r *http.Request) {
 	eventID := strings.TrimSpace(r.PathValue("id"))
-	availability, err := h.usecase.Availability(eventID)
+	availability, err := h.usecase.Availability(r.Context(), eventID)
 	if err != nil {
 		status := http.StatusInternalServerError
 		if errors.Is(err, usecase.ErrInvalidInput) {
@@ -117,6 +126,9 @@ func (h *EventHandler) Availability(w http.ResponseWriter, r *http.Request) {
 		if errors.Is(err, usecase.ErrNotFound) {
 			status = http.StatusNotFound
 		}
+		if s, ok := contextErrorStatus(err); ok {
+			status = s
+		}
 		http.Error(w, err.Error(), status)
 		return
 	}
@@ -3,21 +3,38 @@ package handler
 import (
 	"encoding/json"
 	"net/http"
+
+	"concert-booking/internal/usecase"
 )
 
-type HealthHandler struct{}
+type HealthHandler struct {
+	rehydration *usecase.RehydrateUsecase
+}
+
+func NewHealthHandler(rehydration *usecase.RehydrateUsecase) *HealthHandler {
+	return &HealthHandler{rehydration: rehydration}
+}
 
-func NewHealthHandler() *HealthHandler {
-	return &HealthHandler{}
+type healthResponse struct {
+	Status      string                     `json:"status"`
+	Rehydration *usecase.RehydrationStatus `json:"rehydration,omitempty"`
 }
 
 // Handle godoc
 // @Summary Health check
 // @Tags system
 // @Produce json
-// @Success 200 {object} map[string]string
+// @Success 200 {object} map[string]any
 // @Router /health [get]
 func (h *HealthHandler) Handle(w http.ResponseWriter, _ *http.Request) {
+	resp := healthResponse{Status: "ok"}
+	if h.rehydration != nil {
+		st := h.rehydration.Status()
+		resp.Rehydration = &st
+		if len(st.PendingEvents) > 0 || st.State == usecase.RehydrationPending || st.State == usecase.RehydrationFailed {
+			resp.Status = "degraded"
+		}
+	}
 	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+	_ = json.NewEncoder(w).Encode(resp)
 }
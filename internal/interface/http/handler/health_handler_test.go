@@ -7,7 +7,7 @@ import (
 )
 
 func TestHealthHandler(t *testing.T) {
-	h := NewHealthHandler()
+	h := NewHealthHandler(nil)
 	req := httptest.NewRequest(http.MethodGet, "/health", nil)
 	rr := httptest.NewRecorder()
 
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"concert-booking/internal/usecase"
+)
+
+type LedgerHandler struct {
+	usecase *usecase.LedgerUsecase
+}
+
+func NewLedgerHandler(usecase *usecase.LedgerUsecase) *LedgerHandler {
+	return &LedgerHandler{usecase: usecase}
+}
+
+// Entries godoc
+// @Summary List stock ledger entries of a ticket category
+// @Tags ledger
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Event ID"
+// @Param category path string true "Category name"
+// @Success 200 {array} entity.StockMovement
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /events/{id}/categories/{category}/ledger [get]
+func (h *LedgerHandler) Entries(w http.ResponseWriter, r *http.Request) {
+	entries, err := h.usecase.Entries(r.Context(), r.PathValue("id"), r.PathValue("category"))
+	if err != nil {
+		writeLedgerError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(entries)
+}
+
+// Verify godoc
+// @Summary Verify stock ledger against live remaining stock
+// @Tags ledger
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Event ID"
+// @Param category path string true "Category name"
+// @Success 200 {object} usecase.LedgerVerification
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /events/{id}/categories/{category}/ledger/verify [get]
+func (h *LedgerHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	result, err := h.usecase.Verify(r.Context(), r.PathValue("id"), r.PathValue("category"))
+	if err != nil {
+		writeLedgerError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(result)
+}
+
+func writeLedgerError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, usecase.ErrInvalidInput):
+		status = http.StatusBadRequest
+	case errors.Is(err, usecase.ErrNotFound):
+		status = http.StatusNotFound
+	}
+	if s, ok := contextErrorStatus(err); ok {
+		status = s
+	}
+	http.Error(w, err.Error(), status)
+}
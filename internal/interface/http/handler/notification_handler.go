@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"concert-booking/internal/domain/entity"
+	"concert-booking/internal/interface/http/dto"
+	"concert-booking/internal/usecase"
+)
+
+type NotificationHandler struct {
+	usecase *usecase.NotificationUsecase
+}
+
+func NewNotificationHandler(usecase *usecase.NotificationUsecase) *NotificationHandler {
+	return &NotificationHandler{usecase: usecase}
+}
+
+// Preferences godoc
+// @Summary Get my notification preferences
+// @Tags notifications
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} dto.NotificationPreferenceResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /me/notification-preferences [get]
+func (h *NotificationHandler) Preferences(w http.ResponseWriter, r *http.Request) {
+	p, err := h.usecase.Preferences(r.Context(), strings.TrimSpace(r.Header.Get("X-User-ID")))
+	if err != nil {
+		writeNotificationError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, dto.NewNotificationPreferenceResponse(p))
+}
+
+// SetPreferences godoc
+// @Summary Replace my notification preferences
+// @Description Locale picks the template language (e.g. en, id, id-ID); unknown locales fall back to the default.
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.NotificationPreferenceRequest true "Preferences"
+// @Success 200 {object} dto.NotificationPreferenceResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /me/notification-preferences [put]
+func (h *NotificationHandler) SetPreferences(w http.ResponseWriter, r *http.Request) {
+	var req dto.NotificationPreferenceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	p, err := h.usecase.SetPreferences(r.Context(), entity.NotificationPreference{
+		UserID:       strings.TrimSpace(r.Header.Get("X-User-ID")),
+		Email:        req.Email,
+		Phone:        req.Phone,
+		Locale:       req.Locale,
+		EmailEnabled: req.EmailEnabled,
+		SMSEnabled:   req.SMSEnabled,
+	})
+	if err != nil {
+		writeNotificationError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, dto.NewNotificationPreferenceResponse(p))
+}
+
+// History godoc
+// @Summary List notifications sent to me
+// @Tags notifications
+// @Produce json
+// @Security BearerAuth
+// @Param limit query int false "Maximum notifications, newest first (default 50, max 500)"
+// @Success 200 {array} dto.NotificationResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /me/notifications [get]
+func (h *NotificationHandler) History(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	items, err := h.usecase.History(r.Context(), strings.TrimSpace(r.Header.Get("X-User-ID")), limit)
+	if err != nil {
+		writeNotificationError(w, err)
+		return
+	}
+	out := make([]dto.NotificationResponse, 0, len(items))
+	for _, n := range items {
+		out = append(out, dto.NewNotificationResponse(n))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func writeNotificationError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	if errors.Is(err, usecase.ErrInvalidInput) {
+		status = http.StatusBadRequest
+	}
+	if s, ok := contextErrorStatus(err); ok {
+		status = s
+	}
+	http.Error(w, err.Error(), status)
+}
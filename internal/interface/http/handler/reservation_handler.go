@@ -37,6 +37,7 @@ func NewReservationHandler(usecase *usecase.ReservationUsecase) *ReservationHand
 // @Failure 409 {object} dto.ErrorResponse
 // @Failure 429 {object} dto.ErrorResponse
 // @Failure 500 {object} dto.ErrorResponse
+// @Failure 503 {object} dto.ErrorResponse
 // @Router /reserve [post]
 func (h *ReservationHandler) Reserve(w http.ResponseWriter, r *http.Request) {
 	var req dto.ReserveRequest
@@ -54,11 +55,16 @@ func (h *ReservationHandler) Reserve(w http.ResponseWriter, r *http.Request) {
 			status = http.StatusBadRequest
 		case errors.Is(err, usecase.ErrQueueFull):
 			status = http.StatusTooManyRequests
+		case errors.Is(err, usecase.ErrEventNotReady):
+			status = http.StatusServiceUnavailable
 		case errors.Is(err, usecase.ErrNotFound):
 			status = http.StatusNotFound
 		case errors.Is(err, service.ErrOutOfStock):
 			status = http.StatusConflict
 		}
+		if s, ok := contextErrorStatus(err); ok {
+			status = s
+		}
 		http.Error(w, err.Error(), status)
 		return
 	}
@@ -82,6 +88,7 @@ func (h *ReservationHandler) Reserve(w http.ResponseWriter, r *http.Request) {
 // @Failure 402 {object} dto.ErrorResponse
 // @Failure 404 {object} dto.ErrorResponse
 // @Failure 500 {object} dto.ErrorResponse
+// @Failure 503 {object} dto.ErrorResponse
 // @Router /confirm [post]
 func (h *ReservationHandler) Confirm(w http.ResponseWriter, r *http.Request) {
 	var req dto.ConfirmRequest
@@ -100,6 +107,9 @@ func (h *ReservationHandler) Confirm(w http.ResponseWriter, r *http.Request) {
 		case err.Error() == "payment failed":
 			status = http.StatusPaymentRequired
 		}
+		if s, ok := contextErrorStatus(err); ok {
+			status = s
+		}
 		http.Error(w, err.Error(), status)
 		return
 	}
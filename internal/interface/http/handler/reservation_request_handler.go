@@ -0,0 +1,149 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"concert-booking/internal/domain/service"
+	"concert-booking/internal/interface/http/dto"
+	"concert-booking/internal/observability/metrics"
+	"concert-booking/internal/usecase"
+)
+
+// ReservationRequestHandler serves POST /reserve in async reserve mode and
+// the status of queued requests.
+type ReservationRequestHandler struct {
+	usecase *usecase.ReservationRequestUsecase
+	// poll is how often a status stream rereads the request, heartbeat keeps
+	// idle proxies from closing it, and maxStream ends a stream that is
+	// still queued so the client reconnects.
+	poll      time.Duration
+	heartbeat time.Duration
+	maxStream time.Duration
+}
+
+func NewReservationRequestHandler(usecase *usecase.ReservationRequestUsecase) *ReservationRequestHandler {
+	return &ReservationRequestHandler{usecase: usecase, poll: 200 * time.Millisecond, heartbeat: 15 * time.Second, maxStream: 2 * time.Minute}
+}
+
+// Reserve godoc
+// @Summary Queue a reservation (async reserve mode)
+// @Description The request is allocated in arrival order per event; poll the Location or stream it with Accept: text/event-stream.
+// @Tags reservation
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.ReserveRequest true "Reserve payload"
+// @Success 202 {object} dto.ReservationRequestResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Failure 503 {object} dto.ErrorResponse
+// @Router /reserve [post]
+func (h *ReservationRequestHandler) Reserve(w http.ResponseWriter, r *http.Request) {
+	var req dto.ReserveRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	userID := strings.TrimSpace(r.Header.Get("X-User-ID"))
+	queued, err := h.usecase.Enqueue(r.Context(), userID, req.EventID, req.Category, req.Qty)
+	if err != nil {
+		metrics.IncReservationFailed()
+		writeReservationRequestError(w, err)
+		return
+	}
+	w.Header().Set("Location", "/reserve/requests/"+queued.ID)
+	writeJSON(w, http.StatusAccepted, dto.NewReservationRequestResponse(queued))
+}
+
+// Get godoc
+// @Summary Get a queued reservation request
+// @Description With Accept: text/event-stream the status is streamed as "status" events until it is final.
+// @Tags reservation
+// @Produce json
+// @Produce text/event-stream
+// @Security BearerAuth
+// @Param id path string true "Request ID"
+// @Success 200 {object} dto.ReservationRequestResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /reserve/requests/{id} [get]
+func (h *ReservationRequestHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID := strings.TrimSpace(r.Header.Get("X-User-ID"))
+	req, err := h.usecase.Get(r.Context(), userID, r.PathValue("id"))
+	if err != nil {
+		writeReservationRequestError(w, err)
+		return
+	}
+	if !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		writeJSON(w, http.StatusOK, dto.NewReservationRequestResponse(req))
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	// The server write timeout is meant for ordinary responses.
+	_ = rc.SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	poll := time.NewTicker(h.poll)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+	deadline := time.After(h.maxStream)
+	last := ""
+	for {
+		if req.Status != last || req.Done() {
+			data, _ := json.Marshal(dto.NewReservationRequestResponse(req))
+			_, _ = fmt.Fprintf(w, "event: status\ndata: %s\n\n", data)
+			last = req.Status
+		}
+		if err := rc.Flush(); err != nil || req.Done() {
+			return
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-deadline:
+			return
+		case <-heartbeat.C:
+			_, _ = fmt.Fprint(w, ": ping\n\n")
+			continue
+		case <-poll.C:
+		}
+		next, err := h.usecase.Get(r.Context(), userID, req.ID)
+		if err != nil {
+			// Keep the last status; the next tick tries again.
+			continue
+		}
+		req = next
+	}
+}
+
+func writeReservationRequestError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, usecase.ErrInvalidInput):
+		status = http.StatusBadRequest
+	case errors.Is(err, usecase.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, service.ErrOutOfStock):
+		status = http.StatusConflict
+	case errors.Is(err, usecase.ErrEventNotReady), errors.Is(err, usecase.ErrRequestQueueUnavailable):
+		status = http.StatusServiceUnavailable
+	}
+	if s, ok := contextErrorStatus(err); ok {
+		status = s
+	}
+	http.Error(w, err.Error(), status)
+}
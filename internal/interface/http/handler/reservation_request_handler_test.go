@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"concert-booking/internal/infrastructure/memory"
+	"concert-booking/internal/usecase"
+)
+
+type capturedPublish struct{ payloads chan []byte }
+
+func (p capturedPublish) Publish(_ context.Context, _ string, _ string, value []byte) error {
+	p.payloads <- value
+	return nil
+}
+
+func TestReservationRequestStreamEndsWithFinalStatus(t *testing.T) {
+	ctx := context.Background()
+	stock := memory.NewStockService()
+	_ = stock.InitStock(ctx, "event-1", "VIP", 1)
+	newID := func() string { return "req-1" }
+	reservations := usecase.NewReservationUsecase(memory.NewTicketCategoryRepository(), memory.NewReservationRepository(), memory.NewBookingRepository(), stock, time.Now, newID, time.Minute, 0, 1, true)
+	queue := capturedPublish{payloads: make(chan []byte, 1)}
+	requests := usecase.NewReservationRequestUsecase(reservations, memory.NewReservationRequestRepository(), queue, time.Now, newID)
+	h := NewReservationRequestHandler(requests)
+	h.poll = 5 * time.Millisecond
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/reserve", strings.NewReader(`{"event_id":"event-1","category":"VIP","qty":1}`))
+	req.Header.Set("X-User-ID", "user-1")
+	h.Reserve(rr, req)
+	if rr.Code != http.StatusAccepted || rr.Header().Get("Location") != "/reserve/requests/req-1" {
+		t.Fatalf("expected 202 with location, got %d %q", rr.Code, rr.Header().Get("Location"))
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		_ = requests.Handlers()[usecase.TopicReservationRequests](ctx, <-queue.payloads)
+	}()
+	rr = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/reserve/requests/req-1", nil)
+	req.SetPathValue("id", "req-1")
+	req.Header.Set("X-User-ID", "user-1")
+	req.Header.Set("Accept", "text/event-stream")
+	h.Get(rr, req)
+
+	body := rr.Body.String()
+	if rr.Header().Get("Content-Type") != "text/event-stream" || strings.Count(body, "event: status") != 2 {
+		t.Fatalf("expected a queued and a final status event, got %q", body)
+	}
+	if !strings.Contains(body, `"status":"queued"`) || !strings.Contains(body, `"status":"reserved"`) || !strings.Contains(body, `"reservation_id":"req-1"`) {
+		t.Fatalf("unexpected stream %q", body)
+	}
+}
@@ -0,0 +1,157 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"concert-booking/internal/infrastructure/schemaregistry"
+	"concert-booking/internal/interface/http/dto"
+)
+
+type SchemaHandler struct {
+	registry *schemaregistry.Registry
+}
+
+func NewSchemaHandler(registry *schemaregistry.Registry) *SchemaHandler {
+	return &SchemaHandler{registry: registry}
+}
+
+// Subjects godoc
+// @Summary List schema registry subjects
+// @Tags schemas
+// @Produce json
+// @Success 200 {array} string
+// @Router /schemas/subjects [get]
+func (h *SchemaHandler) Subjects(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, h.registry.Subjects())
+}
+
+// Versions godoc
+// @Summary List versions of a schema subject
+// @Tags schemas
+// @Produce json
+// @Param subject path string true "Subject"
+// @Success 200 {array} int
+// @Failure 404 {object} dto.ErrorResponse
+// @Router /schemas/subjects/{subject}/versions [get]
+func (h *SchemaHandler) Versions(w http.ResponseWriter, r *http.Request) {
+	versions, err := h.registry.Versions(r.PathValue("subject"))
+	if err != nil {
+		writeSchemaError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, versions)
+}
+
+// Version godoc
+// @Summary Get a schema version
+// @Tags schemas
+// @Produce json
+// @Param subject path string true "Subject"
+// @Param version path string true "Version number or latest"
+// @Success 200 {object} schemaregistry.Version
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Router /schemas/subjects/{subject}/versions/{version} [get]
+func (h *SchemaHandler) Version(w http.ResponseWriter, r *http.Request) {
+	version := 0
+	if v := r.PathValue("version"); v != "latest" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid version", http.StatusBadRequest)
+			return
+		}
+		version = n
+	}
+	v, err := h.registry.Get(r.PathValue("subject"), version)
+	if err != nil {
+		writeSchemaError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+// Register godoc
+// @Summary Register a new schema version
+// @Description The schema must be backward compatible with the latest version of the subject.
+// @Tags schemas
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param subject path string true "Subject"
+// @Param request body dto.RegisterSchemaRequest true "Protobuf source"
+// @Success 200 {object} schemaregistry.Version
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.SchemaCompatibilityResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /schemas/subjects/{subject}/versions [post]
+func (h *SchemaHandler) Register(w http.ResponseWriter, r *http.Request) {
+	var req dto.RegisterSchemaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	v, err := h.registry.Register(r.PathValue("subject"), req.Schema)
+	if err != nil {
+		writeSchemaError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, v)
+}
+
+// Compatibility godoc
+// @Summary Check a schema against the latest version without registering it
+// @Tags schemas
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param subject path string true "Subject"
+// @Param request body dto.RegisterSchemaRequest true "Protobuf source"
+// @Success 200 {object} dto.SchemaCompatibilityResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Router /schemas/subjects/{subject}/compatibility [post]
+func (h *SchemaHandler) Compatibility(w http.ResponseWriter, r *http.Request) {
+	var req dto.RegisterSchemaRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	err := h.registry.Check(r.PathValue("subject"), req.Schema)
+	var incompatible *schemaregistry.IncompatibleError
+	switch {
+	case err == nil:
+		writeJSON(w, http.StatusOK, dto.SchemaCompatibilityResponse{Compatible: true})
+	case errors.As(err, &incompatible):
+		writeJSON(w, http.StatusOK, dto.SchemaCompatibilityResponse{Problems: incompatible.Problems})
+	default:
+		writeSchemaError(w, err)
+	}
+}
+
+func writeSchemaError(w http.ResponseWriter, err error) {
+	var incompatible *schemaregistry.IncompatibleError
+	if errors.As(err, &incompatible) {
+		writeJSON(w, http.StatusConflict, dto.SchemaCompatibilityResponse{Problems: incompatible.Problems})
+		return
+	}
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, schemaregistry.ErrInvalidSchema):
+		status = http.StatusBadRequest
+	case errors.Is(err, schemaregistry.ErrSubjectNotFound), errors.Is(err, schemaregistry.ErrVersionNotFound):
+		status = http.StatusNotFound
+	}
+	http.Error(w, err.Error(), status)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
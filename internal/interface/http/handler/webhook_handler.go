@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"concert-booking/internal/interface/http/dto"
+	"concert-booking/internal/usecase"
+)
+
+type WebhookHandler struct {
+	usecase *usecase.WebhookUsecase
+}
+
+func NewWebhookHandler(usecase *usecase.WebhookUsecase) *WebhookHandler {
+	return &WebhookHandler{usecase: usecase}
+}
+
+// Create godoc
+// @Summary Register a webhook
+// @Description An empty event_types list subscribes to every event type. The secret is generated when omitted and is only returned here.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body dto.CreateWebhookRequest true "Webhook"
+// @Success 201 {object} dto.CreateWebhookResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /webhooks [post]
+func (h *WebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	hook, err := h.usecase.Create(r.Context(), req.URL, req.EventTypes, req.Secret)
+	if err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, dto.CreateWebhookResponse{WebhookResponse: dto.NewWebhookResponse(hook), Secret: hook.Secret})
+}
+
+// List godoc
+// @Summary List webhooks
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} dto.WebhookResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /webhooks [get]
+func (h *WebhookHandler) List(w http.ResponseWriter, r *http.Request) {
+	hooks, err := h.usecase.List(r.Context())
+	if err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+	out := make([]dto.WebhookResponse, 0, len(hooks))
+	for _, hook := range hooks {
+		out = append(out, dto.NewWebhookResponse(hook))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// Get godoc
+// @Summary Get a webhook
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} dto.WebhookResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Router /webhooks/{id} [get]
+func (h *WebhookHandler) Get(w http.ResponseWriter, r *http.Request) {
+	hook, err := h.usecase.Get(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, dto.NewWebhookResponse(hook))
+}
+
+// Update godoc
+// @Summary Update a webhook
+// @Description Omitted fields are kept. Setting active to true re-enables a webhook that was disabled after repeated failures.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Webhook ID"
+// @Param request body dto.UpdateWebhookRequest true "Fields to change"
+// @Success 200 {object} dto.WebhookResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Router /webhooks/{id} [patch]
+func (h *WebhookHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var req dto.UpdateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "bad request", http.StatusBadRequest)
+		return
+	}
+	hook, err := h.usecase.Update(r.Context(), r.PathValue("id"), usecase.WebhookPatch{URL: req.URL, EventTypes: req.EventTypes, Active: req.Active})
+	if err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, dto.NewWebhookResponse(hook))
+}
+
+// Delete godoc
+// @Summary Delete a webhook and its delivery log
+// @Tags webhooks
+// @Security BearerAuth
+// @Param id path string true "Webhook ID"
+// @Success 204
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Router /webhooks/{id} [delete]
+func (h *WebhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.usecase.Delete(r.Context(), r.PathValue("id")); err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Deliveries godoc
+// @Summary List recent deliveries of a webhook
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Webhook ID"
+// @Param limit query int false "Maximum deliveries, newest first (default 100, max 500)"
+// @Success 200 {array} dto.WebhookDeliveryResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Router /webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) Deliveries(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	deliveries, err := h.usecase.Deliveries(r.Context(), r.PathValue("id"), limit)
+	if err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+	out := make([]dto.WebhookDeliveryResponse, 0, len(deliveries))
+	for _, d := range deliveries {
+		out = append(out, dto.NewWebhookDeliveryResponse(d))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// Redeliver godoc
+// @Summary Queue a delivery again
+// @Description Resets the attempt count; the dispatcher sends it on its next pass.
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Webhook ID"
+// @Param delivery path string true "Delivery ID"
+// @Success 202 {object} dto.WebhookDeliveryResponse
+// @Failure 401 {object} dto.ErrorResponse
+// @Failure 403 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 409 {object} dto.ErrorResponse
+// @Router /webhooks/{id}/deliveries/{delivery}/redeliver [post]
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	d, err := h.usecase.Redeliver(r.Context(), r.PathValue("id"), r.PathValue("delivery"))
+	if err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, dto.NewWebhookDeliveryResponse(d))
+}
+
+func writeWebhookError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, usecase.ErrInvalidInput):
+		status = http.StatusBadRequest
+	case errors.Is(err, usecase.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, usecase.ErrWebhookDisabled):
+		status = http.StatusConflict
+	}
+	if s, ok := contextErrorStatus(err); ok {
+		status = s
+	}
+	http.Error(w, err.Error(), status)
+}
@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"net/http"
+
+	"concert-booking/internal/domain/service"
+	"concert-booking/internal/domain/ticketevent"
+)
+
+// Trace carries an incoming W3C traceparent header into the request context,
+// so the events the request publishes continue the caller's trace. Malformed
+// values are ignored and the events start a trace of their own.
+func Trace(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if tp := r.Header.Get("traceparent"); ticketevent.ValidTraceParent(tp) {
+			r = r.WithContext(service.WithTraceParent(r.Context(), tp))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -13,8 +13,15 @@ type Dependencies struct {
 	HealthHandler      *handler.HealthHandler
 	EventHandler       *handler.EventHandler
 	ReservationHandler *handler.ReservationHandler
-	Auth               *middleware.AuthMiddleware
-	RateLimiter        *middleware.RateLimiter
+	// ReservationRequestHandler, when set, takes POST /reserve (async mode).
+	ReservationRequestHandler *handler.ReservationRequestHandler
+	LedgerHandler             *handler.LedgerHandler
+	SchemaHandler             *handler.SchemaHandler
+	WebhookHandler            *handler.WebhookHandler
+	NotificationHandler       *handler.NotificationHandler
+	BusHandler                *handler.BusHandler
+	Auth                      *middleware.AuthMiddleware
+	RateLimiter               *middleware.RateLimiter
 }
 
 func New(dep Dependencies) http.Handler {
@@ -28,8 +35,42 @@ func New(dep Dependencies) http.Handler {
 	mux.Handle("POST /events", dep.RateLimiter.Limit(dep.Auth.RequireRole("admin", http.HandlerFunc(dep.EventHandler.CreateEvent))))
 	mux.Handle("POST /events/{id}/ticket-category", dep.RateLimiter.Limit(dep.Auth.RequireRole("admin", http.HandlerFunc(dep.EventHandler.CreateCategory))))
 	mux.Handle("GET /events/{id}/availability", dep.RateLimiter.Limit(http.HandlerFunc(dep.EventHandler.Availability)))
-	mux.Handle("POST /reserve", dep.RateLimiter.Limit(dep.Auth.RequireRole("user", http.HandlerFunc(dep.ReservationHandler.Reserve))))
+	mux.Handle("GET /events/{id}/categories/{category}/ledger", dep.RateLimiter.Limit(dep.Auth.RequireRole("admin", http.HandlerFunc(dep.LedgerHandler.Entries))))
+	mux.Handle("GET /events/{id}/categories/{category}/ledger/verify", dep.RateLimiter.Limit(dep.Auth.RequireRole("admin", http.HandlerFunc(dep.LedgerHandler.Verify))))
+	if dep.ReservationRequestHandler != nil {
+		mux.Handle("POST /reserve", dep.RateLimiter.Limit(dep.Auth.RequireRole("user", http.HandlerFunc(dep.ReservationRequestHandler.Reserve))))
+		mux.Handle("GET /reserve/requests/{id}", dep.RateLimiter.Limit(dep.Auth.RequireRole("user", http.HandlerFunc(dep.ReservationRequestHandler.Get))))
+	} else {
+		mux.Handle("POST /reserve", dep.RateLimiter.Limit(dep.Auth.RequireRole("user", http.HandlerFunc(dep.ReservationHandler.Reserve))))
+	}
 	mux.Handle("POST /confirm", dep.RateLimiter.Limit(dep.Auth.RequireRole("user", http.HandlerFunc(dep.ReservationHandler.Confirm))))
+	if dep.SchemaHandler != nil {
+		mux.HandleFunc("GET /schemas/subjects", dep.SchemaHandler.Subjects)
+		mux.HandleFunc("GET /schemas/subjects/{subject}/versions", dep.SchemaHandler.Versions)
+		mux.HandleFunc("GET /schemas/subjects/{subject}/versions/{version}", dep.SchemaHandler.Version)
+		mux.Handle("POST /schemas/subjects/{subject}/versions", dep.RateLimiter.Limit(dep.Auth.RequireRole("admin", http.HandlerFunc(dep.SchemaHandler.Register))))
+		mux.Handle("POST /schemas/subjects/{subject}/compatibility", dep.RateLimiter.Limit(dep.Auth.RequireRole("admin", http.HandlerFunc(dep.SchemaHandler.Compatibility))))
+	}
+	if dep.WebhookHandler != nil {
+		admin := func(h http.HandlerFunc) http.Handler {
+			return dep.RateLimiter.Limit(dep.Auth.RequireRole("admin", h))
+		}
+		mux.Handle("POST /webhooks", admin(dep.WebhookHandler.Create))
+		mux.Handle("GET /webhooks", admin(dep.WebhookHandler.List))
+		mux.Handle("GET /webhooks/{id}", admin(dep.WebhookHandler.Get))
+		mux.Handle("PATCH /webhooks/{id}", admin(dep.WebhookHandler.Update))
+		mux.Handle("DELETE /webhooks/{id}", admin(dep.WebhookHandler.Delete))
+		mux.Handle("GET /webhooks/{id}/deliveries", admin(dep.WebhookHandler.Deliveries))
+		mux.Handle("POST /webhooks/{id}/deliveries/{delivery}/redeliver", admin(dep.WebhookHandler.Redeliver))
+	}
+	if dep.NotificationHandler != nil {
+		mux.Handle("GET /me/notification-preferences", dep.RateLimiter.Limit(dep.Auth.RequireRole("user", http.HandlerFunc(dep.NotificationHandler.Preferences))))
+		mux.Handle("PUT /me/notification-preferences", dep.RateLimiter.Limit(dep.Auth.RequireRole("user", http.HandlerFunc(dep.NotificationHandler.SetPreferences))))
+		mux.Handle("GET /me/notifications", dep.RateLimiter.Limit(dep.Auth.RequireRole("user", http.HandlerFunc(dep.NotificationHandler.History))))
+	}
+	if dep.BusHandler != nil {
+		mux.Handle("GET /bus/topics", dep.RateLimiter.Limit(dep.Auth.RequireRole("admin", http.HandlerFunc(dep.BusHandler.Topics))))
+	}
 
-	return middleware.Instrument(mux)
+	return middleware.Instrument(middleware.Trace(mux))
 }
@@ -20,6 +20,12 @@ var (
 	requestMu       sync.Mutex
 	httpTotal       = map[string]uint64{}
 	httpDurationSum = map[string]float64{}
+	stockDrift      = map[string]int64{}
+	workerMessages  = map[string]uint64{}
+	producerResults = map[string]uint64{}
+	notifications   = map[string]uint64{}
+	replayMessages  = map[string]uint64{}
+	replayRemaining = map[string]int64{}
 
 	reservationSuccess atomic.Uint64
 	reservationFailed  atomic.Uint64
@@ -27,6 +33,28 @@ var (
 	kafkaLagGauge      atomic.Int64
 	redisMemoryGauge   atomic.Int64
 	dbOpenConnGauge    atomic.Int64
+	outboxPublished    atomic.Uint64
+	outboxFailed       atomic.Uint64
+	outboxParked       atomic.Uint64
+	reconcileRuns      atomic.Uint64
+	stockRepairs       atomic.Uint64
+	stockRebalances    atomic.Uint64
+	reserveBatches     atomic.Uint64
+	reserveBatchItems  atomic.Uint64
+	reserveBatchWaitNs atomic.Uint64
+	reserveBatchMax    atomic.Int64
+	reserveBatchWindow atomic.Int64
+	reaperRuns         atomic.Uint64
+	reaperReleased     atomic.Uint64
+	reaperDurationNs   atomic.Uint64
+	reaperLeaseSkipped atomic.Uint64
+	expiryNotified     atomic.Uint64
+	expiryReleased     atomic.Uint64
+	soldOutRejected    atomic.Uint64
+	webhookSucceeded   atomic.Uint64
+	webhookRetried     atomic.Uint64
+	webhookFailed      atomic.Uint64
+	webhooksDisabled   atomic.Uint64
 )
 
 func ObserveHTTP(method, path string, status int, duration time.Duration) {
@@ -43,10 +71,108 @@ func IncReservationFailed() {
 	reservationFailed.Add(1)
 	failedReservation.Add(1)
 }
+func IncOutboxPublished()    { outboxPublished.Add(1) }
+func IncOutboxFailed()       { outboxFailed.Add(1) }
+func IncOutboxParked()       { outboxParked.Add(1) }
+func IncReconcileRun()       { reconcileRuns.Add(1) }
+func IncStockRepair()        { stockRepairs.Add(1) }
+func IncStockRebalance()     { stockRebalances.Add(1) }
 func SetKafkaLag(v int64)    { kafkaLagGauge.Store(v) }
 func SetRedisMemory(v int64) { redisMemoryGauge.Store(v) }
 func SetDBOpenConn(v int64)  { dbOpenConnGauge.Store(v) }
 
+// ObserveReserveBatch records one flushed reserve batch and how long its
+// first request waited for the flush.
+func ObserveReserveBatch(items int, wait time.Duration) {
+	reserveBatches.Add(1)
+	reserveBatchItems.Add(uint64(items))
+	reserveBatchWaitNs.Add(uint64(wait.Nanoseconds()))
+}
+
+func SetReserveBatchBudget(maxItems int, window time.Duration) {
+	reserveBatchMax.Store(int64(maxItems))
+	reserveBatchWindow.Store(window.Nanoseconds())
+}
+
+// ObserveReaperRun records one expiry sweep and how many holds it released.
+func ObserveReaperRun(released int, d time.Duration) {
+	reaperRuns.Add(1)
+	reaperReleased.Add(uint64(released))
+	reaperDurationNs.Add(uint64(d.Nanoseconds()))
+}
+
+func IncReaperLeaseSkipped()  { reaperLeaseSkipped.Add(1) }
+func IncSoldOutShortCircuit() { soldOutRejected.Add(1) }
+
+// IncExpiryNotification counts an expired-key notification and whether it
+// released the hold (false when the reaper or a client got there first).
+func IncExpiryNotification(released bool) {
+	expiryNotified.Add(1)
+	if released {
+		expiryReleased.Add(1)
+	}
+}
+
+// IncWebhookDelivery counts a webhook delivery attempt: succeeded, retried
+// or failed (attempts exhausted, or the webhook was disabled or deleted).
+func IncWebhookDelivery(result string) {
+	switch result {
+	case "succeeded":
+		webhookSucceeded.Add(1)
+	case "retried":
+		webhookRetried.Add(1)
+	default:
+		webhookFailed.Add(1)
+	}
+}
+
+func IncWebhookDisabled() { webhooksDisabled.Add(1) }
+
+// IncWorkerMessage counts a worker outcome per topic: processed, retried or dead_lettered.
+func IncWorkerMessage(topic, result string) {
+	requestMu.Lock()
+	workerMessages[topic+"|"+result]++
+	requestMu.Unlock()
+}
+
+// IncProducerMessage counts a Kafka write outcome per topic: delivered,
+// failed, and for failed async writes redelivered (retried in place),
+// requeued (back in the outbox at shutdown) or dropped (the fallback failed
+// too).
+func IncProducerMessage(topic, result string) {
+	requestMu.Lock()
+	producerResults[topic+"|"+result]++
+	requestMu.Unlock()
+}
+
+// IncNotification counts a notification outcome per channel: sent, failed
+// or duplicate (already sent for that reservation).
+func IncNotification(channel, result string) {
+	requestMu.Lock()
+	notifications[channel+"|"+result]++
+	requestMu.Unlock()
+}
+
+// IncReplayMessage counts a replayed message per topic: applied, unresolved or invalid.
+func IncReplayMessage(topic, result string) {
+	requestMu.Lock()
+	replayMessages[topic+"|"+result]++
+	requestMu.Unlock()
+}
+
+// SetReplayRemaining reports how many messages of topic the replay still has to read.
+func SetReplayRemaining(topic string, n int64) {
+	requestMu.Lock()
+	replayRemaining[topic] = n
+	requestMu.Unlock()
+}
+
+func SetStockDrift(eventID, category string, drift int) {
+	requestMu.Lock()
+	stockDrift[eventID+"|"+category] = int64(drift)
+	requestMu.Unlock()
+}
+
 func Handler(w http.ResponseWriter, _ *http.Request) {
 	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
 	write(w,
@@ -66,6 +192,62 @@ func Handler(w http.ResponseWriter, _ *http.Request) {
 		"# HELP db_open_connections Database open connections\n",
 		"# TYPE db_open_connections gauge\n",
 		fmt.Sprintf("db_open_connections %d\n", dbOpenConnGauge.Load()),
+		"# HELP outbox_events_total Outbox events relayed to the broker\n",
+		"# TYPE outbox_events_total counter\n",
+		fmt.Sprintf("outbox_events_total{result=\"published\"} %d\n", outboxPublished.Load()),
+		fmt.Sprintf("outbox_events_total{result=\"failed\"} %d\n", outboxFailed.Load()),
+		fmt.Sprintf("outbox_events_total{result=\"parked\"} %d\n", outboxParked.Load()),
+		"# HELP reconcile_runs_total Stock reconciliation runs\n",
+		"# TYPE reconcile_runs_total counter\n",
+		fmt.Sprintf("reconcile_runs_total %d\n", reconcileRuns.Load()),
+		"# HELP stock_repairs_total Stock counters repaired by reconciliation\n",
+		"# TYPE stock_repairs_total counter\n",
+		fmt.Sprintf("stock_repairs_total %d\n", stockRepairs.Load()),
+		"# HELP stock_rebalances_total Stock moved between shard counters\n",
+		"# TYPE stock_rebalances_total counter\n",
+		fmt.Sprintf("stock_rebalances_total %d\n", stockRebalances.Load()),
+		"# HELP reserve_batches_total Reserve batches flushed to Redis\n",
+		"# TYPE reserve_batches_total counter\n",
+		fmt.Sprintf("reserve_batches_total %d\n", reserveBatches.Load()),
+		"# HELP reserve_batch_items_total Reserve requests executed in batches\n",
+		"# TYPE reserve_batch_items_total counter\n",
+		fmt.Sprintf("reserve_batch_items_total %d\n", reserveBatchItems.Load()),
+		"# HELP reserve_batch_wait_seconds Time the first request of each batch waited before the flush, summed\n",
+		"# TYPE reserve_batch_wait_seconds counter\n",
+		fmt.Sprintf("reserve_batch_wait_seconds %.6f\n", time.Duration(reserveBatchWaitNs.Load()).Seconds()),
+		"# HELP reserve_batch_max_items Configured reserve batch size limit\n",
+		"# TYPE reserve_batch_max_items gauge\n",
+		fmt.Sprintf("reserve_batch_max_items %d\n", reserveBatchMax.Load()),
+		"# HELP reserve_batch_window_seconds Configured reserve batch latency budget\n",
+		"# TYPE reserve_batch_window_seconds gauge\n",
+		fmt.Sprintf("reserve_batch_window_seconds %.6f\n", time.Duration(reserveBatchWindow.Load()).Seconds()),
+		"# HELP reaper_runs_total Expiry reaper sweeps\n",
+		"# TYPE reaper_runs_total counter\n",
+		fmt.Sprintf("reaper_runs_total %d\n", reaperRuns.Load()),
+		"# HELP reaper_released_total Reservations released by the expiry reaper\n",
+		"# TYPE reaper_released_total counter\n",
+		fmt.Sprintf("reaper_released_total %d\n", reaperReleased.Load()),
+		"# HELP reaper_duration_seconds Time spent in expiry sweeps, summed\n",
+		"# TYPE reaper_duration_seconds counter\n",
+		fmt.Sprintf("reaper_duration_seconds %.6f\n", time.Duration(reaperDurationNs.Load()).Seconds()),
+		"# HELP reaper_lease_skipped_total Partitions skipped because another replica holds their reaper lease\n",
+		"# TYPE reaper_lease_skipped_total counter\n",
+		fmt.Sprintf("reaper_lease_skipped_total %d\n", reaperLeaseSkipped.Load()),
+		"# HELP expiry_notifications_total Expired reservation key notifications handled\n",
+		"# TYPE expiry_notifications_total counter\n",
+		fmt.Sprintf("expiry_notifications_total{result=\"released\"} %d\n", expiryReleased.Load()),
+		fmt.Sprintf("expiry_notifications_total{result=\"skipped\"} %d\n", expiryNotified.Load()-expiryReleased.Load()),
+		"# HELP soldout_short_circuit_total Reservations rejected by the local sold-out cache\n",
+		"# TYPE soldout_short_circuit_total counter\n",
+		fmt.Sprintf("soldout_short_circuit_total %d\n", soldOutRejected.Load()),
+		"# HELP webhook_deliveries_total Webhook delivery attempts by result\n",
+		"# TYPE webhook_deliveries_total counter\n",
+		fmt.Sprintf("webhook_deliveries_total{result=\"succeeded\"} %d\n", webhookSucceeded.Load()),
+		fmt.Sprintf("webhook_deliveries_total{result=\"retried\"} %d\n", webhookRetried.Load()),
+		fmt.Sprintf("webhook_deliveries_total{result=\"failed\"} %d\n", webhookFailed.Load()),
+		"# HELP webhooks_disabled_total Webhooks switched off after consecutive failed deliveries\n",
+		"# TYPE webhooks_disabled_total counter\n",
+		fmt.Sprintf("webhooks_disabled_total %d\n", webhooksDisabled.Load()),
 	)
 
 	requestMu.Lock()
@@ -89,6 +271,67 @@ func Handler(w http.ResponseWriter, _ *http.Request) {
 		parts := strings.Split(k, "|")
 		write(w, fmt.Sprintf("http_request_duration_seconds{method=\"%s\",path=\"%s\"} %.6f\n", parts[0], parts[1], httpDurationSum[k]))
 	}
+	driftKeys := make([]string, 0, len(stockDrift))
+	for k := range stockDrift {
+		driftKeys = append(driftKeys, k)
+	}
+	sort.Strings(driftKeys)
+	write(w, "# HELP stock_drift Redis remaining stock minus expected remaining stock\n", "# TYPE stock_drift gauge\n")
+	for _, k := range driftKeys {
+		parts := strings.Split(k, "|")
+		write(w, fmt.Sprintf("stock_drift{event_id=\"%s\",category=\"%s\"} %d\n", parts[0], parts[1], stockDrift[k]))
+	}
+	workerKeys := make([]string, 0, len(workerMessages))
+	for k := range workerMessages {
+		workerKeys = append(workerKeys, k)
+	}
+	sort.Strings(workerKeys)
+	write(w, "# HELP worker_messages_total Worker message outcomes per topic\n", "# TYPE worker_messages_total counter\n")
+	for _, k := range workerKeys {
+		parts := strings.Split(k, "|")
+		write(w, fmt.Sprintf("worker_messages_total{topic=\"%s\",result=\"%s\"} %d\n", parts[0], parts[1], workerMessages[k]))
+	}
+	producerKeys := make([]string, 0, len(producerResults))
+	for k := range producerResults {
+		producerKeys = append(producerKeys, k)
+	}
+	sort.Strings(producerKeys)
+	write(w, "# HELP producer_messages_total Kafka producer write outcomes per topic\n", "# TYPE producer_messages_total counter\n")
+	for _, k := range producerKeys {
+		parts := strings.Split(k, "|")
+		write(w, fmt.Sprintf("producer_messages_total{topic=\"%s\",result=\"%s\"} %d\n", parts[0], parts[1], producerResults[k]))
+	}
+	notificationKeys := make([]string, 0, len(notifications))
+	for k := range notifications {
+		notificationKeys = append(notificationKeys, k)
+	}
+	sort.Strings(notificationKeys)
+	write(w, "# HELP notifications_total Customer notification outcomes per channel\n", "# TYPE notifications_total counter\n")
+	for _, k := range notificationKeys {
+		parts := strings.Split(k, "|")
+		write(w, fmt.Sprintf("notifications_total{channel=\"%s\",result=\"%s\"} %d\n", parts[0], parts[1], notifications[k]))
+	}
+	if len(replayMessages) > 0 || len(replayRemaining) > 0 {
+		replayKeys := make([]string, 0, len(replayMessages))
+		for k := range replayMessages {
+			replayKeys = append(replayKeys, k)
+		}
+		sort.Strings(replayKeys)
+		write(w, "# HELP replay_messages_total Replayed message outcomes per topic\n", "# TYPE replay_messages_total counter\n")
+		for _, k := range replayKeys {
+			parts := strings.Split(k, "|")
+			write(w, fmt.Sprintf("replay_messages_total{topic=\"%s\",result=\"%s\"} %d\n", parts[0], parts[1], replayMessages[k]))
+		}
+		remainingKeys := make([]string, 0, len(replayRemaining))
+		for k := range replayRemaining {
+			remainingKeys = append(remainingKeys, k)
+		}
+		sort.Strings(remainingKeys)
+		write(w, "# HELP replay_remaining_messages Messages the replay still has to read per topic\n", "# TYPE replay_remaining_messages gauge\n")
+		for _, k := range remainingKeys {
+			write(w, fmt.Sprintf("replay_remaining_messages{topic=\"%s\"} %d\n", k, replayRemaining[k]))
+		}
+	}
 	requestMu.Unlock()
 }
 
@@ -98,7 +341,7 @@ func write(w io.Writer, lines ...string) {
 	}
 }
 
-func StartInfraCollectors(db *sql.DB, redisClient *goredis.Client, interval time.Duration, stop <-chan struct{}) {
+func StartInfraCollectors(db *sql.DB, redisClient goredis.UniversalClient, interval time.Duration, stop <-chan struct{}) {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
@@ -28,7 +28,7 @@ func NewEventUsecase(events repository.EventRepository, categories repository.Ti
 	return &EventUsecase{events: events, categories: categories, stock: stock, now: now, newID: newID}
 }
 
-func (u *EventUsecase) CreateEvent(name string, date time.Time) (entity.Event, error) {
+func (u *EventUsecase) CreateEvent(ctx context.Context, name string, date time.Time) (entity.Event, error) {
 	if strings.TrimSpace(name) == "" || date.IsZero() {
 		return entity.Event{}, ErrInvalidInput
 	}
@@ -38,18 +38,18 @@ func (u *EventUsecase) CreateEvent(name string, date time.Time) (entity.Event, e
 		Date:      date.UTC(),
 		CreatedAt: u.now().UTC(),
 	}
-	if err := u.events.Create(e); err != nil {
+	if err := u.events.Create(ctx, e); err != nil {
 		return entity.Event{}, err
 	}
 	return e, nil
 }
 
-func (u *EventUsecase) CreateCategory(eventID, name string, totalStock int, price int64) (entity.TicketCategory, error) {
+func (u *EventUsecase) CreateCategory(ctx context.Context, eventID, name string, totalStock int, price int64) (entity.TicketCategory, error) {
 	if strings.TrimSpace(eventID) == "" || strings.TrimSpace(name) == "" || totalStock <= 0 || price < 0 {
 		return entity.TicketCategory{}, ErrInvalidInput
 	}
-	if _, err := u.events.FindByID(eventID); err != nil {
-		return entity.TicketCategory{}, ErrNotFound
+	if _, err := u.events.FindByID(ctx, eventID); err != nil {
+		return entity.TicketCategory{}, notFoundUnlessCanceled(err)
 	}
 	c := entity.TicketCategory{
 		ID:         u.newID(),
@@ -58,23 +58,23 @@ func (u *EventUsecase) CreateCategory(eventID, name string, totalStock int, pric
 		TotalStock: totalStock,
 		Price:      price,
 	}
-	if err := u.categories.Create(c); err != nil {
+	if err := u.categories.Create(ctx, c); err != nil {
 		return entity.TicketCategory{}, err
 	}
-	if err := u.stock.InitStock(context.Background(), c.EventID, c.Name, c.TotalStock); err != nil {
+	if err := u.stock.InitStock(ctx, c.EventID, c.Name, c.TotalStock); err != nil {
 		return entity.TicketCategory{}, err
 	}
 	return c, nil
 }
 
-func (u *EventUsecase) Availability(eventID string) (map[string]int, error) {
+func (u *EventUsecase) Availability(ctx context.Context, eventID string) (map[string]int, error) {
 	if strings.TrimSpace(eventID) == "" {
 		return nil, ErrInvalidInput
 	}
-	if _, err := u.events.FindByID(eventID); err != nil {
-		return nil, ErrNotFound
+	if _, err := u.events.FindByID(ctx, eventID); err != nil {
+		return nil, notFoundUnlessCanceled(err)
 	}
-	categories, err := u.categories.FindByEventID(eventID)
+	categories, err := u.categories.FindByEventID(ctx, eventID)
 	if err != nil {
 		return nil, err
 	}
@@ -84,7 +84,7 @@ func (u *EventUsecase) Availability(eventID string) (map[string]int, error) {
 		names = append(names, c.Name)
 	}
 	// Prefer real-time stock from cache; fallback to configured stock from DB.
-	if stocks, err := u.stock.GetStocks(context.Background(), eventID, names); err == nil {
+	if stocks, err := u.stock.GetStocks(ctx, eventID, names); err == nil {
 		for _, c := range categories {
 			out[strings.ToLower(c.Name)] = stocks[c.Name]
 		}
@@ -95,3 +95,12 @@ func (u *EventUsecase) Availability(eventID string) (map[string]int, error) {
 	}
 	return out, nil
 }
+
+// notFoundUnlessCanceled maps lookup failures to ErrNotFound but keeps
+// cancellation and timeout errors intact so they are not reported as 404.
+func notFoundUnlessCanceled(err error) error {
+	if errors.Is(err, repository.ErrQueryCanceled) || errors.Is(err, repository.ErrQueryTimeout) {
+		return err
+	}
+	return ErrNotFound
+}
@@ -1,6 +1,7 @@
 package usecase
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -13,15 +14,15 @@ func TestEventUsecaseAvailability(t *testing.T) {
 	stock := memory.NewStockService()
 	u := NewEventUsecase(events, categories, stock, func() time.Time { return time.Unix(1000, 0) }, func() string { return "id-1" })
 
-	e, err := u.CreateEvent("Coldplay", time.Now())
+	e, err := u.CreateEvent(context.Background(), "Coldplay", time.Now())
 	if err != nil {
 		t.Fatalf("create event: %v", err)
 	}
-	if _, err := u.CreateCategory(e.ID, "VIP", 10, 100000); err != nil {
+	if _, err := u.CreateCategory(context.Background(), e.ID, "VIP", 10, 100000); err != nil {
 		t.Fatalf("create category: %v", err)
 	}
 
-	av, err := u.Availability(e.ID)
+	av, err := u.Availability(context.Background(), e.ID)
 	if err != nil {
 		t.Fatalf("availability: %v", err)
 	}
@@ -0,0 +1,124 @@
+package usecase
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"concert-booking/internal/domain/entity"
+	"concert-booking/internal/domain/repository"
+	"concert-booking/internal/domain/service"
+)
+
+type LedgerVerification struct {
+	EventID     string `json:"event_id"`
+	Category    string `json:"category"`
+	TotalStock  int    `json:"total_stock"`
+	Remaining   int    `json:"remaining"`
+	Allocated   int    `json:"allocated"`
+	LedgerSum   int    `json:"ledger_sum"`
+	LastBalance int    `json:"last_balance"`
+	Entries     int    `json:"entries"`
+	Consistent  bool   `json:"consistent"`
+}
+
+type LedgerUsecase struct {
+	categories repository.TicketCategoryRepository
+	ledger     repository.StockLedgerRepository
+	stock      service.StockService
+}
+
+func NewLedgerUsecase(categories repository.TicketCategoryRepository, ledger repository.StockLedgerRepository, stock service.StockService) *LedgerUsecase {
+	return &LedgerUsecase{categories: categories, ledger: ledger, stock: stock}
+}
+
+func (u *LedgerUsecase) Entries(ctx context.Context, eventID, category string) ([]entity.StockMovement, error) {
+	c, err := u.category(ctx, eventID, category)
+	if err != nil {
+		return nil, err
+	}
+	return u.ledger.FindByCategory(ctx, c.EventID, c.Name)
+}
+
+// Verify checks that the stock allocated according to the ledger (the negated
+// sum of every non-init delta) equals TotalStock minus the live remaining stock.
+// Balances are per counter, so the last balance is only compared for
+// categories initialised as a single counter.
+func (u *LedgerUsecase) Verify(ctx context.Context, eventID, category string) (LedgerVerification, error) {
+	c, err := u.category(ctx, eventID, category)
+	if err != nil {
+		return LedgerVerification{}, err
+	}
+	entries, err := u.ledger.FindByCategory(ctx, c.EventID, c.Name)
+	if err != nil {
+		return LedgerVerification{}, err
+	}
+	stocks, err := u.stock.GetStocks(ctx, c.EventID, []string{c.Name})
+	if err != nil {
+		return LedgerVerification{}, err
+	}
+	v := LedgerVerification{EventID: c.EventID, Category: c.Name, TotalStock: c.TotalStock, Remaining: stocks[c.Name], Entries: len(entries)}
+	v.Allocated = v.TotalStock - v.Remaining
+	inits := 0
+	for _, m := range entries {
+		if m.Type == entity.MovementInit {
+			inits++
+		} else {
+			v.LedgerSum -= m.Delta
+		}
+		v.LastBalance = m.Balance
+	}
+	v.Consistent = v.LedgerSum == v.Allocated && (len(entries) == 0 || inits > 1 || v.LastBalance == v.Remaining)
+	return v, nil
+}
+
+// Drain moves buffered movements from the stock store into the durable ledger.
+func (u *LedgerUsecase) Drain(ctx context.Context, source service.LedgerSource, batch int) (int, error) {
+	items, err := source.ReadLedger(ctx, batch)
+	if err != nil || len(items) == 0 {
+		return 0, err
+	}
+	if err := u.ledger.Append(ctx, items...); err != nil {
+		return 0, err
+	}
+	ids := make([]string, 0, len(items))
+	for _, m := range items {
+		ids = append(ids, m.ID)
+	}
+	return len(items), source.AckLedger(ctx, ids...)
+}
+
+func (u *LedgerUsecase) StartDrain(ctx context.Context, source service.LedgerSource, interval time.Duration, batch int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for {
+				n, err := u.Drain(ctx, source, batch)
+				if err != nil {
+					log.Printf("ledger drain failed: %v", err)
+				}
+				if err != nil || n < batch {
+					break
+				}
+			}
+		}
+	}
+}
+
+func (u *LedgerUsecase) category(ctx context.Context, eventID, category string) (entity.TicketCategory, error) {
+	eventID = strings.TrimSpace(eventID)
+	category = strings.ToUpper(strings.TrimSpace(category))
+	if eventID == "" || category == "" {
+		return entity.TicketCategory{}, ErrInvalidInput
+	}
+	c, err := u.categories.FindByEventAndName(ctx, eventID, category)
+	if err != nil {
+		return entity.TicketCategory{}, notFoundUnlessCanceled(err)
+	}
+	return c, nil
+}
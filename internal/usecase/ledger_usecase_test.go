@@ -0,0 +1,50 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"concert-booking/internal/domain/entity"
+	"concert-booking/internal/domain/service"
+	"concert-booking/internal/infrastructure/memory"
+)
+
+func TestLedgerDrainAndVerify(t *testing.T) {
+	ctx := context.Background()
+	categories := memory.NewTicketCategoryRepository()
+	stock := memory.NewStockService()
+	_ = categories.Create(ctx, entity.TicketCategory{ID: "cat-1", EventID: "event-1", Name: "VIP", TotalStock: 10})
+	_ = stock.InitStock(ctx, "event-1", "VIP", 10)
+	_ = stock.Reserve(ctx, service.ReservationMeta{ReservationID: "res-1", UserID: "u1", EventID: "event-1", Category: "VIP", Qty: 3}, time.Minute)
+	_ = stock.ConfirmReservation(ctx, "res-1")
+	_ = stock.Reserve(ctx, service.ReservationMeta{ReservationID: "res-2", UserID: "u2", EventID: "event-1", Category: "VIP", Qty: 2}, time.Minute)
+	_, _ = stock.ReleaseReservation(ctx, "res-2")
+
+	u := NewLedgerUsecase(categories, memory.NewStockLedgerRepository(), stock)
+	if n, err := u.Drain(ctx, stock, 100); err != nil || n != 5 {
+		t.Fatalf("expected 5 drained movements, got %d (%v)", n, err)
+	}
+	if pending, _ := stock.ReadLedger(ctx, 100); len(pending) != 0 {
+		t.Fatalf("expected drained buffer, got %d entries", len(pending))
+	}
+
+	entries, err := u.Entries(ctx, "event-1", "vip")
+	if err != nil {
+		t.Fatalf("entries: %v", err)
+	}
+	types := []string{entity.MovementInit, entity.MovementReserve, entity.MovementConfirm, entity.MovementReserve, entity.MovementRelease}
+	for i, m := range entries {
+		if m.Type != types[i] {
+			t.Fatalf("entry %d: expected %s, got %s", i, types[i], m.Type)
+		}
+	}
+
+	v, err := u.Verify(ctx, "event-1", "VIP")
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if !v.Consistent || v.Allocated != 3 || v.LedgerSum != 3 || v.LastBalance != 7 {
+		t.Fatalf("unexpected verification %+v", v)
+	}
+}
@@ -0,0 +1,91 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"concert-booking/internal/domain/entity"
+	"concert-booking/internal/domain/service"
+	"concert-booking/internal/domain/ticketevent"
+	"concert-booking/internal/infrastructure/memory"
+	"concert-booking/internal/infrastructure/notify"
+)
+
+// TestMemoryModePipeline runs reserve and confirm through the outbox, the
+// in-process bus and the worker-side handlers, as APP_MODE=memory wires them.
+func TestMemoryModePipeline(t *testing.T) {
+	ctx := context.Background()
+	events := memory.NewEventRepository()
+	categories := memory.NewTicketCategoryRepository()
+	reservations := memory.NewReservationRepository()
+	bookings := memory.NewBookingRepository()
+	stock := memory.NewStockService()
+	_ = events.Create(ctx, entity.Event{ID: "event-1", Name: "Jazz Night"})
+	_ = stock.InitStock(ctx, "event-1", "VIP", 10)
+
+	var ids atomic.Int64
+	newID := func() string { return fmt.Sprintf("id-%d", ids.Add(1)) }
+	reserve := NewReservationUsecase(categories, reservations, bookings, stock, time.Now, newID, 5*time.Minute, 10, 1, true)
+	bus := memory.NewEventBus(memory.BusOptions{Partitions: 2, MaxAttempts: 3, Backoff: time.Millisecond, MaxBackoff: time.Millisecond}, time.Now)
+	subscribe := func(group string, handlers map[string]EventHandler) {
+		for topic, h := range handlers {
+			if err := bus.Subscribe(topic, group, func(ctx context.Context, msg memory.BusMessage) error { return h(ctx, msg.Value) }); err != nil {
+				t.Fatalf("subscribe: %v", err)
+			}
+		}
+	}
+
+	sender := &stubWebhookSender{}
+	webhooks := NewWebhookUsecase(memory.NewWebhookRepository(), memory.NewWebhookDeliveryRepository(), sender, time.Now, newID, WebhookOptions{MaxAttempts: 1, DisableAfter: 5})
+	if _, err := webhooks.Create(ctx, "https://crm.example/hook", nil, ""); err != nil {
+		t.Fatalf("create webhook: %v", err)
+	}
+	templates, _ := notify.DefaultTemplates("en")
+	email := &stubNotifier{}
+	notifications := NewNotificationUsecase(memory.NewNotificationPreferenceRepository(), memory.NewNotificationRepository(), reservations, events, templates, map[string]service.Notifier{entity.ChannelEmail: email}, time.Now, newID)
+	_, _ = notifications.SetPreferences(ctx, entity.NotificationPreference{UserID: "user-1", Email: "budi@example.com", EmailEnabled: true})
+
+	subscribe("projector", NewProjector(reservations, bookings).Handlers())
+	subscribe("webhooks", webhooks.Handlers())
+	subscribe("notifications", notifications.Handlers())
+
+	res, err := reserve.Reserve(ctx, "user-1", "event-1", "VIP", 2)
+	if err != nil {
+		t.Fatalf("reserve: %v", err)
+	}
+	if _, err := reserve.Confirm(ctx, res.ID, true); err != nil {
+		t.Fatalf("confirm: %v", err)
+	}
+	if n, err := NewOutboxRelay(stock, bus, "test", 100, 1, 0).Flush(ctx); err != nil || n != 2 {
+		t.Fatalf("relay: n=%d err=%v", n, err)
+	}
+	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := bus.Shutdown(shutdownCtx); err != nil {
+		t.Fatalf("bus shutdown: %v", err)
+	}
+
+	if _, err := webhooks.DeliverDue(ctx, 10); err != nil {
+		t.Fatalf("deliver: %v", err)
+	}
+	types := map[string]bool{}
+	for _, req := range sender.sent {
+		types[req.EventType] = true
+	}
+	if len(sender.sent) != 2 || !types[ticketevent.TypeReserved] || !types[ticketevent.TypeConfirmed] {
+		t.Fatalf("expected reserved and confirmed webhooks, got %+v", sender.sent)
+	}
+	if len(email.sent) != 1 || email.sent[0].To != "budi@example.com" {
+		t.Fatalf("expected one confirmation email, got %+v", email.sent)
+	}
+	for _, topic := range bus.Topics() {
+		for _, sub := range topic.Subscriptions {
+			if sub.Pending != 0 || sub.DeadLettered != 0 {
+				t.Fatalf("%s/%s: unexpected stats %+v", topic.Topic, sub.Group, sub)
+			}
+		}
+	}
+}
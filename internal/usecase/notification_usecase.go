@@ -0,0 +1,216 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+
+	"concert-booking/internal/domain/entity"
+	"concert-booking/internal/domain/repository"
+	"concert-booking/internal/domain/service"
+	"concert-booking/internal/domain/ticketevent"
+	"concert-booking/internal/observability/metrics"
+)
+
+var (
+	phonePattern  = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+	localePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}([-_][a-zA-Z0-9]{2,8})*$`)
+)
+
+// NotificationData is what notification templates render.
+type NotificationData struct {
+	ReservationID string
+	BookingID     string
+	EventID       string
+	EventName     string
+	EventDate     time.Time
+	Category      string
+	Qty           int
+	ExpiredAt     time.Time
+}
+
+// NotificationUsecase tells users about their reservations over the
+// channels they opted into. Every (reservation, kind, channel) is sent at
+// most once successfully, however often the event is redelivered.
+type NotificationUsecase struct {
+	prefs        repository.NotificationPreferenceRepository
+	records      repository.NotificationRepository
+	reservations repository.ReservationRepository
+	events       repository.EventRepository
+	renderer     service.NotificationRenderer
+	channels     map[string]service.Notifier
+	now          func() time.Time
+	newID        func() string
+}
+
+func NewNotificationUsecase(prefs repository.NotificationPreferenceRepository, records repository.NotificationRepository, reservations repository.ReservationRepository, events repository.EventRepository, renderer service.NotificationRenderer, channels map[string]service.Notifier, now func() time.Time, newID func() string) *NotificationUsecase {
+	return &NotificationUsecase{prefs: prefs, records: records, reservations: reservations, events: events, renderer: renderer, channels: channels, now: now, newID: newID}
+}
+
+// Preferences returns the user's preferences; a user who never saved any
+// gets everything switched off.
+func (u *NotificationUsecase) Preferences(ctx context.Context, userID string) (entity.NotificationPreference, error) {
+	p, err := u.prefs.Get(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return entity.NotificationPreference{UserID: userID}, nil
+	}
+	return p, err
+}
+
+func (u *NotificationUsecase) SetPreferences(ctx context.Context, p entity.NotificationPreference) (entity.NotificationPreference, error) {
+	p.Email, p.Phone, p.Locale = strings.TrimSpace(p.Email), strings.TrimSpace(p.Phone), strings.TrimSpace(p.Locale)
+	switch {
+	case p.UserID == "":
+		return entity.NotificationPreference{}, ErrInvalidInput
+	case p.EmailEnabled && p.Email == "":
+		return entity.NotificationPreference{}, fmt.Errorf("%w: email is required to enable email", ErrInvalidInput)
+	case p.SMSEnabled && p.Phone == "":
+		return entity.NotificationPreference{}, fmt.Errorf("%w: phone is required to enable sms", ErrInvalidInput)
+	case p.Phone != "" && !phonePattern.MatchString(p.Phone):
+		return entity.NotificationPreference{}, fmt.Errorf("%w: phone must be in E.164 format", ErrInvalidInput)
+	case p.Locale != "" && !localePattern.MatchString(p.Locale):
+		return entity.NotificationPreference{}, fmt.Errorf("%w: invalid locale", ErrInvalidInput)
+	}
+	if p.Email != "" {
+		addr, err := mail.ParseAddress(p.Email)
+		if err != nil || addr.Name != "" {
+			return entity.NotificationPreference{}, fmt.Errorf("%w: invalid email", ErrInvalidInput)
+		}
+	}
+	p.UpdatedAt = u.now()
+	if err := u.prefs.Upsert(ctx, p); err != nil {
+		return entity.NotificationPreference{}, err
+	}
+	return p, nil
+}
+
+// History returns the user's notifications, newest first.
+func (u *NotificationUsecase) History(ctx context.Context, userID string, limit int) ([]entity.Notification, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+	return u.records.ListByUser(ctx, userID, limit)
+}
+
+// Handlers returns the handlers keyed by topic: a booking confirmation on
+// ticket.confirmed and an expiry notice on ticket.expired.
+func (u *NotificationUsecase) Handlers() map[string]EventHandler {
+	return map[string]EventHandler{
+		ticketevent.TopicConfirmed: u.handleConfirmed,
+		ticketevent.TopicExpired:   u.handleExpired,
+	}
+}
+
+func (u *NotificationUsecase) handleConfirmed(ctx context.Context, payload []byte) error {
+	env, err := ticketevent.Decode(ticketevent.TopicConfirmed, payload)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+	c, err := env.Confirmed()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+	return u.notify(ctx, entity.NotificationBookingConfirmed, c.ReservationID, NotificationData{BookingID: c.BookingID})
+}
+
+func (u *NotificationUsecase) handleExpired(ctx context.Context, payload []byte) error {
+	env, err := ticketevent.Decode(ticketevent.TopicExpired, payload)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+	e, err := env.Expired()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+	return u.notify(ctx, entity.NotificationReservationExpired, e.ReservationID, NotificationData{})
+}
+
+func (u *NotificationUsecase) notify(ctx context.Context, kind, reservationID string, data NotificationData) error {
+	if reservationID == "" {
+		return fmt.Errorf("%w: missing reservation_id", ErrInvalidPayload)
+	}
+	// The user is only known from the projection; a miss means the worker
+	// has not stored the reservation yet, so the router retries.
+	res, err := u.reservations.FindByID(ctx, reservationID)
+	if err != nil {
+		return fmt.Errorf("load reservation %s: %w", reservationID, err)
+	}
+	pref, err := u.prefs.Get(ctx, res.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	data.ReservationID, data.EventID, data.EventName = res.ID, res.EventID, res.EventID
+	data.Category, data.Qty, data.ExpiredAt = res.Category, res.Qty, res.ExpiredAt
+	event, err := u.events.FindByID(ctx, res.EventID)
+	switch {
+	case err == nil:
+		data.EventName, data.EventDate = event.Name, event.Date
+	case !errors.Is(err, repository.ErrNotFound):
+		return err
+	}
+	msg, err := u.renderer.Render(kind, pref.Locale, data)
+	if err != nil {
+		return fmt.Errorf("render %s: %w", kind, err)
+	}
+
+	var failed []error
+	for _, ch := range []struct {
+		name    string
+		to      string
+		enabled bool
+	}{
+		{entity.ChannelEmail, pref.Email, pref.EmailEnabled},
+		{entity.ChannelSMS, pref.Phone, pref.SMSEnabled},
+	} {
+		notifier, ok := u.channels[ch.name]
+		if !ch.enabled || ch.to == "" || !ok {
+			continue
+		}
+		now := u.now()
+		n, created, err := u.records.Create(ctx, entity.Notification{
+			ID:            u.newID(),
+			UserID:        res.UserID,
+			ReservationID: res.ID,
+			Kind:          kind,
+			Channel:       ch.name,
+			Recipient:     ch.to,
+			Status:        entity.NotificationPending,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		})
+		if err != nil {
+			return err
+		}
+		if !created && n.Status == entity.NotificationSent {
+			metrics.IncNotification(ch.name, "duplicate")
+			continue
+		}
+		out := msg
+		out.To = ch.to
+		sendErr := notifier.Send(ctx, out)
+		now = u.now()
+		n.Recipient, n.Attempts, n.UpdatedAt = ch.to, n.Attempts+1, now
+		if sendErr == nil {
+			n.Status, n.LastError, n.SentAt = entity.NotificationSent, "", &now
+			metrics.IncNotification(ch.name, "sent")
+		} else {
+			n.Status, n.LastError = entity.NotificationFailed, sendErr.Error()
+			metrics.IncNotification(ch.name, "failed")
+			failed = append(failed, fmt.Errorf("%s to %s: %w", ch.name, ch.to, sendErr))
+		}
+		if err := u.records.Update(ctx, n); err != nil {
+			return err
+		}
+	}
+	// A failed channel makes the router retry the event; channels that
+	// already went out are skipped as duplicates then.
+	return errors.Join(failed...)
+}
@@ -0,0 +1,148 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"concert-booking/internal/domain/entity"
+	"concert-booking/internal/domain/service"
+	"concert-booking/internal/domain/ticketevent"
+	"concert-booking/internal/infrastructure/memory"
+	"concert-booking/internal/infrastructure/notify"
+)
+
+type stubNotifier struct {
+	fail bool
+	sent []service.NotificationMessage
+}
+
+func (n *stubNotifier) Send(_ context.Context, msg service.NotificationMessage) error {
+	if n.fail {
+		return errors.New("relay unavailable")
+	}
+	n.sent = append(n.sent, msg)
+	return nil
+}
+
+// newNotificationUsecase wires the default templates over Jazz Night with
+// reservations res-1 (user-1) and res-2 (user-2).
+func newNotificationUsecase(t *testing.T, email, sms service.Notifier) *NotificationUsecase {
+	t.Helper()
+	ctx := context.Background()
+	templates, err := notify.DefaultTemplates("en")
+	if err != nil {
+		t.Fatalf("templates: %v", err)
+	}
+	reservations := memory.NewReservationRepository()
+	events := memory.NewEventRepository()
+	_ = events.Create(ctx, entity.Event{ID: "event-1", Name: "Jazz Night", Date: time.Date(2026, 5, 1, 19, 0, 0, 0, time.UTC)})
+	_ = reservations.Upsert(ctx, entity.Reservation{ID: "res-1", UserID: "user-1", EventID: "event-1", Category: "VIP", Qty: 2, Status: entity.ReservationStatusReserved})
+	_ = reservations.Upsert(ctx, entity.Reservation{ID: "res-2", UserID: "user-2", EventID: "event-1", Category: "VIP", Qty: 1, Status: entity.ReservationStatusReserved})
+	channels := map[string]service.Notifier{entity.ChannelEmail: email, entity.ChannelSMS: sms}
+	return NewNotificationUsecase(memory.NewNotificationPreferenceRepository(), memory.NewNotificationRepository(), reservations, events, templates, channels, time.Now, seqIDs("n"))
+}
+
+func confirmedEvent(t *testing.T, reservationID string) []byte {
+	t.Helper()
+	return replayEvent(t, ticketevent.TopicConfirmed, reservationID, ticketevent.Confirmed{BookingID: "bk-" + reservationID, ReservationID: reservationID, PaymentStatus: "paid"})
+}
+
+func TestNotificationSendsLocalizedConfirmationOnce(t *testing.T) {
+	ctx := context.Background()
+	email, sms := &stubNotifier{}, &stubNotifier{}
+	u := newNotificationUsecase(t, email, sms)
+	if _, err := u.SetPreferences(ctx, entity.NotificationPreference{UserID: "user-1", Email: "budi@example.com", Phone: "+6281234567890", Locale: "id-ID", EmailEnabled: true, SMSEnabled: true}); err != nil {
+		t.Fatalf("set preferences: %v", err)
+	}
+
+	handler := u.Handlers()[ticketevent.TopicConfirmed]
+	for range 2 {
+		if err := handler(ctx, confirmedEvent(t, "res-1")); err != nil {
+			t.Fatalf("handle: %v", err)
+		}
+	}
+	if len(email.sent) != 1 || len(sms.sent) != 1 {
+		t.Fatalf("expected one email and one sms, got %d and %d", len(email.sent), len(sms.sent))
+	}
+	msg := email.sent[0]
+	if msg.To != "budi@example.com" || !strings.Contains(msg.Subject, "Jazz Night") || !strings.Contains(msg.Subject, "terkonfirmasi") || !strings.Contains(msg.Text, "bk-res-1") || msg.HTML == "" {
+		t.Fatalf("unexpected email %+v", msg)
+	}
+	if sms.sent[0].To != "+6281234567890" {
+		t.Fatalf("unexpected sms %+v", sms.sent[0])
+	}
+	history, _ := u.History(ctx, "user-1", 0)
+	if len(history) != 2 || history[0].Status != entity.NotificationSent || history[0].SentAt == nil {
+		t.Fatalf("unexpected history %+v", history)
+	}
+}
+
+func TestNotificationSkipsUsersWithoutPreferences(t *testing.T) {
+	email, sms := &stubNotifier{}, &stubNotifier{}
+	u := newNotificationUsecase(t, email, sms)
+	if err := u.Handlers()[ticketevent.TopicConfirmed](context.Background(), confirmedEvent(t, "res-2")); err != nil {
+		t.Fatalf("handle: %v", err)
+	}
+	if len(email.sent) != 0 || len(sms.sent) != 0 {
+		t.Fatal("nothing must be sent without preferences")
+	}
+}
+
+func TestNotificationRetriesOnlyFailedChannel(t *testing.T) {
+	ctx := context.Background()
+	email, sms := &stubNotifier{}, &stubNotifier{}
+	u := newNotificationUsecase(t, email, sms)
+	_, _ = u.SetPreferences(ctx, entity.NotificationPreference{UserID: "user-1", Email: "budi@example.com", Phone: "+6281234567890", EmailEnabled: true, SMSEnabled: true})
+	payload := replayEvent(t, ticketevent.TopicExpired, "res-1", ticketevent.Expired{ReservationID: "res-1", EventID: "event-1", Status: entity.ReservationStatusExpired})
+	handler := u.Handlers()[ticketevent.TopicExpired]
+
+	email.fail = true
+	if err := handler(ctx, payload); err == nil {
+		t.Fatal("expected error so the event is retried")
+	}
+	history, _ := u.History(ctx, "user-1", 0)
+	statuses := map[string]string{}
+	for _, n := range history {
+		statuses[n.Channel] = n.Status
+	}
+	if statuses[entity.ChannelEmail] != entity.NotificationFailed || statuses[entity.ChannelSMS] != entity.NotificationSent {
+		t.Fatalf("unexpected statuses %v", statuses)
+	}
+
+	email.fail = false
+	if err := handler(ctx, payload); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if len(email.sent) != 1 || len(sms.sent) != 1 || !strings.Contains(email.sent[0].Subject, "expired") {
+		t.Fatalf("expected email resent and sms not, got %d emails %d sms", len(email.sent), len(sms.sent))
+	}
+}
+
+func TestNotificationWaitsForProjection(t *testing.T) {
+	u := newNotificationUsecase(t, &stubNotifier{}, &stubNotifier{})
+	if err := u.Handlers()[ticketevent.TopicConfirmed](context.Background(), confirmedEvent(t, "res-unknown")); err == nil || errors.Is(err, ErrInvalidPayload) {
+		t.Fatalf("expected a retryable error, got %v", err)
+	}
+}
+
+func TestNotificationPreferencesValidation(t *testing.T) {
+	ctx := context.Background()
+	u := newNotificationUsecase(t, &stubNotifier{}, &stubNotifier{})
+	for _, p := range []entity.NotificationPreference{
+		{UserID: "user-1", EmailEnabled: true},
+		{UserID: "user-1", Email: "not-an-email"},
+		{UserID: "user-1", Phone: "0812345"},
+		{UserID: "user-1", Locale: "in valid"},
+	} {
+		if _, err := u.SetPreferences(ctx, p); !errors.Is(err, ErrInvalidInput) {
+			t.Fatalf("%+v: expected ErrInvalidInput, got %v", p, err)
+		}
+	}
+	p, err := u.Preferences(ctx, "user-9")
+	if err != nil || p.UserID != "user-9" || p.EmailEnabled || p.SMSEnabled {
+		t.Fatalf("expected defaults, got %+v err=%v", p, err)
+	}
+}
@@ -0,0 +1,218 @@
+package usecase
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"concert-booking/internal/domain/service"
+	"concert-booking/internal/observability/metrics"
+)
+
+// OutboxRelay drains the outbox into the broker with at-least-once delivery.
+// Events of one key are published in outbox order; when one fails, later events with the
+// same key are held back until it succeeds so per-key ordering is preserved.
+// An event that still fails after parkAfter flushes is parked, so a poison
+// event does not hold its key back forever.
+type OutboxRelay struct {
+	store       service.OutboxStore
+	producer    service.EventProducer
+	owner       string
+	batch       int
+	maxAttempts int
+	baseBackoff time.Duration
+	parkAfter   int
+	now         func() time.Time
+
+	mu       sync.Mutex
+	failures map[string]int
+}
+
+func NewOutboxRelay(store service.OutboxStore, producer service.EventProducer, owner string, batch, maxAttempts int, baseBackoff time.Duration) *OutboxRelay {
+	if batch <= 0 {
+		batch = 100
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+	return &OutboxRelay{store: store, producer: producer, owner: owner, batch: batch, maxAttempts: maxAttempts, baseBackoff: baseBackoff, now: time.Now, failures: map[string]int{}}
+}
+
+// SetParkAfter parks an event once n flushes in a row failed to publish it.
+// Zero keeps retrying forever.
+func (r *OutboxRelay) SetParkAfter(n int) {
+	r.parkAfter = n
+}
+
+func (r *OutboxRelay) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ok, err := r.store.AcquireRelayLease(ctx, r.owner, 3*interval)
+			if err != nil || !ok {
+				continue
+			}
+			r.drain(ctx, interval)
+		}
+	}
+}
+
+// drain flushes until the outbox is empty. The lease is renewed every
+// interval while it runs, however long the flushes take; if it cannot be
+// renewed, publishing stops before another replica takes over.
+func (r *OutboxRelay) drain(ctx context.Context, interval time.Duration) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				ok, err := r.store.AcquireRelayLease(ctx, r.owner, 3*interval)
+				if (err != nil || !ok) && ctx.Err() == nil {
+					log.Printf("outbox relay: lease lost (%v)", err)
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+	for ctx.Err() == nil {
+		n, err := r.Flush(ctx)
+		if err != nil {
+			log.Printf("outbox relay: %v", err)
+		}
+		if err != nil || n < r.batch {
+			return
+		}
+	}
+}
+
+// Flush publishes one batch of pending events and returns how many were read.
+// Keys are published concurrently, so a synchronous producer batches writes
+// of different keys together; events of one key still go out in order.
+func (r *OutboxRelay) Flush(ctx context.Context) (int, error) {
+	events, err := r.store.Pending(ctx, r.batch)
+	if err != nil {
+		return 0, err
+	}
+	byKey := map[string][]service.OutboxEvent{}
+	keys := make([]string, 0, len(events))
+	for _, e := range events {
+		if _, ok := byKey[e.Key]; !ok {
+			keys = append(keys, e.Key)
+		}
+		byKey[e.Key] = append(byKey[e.Key], e)
+	}
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		acked    = make([]string, 0, len(events))
+		firstErr error
+	)
+	for _, key := range keys {
+		wg.Add(1)
+		go func(events []service.OutboxEvent) {
+			defer wg.Done()
+			for _, e := range events {
+				if err := r.publish(ctx, e); err != nil {
+					metrics.IncOutboxFailed()
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+					if ctx.Err() == nil && r.failed(e.Ref) {
+						if err := r.park(ctx, e, err); err == nil {
+							continue
+						}
+					}
+					// Later events of this key wait for the next flush.
+					return
+				}
+				r.clearFailures(e.Ref)
+				metrics.IncOutboxPublished()
+				mu.Lock()
+				acked = append(acked, e.Ref)
+				mu.Unlock()
+			}
+		}(byKey[key])
+	}
+	wg.Wait()
+	r.forgetFailures(events)
+	if err := r.store.Ack(ctx, acked...); err != nil {
+		return len(events), err
+	}
+	return len(events), firstErr
+}
+
+// failed counts a failed flush of the event and reports whether it is due
+// to be parked.
+func (r *OutboxRelay) failed(ref string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failures[ref]++
+	return r.parkAfter > 0 && r.failures[ref] >= r.parkAfter
+}
+
+func (r *OutboxRelay) clearFailures(ref string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.failures, ref)
+}
+
+// forgetFailures drops the counts of events no longer pending, e.g. ones
+// another replica published while this one had lost the lease.
+func (r *OutboxRelay) forgetFailures(pending []service.OutboxEvent) {
+	refs := make(map[string]struct{}, len(pending))
+	for _, e := range pending {
+		refs[e.Ref] = struct{}{}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for ref := range r.failures {
+		if _, ok := refs[ref]; !ok {
+			delete(r.failures, ref)
+		}
+	}
+}
+
+func (r *OutboxRelay) park(ctx context.Context, e service.OutboxEvent, cause error) error {
+	err := r.store.Park(ctx, service.ParkedEvent{OutboxEvent: e, Error: cause.Error(), ParkedAt: r.now()})
+	if err != nil {
+		log.Printf("outbox relay: park %s: %v", e.ID, err)
+		return err
+	}
+	log.Printf("outbox relay: parked %s (topic %s, key %s) after %d failed flushes: %v", e.ID, e.Topic, e.Key, r.parkAfter, cause)
+	r.clearFailures(e.Ref)
+	metrics.IncOutboxParked()
+	return nil
+}
+
+func (r *OutboxRelay) publish(ctx context.Context, e service.OutboxEvent) error {
+	var err error
+	backoff := r.baseBackoff
+	for attempt := 1; attempt <= r.maxAttempts; attempt++ {
+		pubCtx, cancel := context.WithTimeout(service.WithMessageID(ctx, e.ID), 5*time.Second)
+		err = r.producer.Publish(pubCtx, e.Topic, e.Key, e.Payload)
+		cancel()
+		if err == nil || attempt == r.maxAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	return err
+}
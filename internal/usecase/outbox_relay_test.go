@@ -0,0 +1,247 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"concert-booking/internal/domain/service"
+	"concert-booking/internal/infrastructure/memory"
+)
+
+type recordingProducer struct {
+	mu      sync.Mutex
+	failKey string
+	sent    []string
+}
+
+func (p *recordingProducer) Publish(ctx context.Context, _ string, key string, _ []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key == p.failKey {
+		return errors.New("broker down")
+	}
+	id, _ := service.MessageIDFromContext(ctx)
+	p.sent = append(p.sent, id)
+	return nil
+}
+
+func TestOutboxRelayHoldsBackFailedKey(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStockService()
+	_ = store.Append(ctx,
+		service.OutboxEvent{ID: "a1", Topic: "t", Key: "a"},
+		service.OutboxEvent{ID: "b1", Topic: "t", Key: "b"},
+		service.OutboxEvent{ID: "a2", Topic: "t", Key: "a"},
+		service.OutboxEvent{ID: "c1", Topic: "t", Key: "c"},
+	)
+	producer := &recordingProducer{failKey: "b"}
+	relay := NewOutboxRelay(store, producer, "test", 10, 1, 0)
+
+	if _, err := relay.Flush(ctx); err == nil {
+		t.Fatal("expected publish error for key b")
+	}
+	if len(producer.sent) != 3 {
+		t.Fatalf("expected a1,a2,c1 published, got %v", producer.sent)
+	}
+
+	producer.failKey = ""
+	if _, err := relay.Flush(ctx); err != nil {
+		t.Fatalf("second flush: %v", err)
+	}
+	pending, _ := store.Pending(ctx, 10)
+	if len(pending) != 0 || producer.sent[3] != "b1" {
+		t.Fatalf("expected b1 relayed on retry, pending=%v sent=%v", pending, producer.sent)
+	}
+}
+
+// orderProducer records the order each key was published in and how many
+// publishes overlapped; IDs in fail are refused.
+type orderProducer struct {
+	mu       sync.Mutex
+	fail     map[string]bool
+	byKey    map[string][]string
+	inFlight int
+	maxIn    int
+}
+
+func (p *orderProducer) Publish(ctx context.Context, _ string, key string, _ []byte) error {
+	id, _ := service.MessageIDFromContext(ctx)
+	p.mu.Lock()
+	p.inFlight++
+	p.maxIn = max(p.maxIn, p.inFlight)
+	p.mu.Unlock()
+	time.Sleep(time.Millisecond)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.inFlight--
+	if p.fail[id] {
+		return errors.New("broker down")
+	}
+	p.byKey[key] = append(p.byKey[key], id)
+	return nil
+}
+
+func TestOutboxRelayKeepsKeyOrderAcrossConcurrentKeys(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStockService()
+	want := map[string][]string{}
+	for i := 0; i < 5; i++ {
+		for _, key := range []string{"a", "b", "c", "d"} {
+			id := fmt.Sprintf("%s%d", key, i)
+			want[key] = append(want[key], id)
+			_ = store.Append(ctx, service.OutboxEvent{ID: id, Topic: "t", Key: key})
+		}
+	}
+	producer := &orderProducer{byKey: map[string][]string{}}
+	if _, err := NewOutboxRelay(store, producer, "test", 100, 1, 0).Flush(ctx); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	for key, ids := range want {
+		if !slices.Equal(producer.byKey[key], ids) {
+			t.Fatalf("key %s published as %v, want %v", key, producer.byKey[key], ids)
+		}
+	}
+	if producer.maxIn < 2 {
+		t.Fatal("expected keys to be published concurrently")
+	}
+}
+
+func TestOutboxRelayAcksOnlyWhatWasPublishedBeforeAFailure(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStockService()
+	_ = store.Append(ctx,
+		service.OutboxEvent{ID: "a1", Topic: "t", Key: "a"},
+		service.OutboxEvent{ID: "b1", Topic: "t", Key: "b"},
+		service.OutboxEvent{ID: "a2", Topic: "t", Key: "a"},
+		service.OutboxEvent{ID: "b2", Topic: "t", Key: "b"},
+		service.OutboxEvent{ID: "a3", Topic: "t", Key: "a"},
+	)
+	producer := &orderProducer{fail: map[string]bool{"a2": true}, byKey: map[string][]string{}}
+	relay := NewOutboxRelay(store, producer, "test", 10, 1, 0)
+
+	if n, err := relay.Flush(ctx); err == nil || n != 5 {
+		t.Fatalf("expected the failure reported after reading 5 events, got %d (%v)", n, err)
+	}
+	pending, _ := store.Pending(ctx, 10)
+	ids := make([]string, 0, len(pending))
+	for _, e := range pending {
+		ids = append(ids, e.ID)
+	}
+	if !slices.Equal(ids, []string{"a2", "a3"}) {
+		t.Fatalf("expected a2 and a3 left pending, got %v", ids)
+	}
+
+	producer.fail = nil
+	if _, err := relay.Flush(ctx); err != nil {
+		t.Fatalf("second flush: %v", err)
+	}
+	if got := producer.byKey["a"]; !slices.Equal(got, []string{"a1", "a2", "a3"}) {
+		t.Fatalf("expected key a published in order, got %v", got)
+	}
+}
+
+func TestOutboxRelayParksEventThatKeepsFailing(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStockService()
+	_ = store.Append(ctx,
+		service.OutboxEvent{ID: "a1", Topic: "t", Key: "a"},
+		service.OutboxEvent{ID: "a2", Topic: "t", Key: "a"},
+		service.OutboxEvent{ID: "b1", Topic: "t", Key: "b"},
+	)
+	producer := &orderProducer{fail: map[string]bool{"a1": true}, byKey: map[string][]string{}}
+	relay := NewOutboxRelay(store, producer, "test", 10, 1, 0)
+	relay.SetParkAfter(3)
+
+	for i := 0; i < 2; i++ {
+		if _, err := relay.Flush(ctx); err == nil {
+			t.Fatalf("flush %d: expected a1 to fail", i+1)
+		}
+	}
+	if len(store.Parked()) != 0 || len(producer.byKey["a"]) != 0 {
+		t.Fatalf("expected a1 retried and a2 held back, parked=%v sent=%v", store.Parked(), producer.byKey["a"])
+	}
+
+	if _, err := relay.Flush(ctx); err == nil {
+		t.Fatal("expected the third failure reported")
+	}
+	parked := store.Parked()
+	if len(parked) != 1 || parked[0].ID != "a1" || parked[0].Error != "broker down" {
+		t.Fatalf("expected a1 parked with its error, got %+v", parked)
+	}
+	if got := producer.byKey["a"]; !slices.Equal(got, []string{"a2"}) {
+		t.Fatalf("expected a2 published once a1 was parked, got %v", got)
+	}
+	if pending, _ := store.Pending(ctx, 10); len(pending) != 0 {
+		t.Fatalf("expected nothing pending, got %v", pending)
+	}
+}
+
+// leaseStore grants the relay lease a fixed number of times.
+type leaseStore struct {
+	*memory.StockService
+	mu     sync.Mutex
+	grants int
+	calls  int
+}
+
+func (s *leaseStore) AcquireRelayLease(context.Context, string, time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	return s.calls <= s.grants, nil
+}
+
+// blockingProducer publishes nothing until its context ends.
+type blockingProducer struct{}
+
+func (blockingProducer) Publish(ctx context.Context, _, _ string, _ []byte) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestOutboxRelayRenewsLeaseDuringFlush(t *testing.T) {
+	ctx := context.Background()
+	store := &leaseStore{StockService: memory.NewStockService(), grants: 3}
+	_ = store.Append(ctx, service.OutboxEvent{ID: "a1", Topic: "t", Key: "a"})
+	relay := NewOutboxRelay(store, blockingProducer{}, "test", 10, 1, 0)
+
+	done := make(chan struct{})
+	go func() {
+		relay.drain(ctx, 5*time.Millisecond)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("flush kept publishing after the lease was lost")
+	}
+	store.mu.Lock()
+	calls := store.calls
+	store.mu.Unlock()
+	if calls != 4 {
+		t.Fatalf("expected the lease renewed until refused, got %d calls", calls)
+	}
+	if pending, _ := store.Pending(ctx, 10); len(pending) != 1 {
+		t.Fatalf("unpublished event must stay pending, got %v", pending)
+	}
+}
+
+func TestReserveWritesOutbox(t *testing.T) {
+	ctx := context.Background()
+	stock := memory.NewStockService()
+	_ = stock.InitStock(ctx, "event-1", "VIP", 1)
+	u := NewReservationUsecase(memory.NewTicketCategoryRepository(), memory.NewReservationRepository(), memory.NewBookingRepository(), stock, time.Now, func() string { return "res-1" }, 5*time.Minute, 10, 1, true)
+
+	if _, err := u.Reserve(ctx, "user-1", "event-1", "VIP", 1); err != nil {
+		t.Fatalf("reserve: %v", err)
+	}
+	pending, _ := stock.Pending(ctx, 10)
+	if len(pending) != 1 || pending[0].Topic != "ticket.reserved" || pending[0].ID != "ticket.reserved:res-1" {
+		t.Fatalf("unexpected outbox %+v", pending)
+	}
+}
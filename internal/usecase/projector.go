@@ -0,0 +1,107 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"concert-booking/internal/domain/entity"
+	"concert-booking/internal/domain/repository"
+	"concert-booking/internal/domain/ticketevent"
+)
+
+// ErrRetryLater marks an event that arrived before the state it depends on,
+// e.g. a confirmation whose reservation row has not been written yet.
+// Topics are consumed independently, so this is expected and transient.
+var ErrRetryLater = errors.New("dependent record not persisted yet")
+
+// ErrInvalidPayload marks an event that can never be applied; consumers
+// should not retry it.
+var ErrInvalidPayload = errors.New("invalid event payload")
+
+// EventHandler applies one event payload to the durable store.
+type EventHandler func(ctx context.Context, payload []byte) error
+
+// Projector brings Postgres in line with the ticket events, so it converges
+// even when the API's own writes fail. Every handler is idempotent.
+type Projector struct {
+	reservations repository.ReservationRepository
+	bookings     repository.BookingRepository
+}
+
+func NewProjector(reservations repository.ReservationRepository, bookings repository.BookingRepository) *Projector {
+	return &Projector{reservations: reservations, bookings: bookings}
+}
+
+// Handlers returns the handler registry keyed by topic.
+func (p *Projector) Handlers() map[string]EventHandler {
+	return map[string]EventHandler{
+		ticketevent.TopicReserved:  p.HandleReserved,
+		ticketevent.TopicConfirmed: p.HandleConfirmed,
+		ticketevent.TopicExpired:   p.HandleExpired,
+	}
+}
+
+// Payloads are decoded through ticketevent, so older payload versions
+// (including bare pre-envelope JSON) are upgraded before they are applied.
+
+func (p *Projector) HandleReserved(ctx context.Context, payload []byte) error {
+	env, err := ticketevent.Decode(ticketevent.TopicReserved, payload)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+	data, err := env.Reserved()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+	return p.reservations.Upsert(ctx, data.Reservation())
+}
+
+// HandleConfirmed records the booking and marks its reservation confirmed.
+// The stock store already decided the confirm won, so a reservation the
+// projection saw expire is left as is rather than failing the event.
+func (p *Projector) HandleConfirmed(ctx context.Context, payload []byte) error {
+	env, err := ticketevent.Decode(ticketevent.TopicConfirmed, payload)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+	data, err := env.Confirmed()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+	if data.ReservationID == "" {
+		return fmt.Errorf("%w: booking without reservation id", ErrInvalidPayload)
+	}
+	if err := p.transition(ctx, data.ReservationID, entity.ReservationStatusConfirmed); err != nil {
+		return err
+	}
+	_, err = p.bookings.CreateIfNotExists(ctx, data.Booking())
+	return err
+}
+
+func (p *Projector) HandleExpired(ctx context.Context, payload []byte) error {
+	env, err := ticketevent.Decode(ticketevent.TopicExpired, payload)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+	data, err := env.Expired()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+	if data.ReservationID == "" {
+		return fmt.Errorf("%w: expiry without reservation id", ErrInvalidPayload)
+	}
+	return p.transition(ctx, data.ReservationID, entity.ReservationStatusExpired)
+}
+
+func (p *Projector) transition(ctx context.Context, id, status string) error {
+	_, err := p.reservations.Transition(ctx, id, status)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return ErrRetryLater
+	case errors.Is(err, entity.ErrInvalidTransition):
+		// Already in a later or competing terminal state.
+		return nil
+	}
+	return err
+}
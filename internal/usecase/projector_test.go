@@ -0,0 +1,84 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"concert-booking/internal/domain/entity"
+	"concert-booking/internal/domain/ticketevent"
+	"concert-booking/internal/infrastructure/memory"
+)
+
+func TestProjectorConvergesOutOfOrder(t *testing.T) {
+	ctx := context.Background()
+	reservations := memory.NewReservationRepository()
+	bookings := memory.NewBookingRepository()
+	h := NewProjector(reservations, bookings).Handlers()
+
+	booking, _ := json.Marshal(entity.Booking{ID: "book-1", ReservationID: "res-1", PaymentStatus: "paid", CreatedAt: time.Now()})
+	if err := h["ticket.confirmed"](ctx, booking); !errors.Is(err, ErrRetryLater) {
+		t.Fatalf("expected confirm before reserve to be retried, got %v", err)
+	}
+
+	reserved, _ := json.Marshal(entity.Reservation{ID: "res-1", UserID: "u1", EventID: "event-1", Category: "VIP", Qty: 1, Status: entity.ReservationStatusReserved})
+	if err := h["ticket.reserved"](ctx, reserved); err != nil {
+		t.Fatalf("reserved: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := h["ticket.confirmed"](ctx, booking); err != nil {
+			t.Fatalf("confirmed (delivery %d): %v", i+1, err)
+		}
+	}
+	expired, _ := json.Marshal(map[string]string{"reservation_id": "res-1", "status": "expired"})
+	if err := h["ticket.expired"](ctx, expired); err != nil {
+		t.Fatalf("late expiry should be ignored, got %v", err)
+	}
+
+	res, err := reservations.FindByID(ctx, "res-1")
+	if err != nil || res.Status != entity.ReservationStatusConfirmed {
+		t.Fatalf("expected confirmed reservation, got %+v (%v)", res, err)
+	}
+	if b, err := bookings.FindByReservationID(ctx, "res-1"); err != nil || b.ID != "book-1" {
+		t.Fatalf("expected booking persisted, got %+v (%v)", b, err)
+	}
+}
+
+func TestProjectorExpires(t *testing.T) {
+	ctx := context.Background()
+	reservations := memory.NewReservationRepository()
+	p := NewProjector(reservations, memory.NewBookingRepository())
+	_ = reservations.Upsert(ctx, entity.Reservation{ID: "res-1", Status: entity.ReservationStatusReserved})
+	if err := p.HandleExpired(ctx, []byte(`{"reservation_id":"res-1","status":"expired"}`)); err != nil {
+		t.Fatalf("expired: %v", err)
+	}
+	if res, _ := reservations.FindByID(ctx, "res-1"); res.Status != entity.ReservationStatusExpired {
+		t.Fatalf("expected expired, got %s", res.Status)
+	}
+	if err := p.HandleExpired(ctx, []byte(`{`)); !errors.Is(err, ErrInvalidPayload) {
+		t.Fatalf("expected invalid payload, got %v", err)
+	}
+}
+
+func TestProjectorDecodesBothEncodings(t *testing.T) {
+	ctx := context.Background()
+	reservations := memory.NewReservationRepository()
+	p := NewProjector(reservations, memory.NewBookingRepository())
+	for i, enc := range []ticketevent.Encoding{ticketevent.EncodingJSON, ticketevent.EncodingProtobuf} {
+		id := fmt.Sprintf("res-%d", i)
+		data := ticketevent.Reserved{ReservationID: id, UserID: "u1", EventID: "event-1", Category: "VIP", Qty: 2, Status: entity.ReservationStatusReserved}
+		payload, err := enc.New(ctx, "m-"+id, ticketevent.TopicReserved, id, data, time.Now())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := p.HandleReserved(ctx, payload); err != nil {
+			t.Fatalf("%s: %v", enc, err)
+		}
+		if res, err := reservations.FindByID(ctx, id); err != nil || res.Qty != 2 || res.Category != "VIP" {
+			t.Fatalf("%s: expected projected reservation, got %+v (%v)", enc, res, err)
+		}
+	}
+}
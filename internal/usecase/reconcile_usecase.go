@@ -0,0 +1,190 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"concert-booking/internal/domain/entity"
+	"concert-booking/internal/domain/repository"
+	"concert-booking/internal/domain/service"
+	"concert-booking/internal/observability/metrics"
+)
+
+var ErrReconcileBusy = errors.New("reconciliation already running")
+
+const reconcileLockName = "stock_reconcile"
+
+type StockDiscrepancy struct {
+	EventID    string `json:"event_id"`
+	Category   string `json:"category"`
+	TotalStock int    `json:"total_stock"`
+	Held       int    `json:"held"`
+	Expected   int    `json:"expected"`
+	Actual     int    `json:"actual"`
+	Drift      int    `json:"drift"`
+	Repaired   bool   `json:"repaired"`
+}
+
+type ReconcileReport struct {
+	CheckedAt     time.Time          `json:"checked_at"`
+	Categories    int                `json:"categories"`
+	Skipped       []string           `json:"skipped,omitempty"`
+	Discrepancies []StockDiscrepancy `json:"discrepancies"`
+}
+
+// ReconcileUsecase compares live stock counters with the stock implied by
+// TotalStock minus every reservation that still holds tickets. The stock
+// store's own reservation records win over Postgres when both know an ID,
+// since Postgres is written asynchronously and may lag behind.
+type ReconcileUsecase struct {
+	categories   repository.TicketCategoryRepository
+	reservations repository.ReservationRepository
+	stock        service.StockService
+	reconciler   service.StockReconciler
+	now          func() time.Time
+	owner        string
+}
+
+func NewReconcileUsecase(categories repository.TicketCategoryRepository, reservations repository.ReservationRepository, stock service.StockService, reconciler service.StockReconciler, now func() time.Time, owner string) *ReconcileUsecase {
+	return &ReconcileUsecase{categories: categories, reservations: reservations, stock: stock, reconciler: reconciler, now: now, owner: owner}
+}
+
+func (u *ReconcileUsecase) Run(ctx context.Context, repair bool) (ReconcileReport, error) {
+	if repair {
+		ok, err := u.reconciler.AcquireLock(ctx, reconcileLockName, u.owner, time.Minute)
+		if err != nil {
+			return ReconcileReport{}, err
+		}
+		if !ok {
+			return ReconcileReport{}, ErrReconcileBusy
+		}
+		defer func() { _ = u.reconciler.ReleaseLock(context.Background(), reconcileLockName, u.owner) }()
+	}
+	metrics.IncReconcileRun()
+
+	now := u.now()
+	report := ReconcileReport{CheckedAt: now.UTC(), Discrepancies: []StockDiscrepancy{}}
+	categories, err := u.categories.List(ctx)
+	if err != nil {
+		return report, err
+	}
+	report.Categories = len(categories)
+	byEvent := map[string][]entity.TicketCategory{}
+	for _, c := range categories {
+		byEvent[c.EventID] = append(byEvent[c.EventID], c)
+	}
+
+	// Stock is read before and after collecting reservations; a category whose
+	// counter moved in between is skipped rather than reported as drift.
+	before, err := u.readStocks(ctx, byEvent)
+	if err != nil {
+		return report, err
+	}
+	held, err := u.heldStock(ctx, now)
+	if err != nil {
+		return report, err
+	}
+	after, err := u.readStocks(ctx, byEvent)
+	if err != nil {
+		return report, err
+	}
+
+	for _, c := range categories {
+		k := c.EventID + "|" + c.Name
+		if before[k] != after[k] {
+			report.Skipped = append(report.Skipped, c.EventID+"/"+c.Name)
+			continue
+		}
+		d := StockDiscrepancy{EventID: c.EventID, Category: c.Name, TotalStock: c.TotalStock, Held: held[k], Actual: after[k]}
+		d.Expected = c.TotalStock - d.Held
+		if d.Expected < 0 {
+			d.Expected = 0
+		}
+		d.Drift = d.Actual - d.Expected
+		metrics.SetStockDrift(c.EventID, c.Name, d.Drift)
+		if d.Drift == 0 {
+			continue
+		}
+		if repair {
+			ok, err := u.reconciler.CompareAndSetStock(ctx, c.EventID, c.Name, d.Actual, d.Expected)
+			if err != nil {
+				log.Printf("reconcile repair %s/%s failed: %v", c.EventID, c.Name, err)
+			}
+			if ok {
+				d.Repaired = true
+				metrics.IncStockRepair()
+				metrics.SetStockDrift(c.EventID, c.Name, 0)
+			}
+		}
+		report.Discrepancies = append(report.Discrepancies, d)
+	}
+	return report, nil
+}
+
+func (u *ReconcileUsecase) StartPeriodic(ctx context.Context, interval time.Duration, repair bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := u.Run(ctx, repair)
+			if err != nil {
+				if !errors.Is(err, ErrReconcileBusy) {
+					log.Printf("stock reconcile failed: %v", err)
+				}
+				continue
+			}
+			for _, d := range report.Discrepancies {
+				log.Printf("stock drift %s/%s: actual=%d expected=%d repaired=%t", d.EventID, d.Category, d.Actual, d.Expected, d.Repaired)
+			}
+		}
+	}
+}
+
+func (u *ReconcileUsecase) readStocks(ctx context.Context, byEvent map[string][]entity.TicketCategory) (map[string]int, error) {
+	out := map[string]int{}
+	for eventID, items := range byEvent {
+		names := make([]string, 0, len(items))
+		for _, c := range items {
+			names = append(names, c.Name)
+		}
+		stocks, err := u.stock.GetStocks(ctx, eventID, names)
+		if err != nil {
+			return nil, err
+		}
+		for name, n := range stocks {
+			out[eventID+"|"+name] = n
+		}
+	}
+	return out, nil
+}
+
+func (u *ReconcileUsecase) heldStock(ctx context.Context, now time.Time) (map[string]int, error) {
+	metas, err := u.reconciler.ReservationSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	persisted, err := u.reservations.FindHolding(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	held := map[string]int{}
+	known := make(map[string]struct{}, len(metas))
+	for _, m := range metas {
+		known[m.ReservationID] = struct{}{}
+		if m.Status == entity.ReservationStatusReserved || m.Status == entity.ReservationStatusConfirmed {
+			held[m.EventID+"|"+m.Category] += m.Qty
+		}
+	}
+	for _, r := range persisted {
+		if _, ok := known[r.ID]; ok {
+			continue
+		}
+		held[r.EventID+"|"+r.Category] += r.Qty
+	}
+	return held, nil
+}
@@ -0,0 +1,45 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"concert-booking/internal/domain/entity"
+	"concert-booking/internal/domain/service"
+	"concert-booking/internal/infrastructure/memory"
+)
+
+func TestReconcileDetectsAndRepairsDrift(t *testing.T) {
+	ctx := context.Background()
+	categories := memory.NewTicketCategoryRepository()
+	reservations := memory.NewReservationRepository()
+	stock := memory.NewStockService()
+
+	_ = categories.Create(ctx, entity.TicketCategory{ID: "cat-1", EventID: "event-1", Name: "VIP", TotalStock: 10})
+	_ = stock.InitStock(ctx, "event-1", "VIP", 10)
+	_ = stock.Reserve(ctx, service.ReservationMeta{ReservationID: "res-1", EventID: "event-1", Category: "VIP", Qty: 3}, time.Minute)
+	// Simulate a lost write: the counter claims more stock than exists.
+	_, _ = stock.CompareAndSetStock(ctx, "event-1", "VIP", 7, 9)
+
+	u := NewReconcileUsecase(categories, reservations, stock, stock, time.Now, "test")
+	report, err := u.Run(ctx, false)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if len(report.Discrepancies) != 1 || report.Discrepancies[0].Expected != 7 || report.Discrepancies[0].Drift != 2 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	report, err = u.Run(ctx, true)
+	if err != nil {
+		t.Fatalf("reconcile repair: %v", err)
+	}
+	if !report.Discrepancies[0].Repaired {
+		t.Fatalf("expected repair, got %+v", report)
+	}
+	stocks, _ := stock.GetStocks(ctx, "event-1", []string{"VIP"})
+	if stocks["VIP"] != 7 {
+		t.Fatalf("expected stock 7 after repair, got %d", stocks["VIP"])
+	}
+}
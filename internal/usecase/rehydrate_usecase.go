@@ -0,0 +1,228 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"concert-booking/internal/domain/entity"
+	"concert-booking/internal/domain/repository"
+	"concert-booking/internal/domain/service"
+)
+
+var ErrEventNotReady = errors.New("event stock is being restored")
+
+const (
+	RehydrationPending = "pending"
+	RehydrationRunning = "running"
+	RehydrationReady   = "ready"
+	RehydrationFailed  = "failed"
+)
+
+type RehydrationStatus struct {
+	State         string    `json:"state"`
+	PendingEvents []string  `json:"pending_events,omitempty"`
+	Restored      int       `json:"restored_categories"`
+	LastRun       time.Time `json:"last_run,omitempty"`
+	LastError     string    `json:"last_error,omitempty"`
+}
+
+// RehydrateUsecase rebuilds stock counters and live reservations from
+// Postgres when the stock store comes back empty. Until the first check has
+// finished no event is considered ready; afterwards only events that are
+// being restored are refused.
+type RehydrateUsecase struct {
+	categories   repository.TicketCategoryRepository
+	reservations repository.ReservationRepository
+	stock        service.StockService
+	rehydrator   service.StockRehydrator
+	now          func() time.Time
+
+	mu      sync.RWMutex
+	status  RehydrationStatus
+	checked bool
+	pending map[string]struct{}
+}
+
+func NewRehydrateUsecase(categories repository.TicketCategoryRepository, reservations repository.ReservationRepository, stock service.StockService, rehydrator service.StockRehydrator, now func() time.Time) *RehydrateUsecase {
+	return &RehydrateUsecase{
+		categories:   categories,
+		reservations: reservations,
+		stock:        stock,
+		rehydrator:   rehydrator,
+		now:          now,
+		status:       RehydrationStatus{State: RehydrationPending},
+		pending:      map[string]struct{}{},
+	}
+}
+
+func (u *RehydrateUsecase) Ready(eventID string) bool {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	if !u.checked {
+		return false
+	}
+	_, waiting := u.pending[eventID]
+	return !waiting
+}
+
+func (u *RehydrateUsecase) Status() RehydrationStatus {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	st := u.status
+	st.PendingEvents = make([]string, 0, len(u.pending))
+	for id := range u.pending {
+		st.PendingEvents = append(st.PendingEvents, id)
+	}
+	sort.Strings(st.PendingEvents)
+	return st
+}
+
+func (u *RehydrateUsecase) Start(ctx context.Context, interval time.Duration) {
+	u.runAndLog(ctx)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			u.runAndLog(ctx)
+		}
+	}
+}
+
+func (u *RehydrateUsecase) runAndLog(ctx context.Context) {
+	if n, err := u.Run(ctx); err != nil {
+		log.Printf("stock rehydration failed: %v", err)
+	} else if n > 0 {
+		log.Printf("stock rehydrated for %d categories", n)
+	}
+}
+
+// Run restores every category whose stock counter is missing and returns how many were restored.
+// The state only reads running while categories are actually being restored,
+// not during the periodic check that finds nothing missing.
+func (u *RehydrateUsecase) Run(ctx context.Context) (int, error) {
+	restored, err := u.run(ctx)
+	u.mu.Lock()
+	u.status.Restored += restored
+	u.mu.Unlock()
+	if err != nil {
+		u.setState(RehydrationFailed, err)
+		return restored, err
+	}
+	u.setState(RehydrationReady, nil)
+	return restored, nil
+}
+
+func (u *RehydrateUsecase) run(ctx context.Context) (int, error) {
+	categories, err := u.categories.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	byEvent := map[string][]entity.TicketCategory{}
+	for _, c := range categories {
+		byEvent[c.EventID] = append(byEvent[c.EventID], c)
+	}
+
+	missing := map[string][]string{}
+	for eventID, items := range byEvent {
+		names := make([]string, 0, len(items))
+		for _, c := range items {
+			names = append(names, c.Name)
+		}
+		gone, err := u.rehydrator.MissingStock(ctx, eventID, names)
+		if err != nil {
+			return 0, err
+		}
+		if len(gone) > 0 {
+			missing[eventID] = gone
+		}
+	}
+	u.mu.Lock()
+	for eventID := range missing {
+		u.pending[eventID] = struct{}{}
+	}
+	if len(missing) > 0 {
+		u.status.State = RehydrationRunning
+	}
+	u.checked = true
+	u.mu.Unlock()
+	if len(missing) == 0 {
+		return 0, nil
+	}
+
+	now := u.now()
+	holding, err := u.reservations.FindHolding(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+	restored := 0
+	for eventID, names := range missing {
+		wanted := make(map[string]struct{}, len(names))
+		for _, name := range names {
+			wanted[name] = struct{}{}
+		}
+		held := map[string]int{}
+		metas := make([]service.ReservationMeta, 0)
+		for _, r := range holding {
+			if r.EventID != eventID {
+				continue
+			}
+			if _, ok := wanted[r.Category]; !ok {
+				continue
+			}
+			held[r.Category] += r.Qty
+			metas = append(metas, service.ReservationMeta{
+				ReservationID: r.ID,
+				UserID:        r.UserID,
+				EventID:       r.EventID,
+				Category:      r.Category,
+				Qty:           r.Qty,
+				Status:        r.Status,
+				ExpiredAt:     r.ExpiredAt,
+			})
+		}
+		remaining := map[string]int{}
+		for _, c := range byEvent[eventID] {
+			if _, ok := wanted[c.Name]; !ok {
+				continue
+			}
+			remaining[c.Name] = max(c.TotalStock-held[c.Name], 0)
+			if err := u.rehydrator.PrepareStock(ctx, eventID, c.Name, remaining[c.Name]); err != nil {
+				return restored, err
+			}
+		}
+		if err := u.rehydrator.RestoreReservations(ctx, metas); err != nil {
+			return restored, err
+		}
+		for name, n := range remaining {
+			if err := u.stock.InitStock(ctx, eventID, name, n); err != nil {
+				return restored, err
+			}
+			restored++
+		}
+		u.mu.Lock()
+		delete(u.pending, eventID)
+		u.mu.Unlock()
+	}
+	return restored, nil
+}
+
+func (u *RehydrateUsecase) setState(state string, err error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.status.State = state
+	if state == RehydrationRunning {
+		return
+	}
+	u.status.LastRun = u.now().UTC()
+	u.status.LastError = ""
+	if err != nil {
+		u.status.LastError = err.Error()
+	}
+}
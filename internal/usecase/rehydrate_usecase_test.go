@@ -0,0 +1,74 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"concert-booking/internal/domain/entity"
+	"concert-booking/internal/infrastructure/memory"
+)
+
+func TestRehydrateRestoresMissingStock(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	categories := memory.NewTicketCategoryRepository()
+	reservations := memory.NewReservationRepository()
+	stock := memory.NewStockService()
+
+	_ = categories.Create(ctx, entity.TicketCategory{ID: "cat-1", EventID: "event-1", Name: "VIP", TotalStock: 10})
+	_ = reservations.Upsert(ctx, entity.Reservation{ID: "res-1", EventID: "event-1", Category: "VIP", Qty: 2, Status: entity.ReservationStatusReserved, ExpiredAt: now.Add(time.Minute)})
+	_ = reservations.Upsert(ctx, entity.Reservation{ID: "res-2", EventID: "event-1", Category: "VIP", Qty: 1, Status: entity.ReservationStatusConfirmed, ExpiredAt: now})
+	_ = reservations.Upsert(ctx, entity.Reservation{ID: "res-3", EventID: "event-1", Category: "VIP", Qty: 4, Status: entity.ReservationStatusReserved, ExpiredAt: now.Add(-time.Minute)})
+
+	u := NewRehydrateUsecase(categories, reservations, stock, stock, func() time.Time { return now })
+	if u.Ready("event-1") {
+		t.Fatal("event must not be ready before the first check")
+	}
+	n, err := u.Run(ctx)
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 restored category, got %d (%v)", n, err)
+	}
+	stocks, _ := stock.GetStocks(ctx, "event-1", []string{"VIP"})
+	if stocks["VIP"] != 7 {
+		t.Fatalf("expected stock 7, got %d", stocks["VIP"])
+	}
+	if _, err := stock.GetReservation(ctx, "res-1"); err != nil {
+		t.Fatalf("expected res-1 restored: %v", err)
+	}
+	if !u.Ready("event-1") || u.Status().State != RehydrationReady {
+		t.Fatalf("expected event ready, status %+v", u.Status())
+	}
+}
+
+// observingRehydrator records the rehydration state each check runs in.
+type observingRehydrator struct {
+	*memory.StockService
+	u      *RehydrateUsecase
+	states []string
+}
+
+func (r *observingRehydrator) MissingStock(ctx context.Context, eventID string, categories []string) ([]string, error) {
+	r.states = append(r.states, r.u.Status().State)
+	return r.StockService.MissingStock(ctx, eventID, categories)
+}
+
+func TestRehydrateCheckFindingNothingStaysReady(t *testing.T) {
+	ctx := context.Background()
+	categories := memory.NewTicketCategoryRepository()
+	stock := memory.NewStockService()
+	_ = categories.Create(ctx, entity.TicketCategory{ID: "cat-1", EventID: "event-1", Name: "VIP", TotalStock: 10})
+	_ = stock.InitStock(ctx, "event-1", "VIP", 10)
+
+	rehydrator := &observingRehydrator{StockService: stock}
+	u := NewRehydrateUsecase(categories, memory.NewReservationRepository(), stock, rehydrator, time.Now)
+	rehydrator.u = u
+	for range 2 {
+		if _, err := u.Run(ctx); err != nil {
+			t.Fatalf("run: %v", err)
+		}
+	}
+	if len(rehydrator.states) != 2 || rehydrator.states[1] != RehydrationReady || u.Status().State != RehydrationReady {
+		t.Fatalf("expected a check with nothing missing to keep the state ready, saw %v", rehydrator.states)
+	}
+}
@@ -0,0 +1,160 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+
+	"concert-booking/internal/domain/repository"
+	"concert-booking/internal/domain/ticketevent"
+)
+
+// ReplayOrder is the order topics are replayed in. Each topic is replayed in
+// full before the next, so a confirmation or expiry always finds the
+// reservation row its topic depends on when that row is in the replayed
+// range. Only one of confirm and expire wins for a reservation, so the
+// result matches live consumption.
+var ReplayOrder = []string{ticketevent.TopicReserved, ticketevent.TopicConfirmed, ticketevent.TopicExpired}
+
+// Replay outcomes, used as metric labels.
+const (
+	ReplayApplied = "applied"
+	// ReplayUnresolved is an event whose reservation is neither in the store
+	// nor in the replayed range; start the replay earlier to resolve it.
+	ReplayUnresolved = "unresolved"
+	ReplayInvalid    = "invalid"
+)
+
+// Replay re-applies retained events through the worker's handlers. They are
+// idempotent, so replaying over a populated store only fills gaps, and
+// replaying into an empty one rebuilds it.
+type Replay struct {
+	handlers map[string]EventHandler
+	touched  map[string]struct{}
+}
+
+func NewReplay(p *Projector) *Replay {
+	return &Replay{handlers: p.Handlers(), touched: map[string]struct{}{}}
+}
+
+// Apply applies one message and reports its outcome. Only store failures are
+// returned as errors; the caller should stop on them.
+func (r *Replay) Apply(ctx context.Context, topic string, payload []byte) (string, error) {
+	h, ok := r.handlers[topic]
+	if !ok {
+		return ReplayInvalid, nil
+	}
+	if id := reservationIDOf(topic, payload); id != "" {
+		r.touched[id] = struct{}{}
+	}
+	switch err := h(ctx, payload); {
+	case err == nil:
+		return ReplayApplied, nil
+	case errors.Is(err, ErrInvalidPayload):
+		return ReplayInvalid, nil
+	case errors.Is(err, ErrRetryLater):
+		return ReplayUnresolved, nil
+	default:
+		return "", err
+	}
+}
+
+// Touched returns the reservations the replayed events referred to.
+func (r *Replay) Touched() []string {
+	out := make([]string, 0, len(r.touched))
+	for id := range r.touched {
+		out = append(out, id)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func reservationIDOf(topic string, payload []byte) string {
+	env, err := ticketevent.Decode(topic, payload)
+	if err != nil {
+		return ""
+	}
+	switch env.Type {
+	case ticketevent.TypeReserved:
+		d, _ := env.Reserved()
+		return d.ReservationID
+	case ticketevent.TypeConfirmed:
+		d, _ := env.Confirmed()
+		return d.ReservationID
+	case ticketevent.TypeExpired:
+		d, _ := env.Expired()
+		return d.ReservationID
+	}
+	return ""
+}
+
+// ProjectionStore is a set of projection tables a replay can write to or be
+// compared with.
+type ProjectionStore struct {
+	Reservations repository.ReservationRepository
+	Bookings     repository.BookingRepository
+}
+
+// ProjectionDiff is one field where the replayed projection differs from the
+// current one. A missing row is reported with Field "row".
+type ProjectionDiff struct {
+	ReservationID string `json:"reservation_id"`
+	Field         string `json:"field"`
+	Replayed      string `json:"replayed"`
+	Current       string `json:"current"`
+}
+
+// DiffProjections compares the rows of ids in replayed with current. Rows
+// only current has are not reported: a replay of part of the history does
+// not know about them.
+func DiffProjections(ctx context.Context, ids []string, replayed, current ProjectionStore) ([]ProjectionDiff, error) {
+	type field struct{ name, want, have string }
+	var out []ProjectionDiff
+	for _, id := range ids {
+		want, err := replayed.Reservations.FindByID(ctx, id)
+		if errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		have, err := current.Reservations.FindByID(ctx, id)
+		if errors.Is(err, repository.ErrNotFound) {
+			out = append(out, ProjectionDiff{ReservationID: id, Field: "row", Replayed: "reservation", Current: "missing"})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		fields := []field{
+			{"status", want.Status, have.Status},
+			{"user_id", want.UserID, have.UserID},
+			{"event_id", want.EventID, have.EventID},
+			{"category", want.Category, have.Category},
+			{"qty", strconv.Itoa(want.Qty), strconv.Itoa(have.Qty)},
+		}
+		wantBooking, werr := replayed.Bookings.FindByReservationID(ctx, id)
+		haveBooking, herr := current.Bookings.FindByReservationID(ctx, id)
+		for _, e := range []error{werr, herr} {
+			if e != nil && !errors.Is(e, repository.ErrNotFound) {
+				return nil, e
+			}
+		}
+		switch {
+		case werr == nil && herr != nil:
+			out = append(out, ProjectionDiff{ReservationID: id, Field: "row", Replayed: "booking", Current: "missing"})
+		case werr == nil:
+			fields = append(fields,
+				field{"booking_id", wantBooking.ID, haveBooking.ID},
+				field{"payment_status", wantBooking.PaymentStatus, haveBooking.PaymentStatus},
+			)
+		}
+		for _, f := range fields {
+			if f.want != f.have {
+				out = append(out, ProjectionDiff{ReservationID: id, Field: f.name, Replayed: f.want, Current: f.have})
+			}
+		}
+	}
+	return out, nil
+}
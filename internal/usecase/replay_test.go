@@ -0,0 +1,79 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"concert-booking/internal/domain/entity"
+	"concert-booking/internal/domain/ticketevent"
+	"concert-booking/internal/infrastructure/memory"
+)
+
+func replayEvent(t *testing.T, topic, id string, data any) []byte {
+	t.Helper()
+	payload, err := ticketevent.EncodingJSON.New(context.Background(), topic+":"+id, topic, id, data, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payload
+}
+
+func TestReplayRebuildsAndDiffs(t *testing.T) {
+	ctx := context.Background()
+	messages := map[string][][]byte{
+		ticketevent.TopicReserved: {
+			replayEvent(t, ticketevent.TopicReserved, "res-1", ticketevent.Reserved{ReservationID: "res-1", UserID: "u1", EventID: "e1", Category: "VIP", Qty: 1, Status: entity.ReservationStatusReserved}),
+			replayEvent(t, ticketevent.TopicReserved, "res-2", ticketevent.Reserved{ReservationID: "res-2", UserID: "u2", EventID: "e1", Category: "VIP", Qty: 2, Status: entity.ReservationStatusReserved}),
+			[]byte(`{`),
+		},
+		ticketevent.TopicConfirmed: {
+			replayEvent(t, ticketevent.TopicConfirmed, "res-1", ticketevent.Confirmed{BookingID: "b1", ReservationID: "res-1", EventID: "e1", PaymentStatus: "paid"}),
+			replayEvent(t, ticketevent.TopicConfirmed, "res-0", ticketevent.Confirmed{BookingID: "b0", ReservationID: "res-0", EventID: "e1", PaymentStatus: "paid"}),
+		},
+		ticketevent.TopicExpired: {
+			replayEvent(t, ticketevent.TopicExpired, "res-1", ticketevent.Expired{ReservationID: "res-1", EventID: "e1", Status: entity.ReservationStatusExpired}),
+			replayEvent(t, ticketevent.TopicExpired, "res-2", ticketevent.Expired{ReservationID: "res-2", EventID: "e1", Status: entity.ReservationStatusExpired}),
+		},
+	}
+
+	// The live projection missed the expiry of res-2 and never saw res-1.
+	current := ProjectionStore{Reservations: memory.NewReservationRepository(), Bookings: memory.NewBookingRepository()}
+	_ = current.Reservations.Upsert(ctx, entity.Reservation{ID: "res-2", UserID: "u2", EventID: "e1", Category: "VIP", Qty: 2, Status: entity.ReservationStatusReserved})
+
+	replayed := ProjectionStore{Reservations: memory.NewReservationRepository(), Bookings: memory.NewBookingRepository()}
+	replay := NewReplay(NewProjector(replayed.Reservations, replayed.Bookings))
+	results := map[string]int{}
+	for _, topic := range ReplayOrder {
+		for _, payload := range messages[topic] {
+			result, err := replay.Apply(ctx, topic, payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			results[result]++
+		}
+	}
+	if results[ReplayApplied] != 5 || results[ReplayUnresolved] != 1 || results[ReplayInvalid] != 1 {
+		t.Fatalf("unexpected outcomes %v", results)
+	}
+	if res, _ := replayed.Reservations.FindByID(ctx, "res-1"); res.Status != entity.ReservationStatusConfirmed {
+		t.Fatalf("expected the late expiry to be ignored, got %s", res.Status)
+	}
+
+	diffs, err := DiffProjections(ctx, replay.Touched(), replayed, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ProjectionDiff{
+		{ReservationID: "res-1", Field: "row", Replayed: "reservation", Current: "missing"},
+		{ReservationID: "res-2", Field: "status", Replayed: entity.ReservationStatusExpired, Current: entity.ReservationStatusReserved},
+	}
+	if len(diffs) != len(want) {
+		t.Fatalf("expected %v, got %v", want, diffs)
+	}
+	for i := range want {
+		if diffs[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, diffs)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"concert-booking/internal/domain/entity"
+	"concert-booking/internal/domain/repository"
+	"concert-booking/internal/domain/service"
+	"concert-booking/internal/observability/metrics"
+)
+
+// TopicReservationRequests carries queued reserve calls, keyed by event so
+// all requests for one event land on one partition in arrival order.
+const TopicReservationRequests = "reservation.requests"
+
+var ErrRequestQueueUnavailable = errors.New("reservation queue unavailable")
+
+type reservationRequestMessage struct {
+	ID          string    `json:"id"`
+	UserID      string    `json:"user_id"`
+	EventID     string    `json:"event_id"`
+	Category    string    `json:"category"`
+	Qty         int       `json:"qty"`
+	RequestedAt time.Time `json:"requested_at"`
+}
+
+// ReservationRequestUsecase is the async reserve mode: requests are queued
+// and answered at once, and a single allocator per partition takes stock
+// in queue order, so nobody overtakes an earlier request for the same event.
+type ReservationRequestUsecase struct {
+	reservations *ReservationUsecase
+	requests     repository.ReservationRequestRepository
+	producer     service.EventProducer
+	now          func() time.Time
+	newID        func() string
+	// attempts bounds how often a stock error is retried before the request
+	// fails; retryDelay is the first delay and the poll interval while the
+	// event is being restored.
+	attempts   int
+	retryDelay time.Duration
+}
+
+func NewReservationRequestUsecase(reservations *ReservationUsecase, requests repository.ReservationRequestRepository, producer service.EventProducer, now func() time.Time, newID func() string) *ReservationRequestUsecase {
+	return &ReservationRequestUsecase{reservations: reservations, requests: requests, producer: producer, now: now, newID: newID, attempts: 5, retryDelay: 200 * time.Millisecond}
+}
+
+// Enqueue records the request and publishes it for the allocator. Input
+// errors, a sold-out category and a restoring event are reported right away.
+func (u *ReservationRequestUsecase) Enqueue(ctx context.Context, userID, eventID, category string, qty int) (entity.ReservationRequest, error) {
+	category, err := u.reservations.admit(userID, eventID, category, qty)
+	if err != nil {
+		return entity.ReservationRequest{}, err
+	}
+	now := u.now()
+	req := entity.ReservationRequest{
+		ID:        u.newID(),
+		UserID:    userID,
+		EventID:   eventID,
+		Category:  category,
+		Qty:       qty,
+		Status:    entity.ReservationRequestQueued,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := u.requests.Upsert(ctx, req); err != nil {
+		return entity.ReservationRequest{}, err
+	}
+	payload, err := json.Marshal(reservationRequestMessage{ID: req.ID, UserID: userID, EventID: eventID, Category: category, Qty: qty, RequestedAt: now})
+	if err != nil {
+		return entity.ReservationRequest{}, err
+	}
+	if err := u.producer.Publish(service.WithMessageID(ctx, req.ID), TopicReservationRequests, eventID, payload); err != nil {
+		req.Status, req.Reason, req.UpdatedAt = entity.ReservationRequestFailed, "not queued", u.now()
+		_ = u.requests.Upsert(context.WithoutCancel(ctx), req)
+		return entity.ReservationRequest{}, fmt.Errorf("%w: %v", ErrRequestQueueUnavailable, err)
+	}
+	return req, nil
+}
+
+// Get returns a request of the user; other users' requests are not found.
+func (u *ReservationRequestUsecase) Get(ctx context.Context, userID, id string) (entity.ReservationRequest, error) {
+	req, err := u.requests.Get(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && req.UserID != userID) {
+		return entity.ReservationRequest{}, ErrNotFound
+	}
+	return req, err
+}
+
+// Handlers returns the allocator, to be consumed by exactly one consumer
+// group.
+func (u *ReservationRequestUsecase) Handlers() map[string]EventHandler {
+	return map[string]EventHandler{TopicReservationRequests: u.allocate}
+}
+
+// allocate settles one request. Every outcome is recorded on the request, so
+// none is left queued behind a dead-lettered message; only a failure to
+// read or record the request is returned to the consumer.
+func (u *ReservationRequestUsecase) allocate(ctx context.Context, payload []byte) error {
+	var msg reservationRequestMessage
+	if err := json.Unmarshal(payload, &msg); err != nil || msg.ID == "" {
+		return fmt.Errorf("%w: reservation request", ErrInvalidPayload)
+	}
+	req, err := u.requests.Get(ctx, msg.ID)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		req = entity.ReservationRequest{ID: msg.ID, UserID: msg.UserID, EventID: msg.EventID, Category: msg.Category, Qty: msg.Qty, Status: entity.ReservationRequestQueued, CreatedAt: msg.RequestedAt}
+	case err != nil:
+		return err
+	case req.Done():
+		// Redelivered after it was settled.
+		return nil
+	}
+
+	res, err := u.settle(ctx, req)
+	switch {
+	case err == nil:
+		metrics.IncReservationSuccess()
+		req.Status, req.ReservationID, req.ExpiredAt = entity.ReservationRequestReserved, res.ID, &res.ExpiredAt
+	case errors.Is(err, service.ErrOutOfStock), errors.Is(err, ErrInvalidInput):
+		metrics.IncReservationFailed()
+		req.Status, req.Reason = entity.ReservationRequestRejected, err.Error()
+	case errors.Is(err, ErrReservationExpired):
+		// Redelivered after the first delivery's hold ran out.
+		req.Status, req.ReservationID, req.Reason = entity.ReservationRequestExpired, res.ID, err.Error()
+	case ctx.Err() != nil:
+		// Shutting down: the request is redelivered to the next allocator.
+		return err
+	default:
+		metrics.IncReservationFailed()
+		req.Status, req.Reason = entity.ReservationRequestFailed, err.Error()
+	}
+	req.UpdatedAt = u.now()
+	return u.requests.Upsert(ctx, req)
+}
+
+// settle retries the allocation in place so the request keeps its place in
+// the queue. A restoring event is waited for as long as it takes; other
+// errors are retried with backoff up to attempts times.
+func (u *ReservationRequestUsecase) settle(ctx context.Context, req entity.ReservationRequest) (entity.Reservation, error) {
+	delay := u.retryDelay
+	for failures := 0; ; {
+		res, err := u.reservations.allocate(ctx, req)
+		if err == nil || errors.Is(err, service.ErrOutOfStock) || errors.Is(err, ErrInvalidInput) || errors.Is(err, ErrReservationExpired) {
+			return res, err
+		}
+		wait := u.retryDelay
+		if !errors.Is(err, ErrEventNotReady) {
+			if failures++; failures >= u.attempts {
+				return entity.Reservation{}, err
+			}
+			wait, delay = delay, min(delay*2, 5*time.Second)
+		}
+		select {
+		case <-ctx.Done():
+			return entity.Reservation{}, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
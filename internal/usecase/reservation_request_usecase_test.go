@@ -0,0 +1,218 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"concert-booking/internal/domain/entity"
+	"concert-booking/internal/domain/service"
+	"concert-booking/internal/infrastructure/memory"
+)
+
+type queueProducer struct {
+	fail     bool
+	payloads [][]byte
+	keys     []string
+}
+
+func (p *queueProducer) Publish(_ context.Context, _ string, key string, value []byte) error {
+	if p.fail {
+		return errors.New("broker down")
+	}
+	p.keys = append(p.keys, key)
+	p.payloads = append(p.payloads, value)
+	return nil
+}
+
+func TestReservationRequestsAllocateInQueueOrder(t *testing.T) {
+	ctx := context.Background()
+	stock := memory.NewStockService()
+	_ = stock.InitStock(ctx, "event-1", "VIP", 2)
+	newID := seqIDs("req")
+	reservations := NewReservationUsecase(memory.NewTicketCategoryRepository(), memory.NewReservationRepository(), memory.NewBookingRepository(), stock, time.Now, newID, 5*time.Minute, 0, 1, true)
+	producer := &queueProducer{}
+	u := NewReservationRequestUsecase(reservations, memory.NewReservationRequestRepository(), producer, time.Now, newID)
+	for _, user := range []string{"user-1", "user-2", "user-3"} {
+		req, err := u.Enqueue(ctx, user, "event-1", "vip", 1)
+		if err != nil || req.Status != entity.ReservationRequestQueued {
+			t.Fatalf("enqueue %s: %+v %v", user, req, err)
+		}
+	}
+	if len(producer.payloads) != 3 || producer.keys[0] != "event-1" {
+		t.Fatalf("expected 3 requests keyed by event, got %v", producer.keys)
+	}
+
+	allocate := u.Handlers()[TopicReservationRequests]
+	for _, payload := range producer.payloads {
+		if err := allocate(ctx, payload); err != nil {
+			t.Fatalf("allocate: %v", err)
+		}
+	}
+	// A redelivered request must not take a second hold.
+	if err := allocate(ctx, producer.payloads[0]); err != nil {
+		t.Fatalf("redelivery: %v", err)
+	}
+
+	first, _ := u.Get(ctx, "user-1", "req-1")
+	if first.Status != entity.ReservationRequestReserved || first.ReservationID != "req-1" || first.ExpiredAt == nil {
+		t.Fatalf("expected first request reserved, got %+v", first)
+	}
+	second, _ := u.Get(ctx, "user-2", "req-2")
+	last, _ := u.Get(ctx, "user-3", "req-3")
+	if second.Status != entity.ReservationRequestReserved || last.Status != entity.ReservationRequestRejected || last.Reason == "" {
+		t.Fatalf("expected the last request rejected, got %+v and %+v", second, last)
+	}
+	left, _ := stock.GetStocks(ctx, "event-1", []string{"VIP"})
+	if left["VIP"] != 0 {
+		t.Fatalf("expected stock fully held once, got %d left", left["VIP"])
+	}
+	if _, err := u.Get(ctx, "user-2", "req-1"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected another user's request to be hidden, got %v", err)
+	}
+}
+
+func TestReservationRequestRedeliveryAfterHoldIsGone(t *testing.T) {
+	ctx := context.Background()
+	stock := memory.NewStockService()
+	_ = stock.InitStock(ctx, "event-1", "VIP", 2)
+	newID := seqIDs("req")
+	reservations := NewReservationUsecase(memory.NewTicketCategoryRepository(), memory.NewReservationRepository(), memory.NewBookingRepository(), stock, time.Now, newID, 5*time.Minute, 0, 1, true)
+	producer := &queueProducer{}
+	u := NewReservationRequestUsecase(reservations, memory.NewReservationRequestRepository(), producer, time.Now, newID)
+	if _, err := u.Enqueue(ctx, "user-1", "event-1", "VIP", 1); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	allocate := u.Handlers()[TopicReservationRequests]
+	if err := allocate(ctx, producer.payloads[0]); err != nil {
+		t.Fatalf("allocate: %v", err)
+	}
+	// The hold is released, then the request is redelivered as if the
+	// allocator had crashed before recording the result.
+	if _, err := stock.ReleaseReservation(ctx, "req-1"); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	req, _ := u.requests.Get(ctx, "req-1")
+	req.Status, req.ReservationID = entity.ReservationRequestQueued, ""
+	_ = u.requests.Upsert(ctx, req)
+
+	if err := allocate(ctx, producer.payloads[0]); err != nil {
+		t.Fatalf("redelivery: %v", err)
+	}
+	if left, _ := stock.GetStocks(ctx, "event-1", []string{"VIP"}); left["VIP"] != 2 {
+		t.Fatalf("expected no second hold, got %d left", left["VIP"])
+	}
+	if got, _ := u.Get(ctx, "user-1", "req-1"); got.Status != entity.ReservationRequestReserved || got.ReservationID != "req-1" {
+		t.Fatalf("expected the request settled with its reservation, got %+v", got)
+	}
+}
+
+func TestReservationRequestRedeliveryBeforeRowIsProjected(t *testing.T) {
+	ctx := context.Background()
+	stock := memory.NewStockService()
+	_ = stock.InitStock(ctx, "event-1", "VIP", 2)
+	// persistSync off: the reservation row is left to the projector, which
+	// has not run when the request is redelivered.
+	reservations := NewReservationUsecase(memory.NewTicketCategoryRepository(), memory.NewReservationRepository(), memory.NewBookingRepository(), stock, time.Now, func() string { return "req-1" }, 5*time.Minute, 0, 1, false)
+	producer := &queueProducer{}
+	u := NewReservationRequestUsecase(reservations, memory.NewReservationRequestRepository(), producer, time.Now, func() string { return "req-1" })
+	if _, err := u.Enqueue(ctx, "user-1", "event-1", "VIP", 1); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	allocate := u.Handlers()[TopicReservationRequests]
+	if err := allocate(ctx, producer.payloads[0]); err != nil {
+		t.Fatalf("allocate: %v", err)
+	}
+	if _, err := stock.ReleaseReservation(ctx, "req-1"); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	req, _ := u.requests.Get(ctx, "req-1")
+	req.Status, req.ReservationID, req.ExpiredAt = entity.ReservationRequestQueued, "", nil
+	_ = u.requests.Upsert(ctx, req)
+
+	if err := allocate(ctx, producer.payloads[0]); err != nil {
+		t.Fatalf("redelivery: %v", err)
+	}
+	if left, _ := stock.GetStocks(ctx, "event-1", []string{"VIP"}); left["VIP"] != 2 {
+		t.Fatalf("expected no second hold, got %d left", left["VIP"])
+	}
+	if got, _ := u.Get(ctx, "user-1", "req-1"); got.Status != entity.ReservationRequestExpired || got.ReservationID != "req-1" {
+		t.Fatalf("expected the request settled as expired, got %+v", got)
+	}
+}
+
+// flakyGate reports an event ready after a number of checks.
+type flakyGate struct{ checks, readyAfter int }
+
+func (g *flakyGate) Ready(string) bool {
+	g.checks++
+	return g.checks > g.readyAfter
+}
+
+// brokenStock fails every reserve with an error retrying cannot fix.
+type brokenStock struct{ *memory.StockService }
+
+func (brokenStock) Reserve(context.Context, service.ReservationMeta, time.Duration, ...service.OutboxEvent) error {
+	return errors.New("stock store down")
+}
+
+func TestReservationRequestsNeverStayQueued(t *testing.T) {
+	ctx := context.Background()
+	stock := memory.NewStockService()
+	_ = stock.InitStock(ctx, "event-1", "VIP", 2)
+	newID := seqIDs("req")
+	reservations := NewReservationUsecase(memory.NewTicketCategoryRepository(), memory.NewReservationRepository(), memory.NewBookingRepository(), stock, time.Now, newID, 5*time.Minute, 0, 1, true)
+	producer := &queueProducer{}
+	u := NewReservationRequestUsecase(reservations, memory.NewReservationRequestRepository(), producer, time.Now, newID)
+	u.retryDelay = time.Millisecond
+	if _, err := u.Enqueue(ctx, "user-1", "event-1", "VIP", 1); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	// The event starts restoring after the request was queued: the
+	// allocator waits in place instead of handing it back to the consumer.
+	gate := &flakyGate{readyAfter: 20}
+	u.reservations.SetReadinessGate(gate)
+	if err := u.Handlers()[TopicReservationRequests](ctx, producer.payloads[0]); err != nil {
+		t.Fatalf("allocate while restoring: %v", err)
+	}
+	if got, _ := u.Get(ctx, "user-1", "req-1"); got.Status != entity.ReservationRequestReserved || gate.checks <= 20 {
+		t.Fatalf("expected the request reserved once the event was ready, got %+v after %d checks", got, gate.checks)
+	}
+
+	broken := NewReservationUsecase(memory.NewTicketCategoryRepository(), memory.NewReservationRepository(), memory.NewBookingRepository(), brokenStock{memory.NewStockService()}, time.Now, func() string { return "res-1" }, 5*time.Minute, 0, 1, true)
+	u.reservations = broken
+	if _, err := u.Enqueue(ctx, "user-2", "event-1", "VIP", 1); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	if err := u.Handlers()[TopicReservationRequests](ctx, producer.payloads[1]); err != nil {
+		t.Fatalf("allocate with a broken stock store: %v", err)
+	}
+	if got, _ := u.Get(ctx, "user-2", "req-2"); got.Status != entity.ReservationRequestFailed || got.Reason == "" {
+		t.Fatalf("expected the request marked failed, got %+v", got)
+	}
+}
+
+func TestReservationRequestEnqueueFailures(t *testing.T) {
+	ctx := context.Background()
+	stock := memory.NewStockService()
+	_ = stock.InitStock(ctx, "event-1", "VIP", 1)
+	newID := seqIDs("req")
+	reservations := NewReservationUsecase(memory.NewTicketCategoryRepository(), memory.NewReservationRepository(), memory.NewBookingRepository(), stock, time.Now, newID, 5*time.Minute, 0, 1, true)
+	producer := &queueProducer{}
+	u := NewReservationRequestUsecase(reservations, memory.NewReservationRequestRepository(), producer, time.Now, newID)
+	if _, err := u.Enqueue(ctx, "user-1", "event-1", "VIP", 0); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected invalid input, got %v", err)
+	}
+	producer.fail = true
+	if _, err := u.Enqueue(ctx, "user-1", "event-1", "VIP", 1); !errors.Is(err, ErrRequestQueueUnavailable) {
+		t.Fatalf("expected queue unavailable, got %v", err)
+	}
+	req, err := u.Get(ctx, "user-1", "req-1")
+	if err != nil || req.Status != entity.ReservationRequestFailed {
+		t.Fatalf("expected the unqueued request marked failed, got %+v %v", req, err)
+	}
+	if err := u.Handlers()[TopicReservationRequests](ctx, []byte("{")); !errors.Is(err, ErrInvalidPayload) {
+		t.Fatalf("expected invalid payload, got %v", err)
+	}
+}
@@ -2,8 +2,8 @@ package usecase
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
+	"log"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -11,16 +11,26 @@ import (
 	"concert-booking/internal/domain/entity"
 	"concert-booking/internal/domain/repository"
 	"concert-booking/internal/domain/service"
+	"concert-booking/internal/domain/ticketevent"
+	"concert-booking/internal/observability/metrics"
 )
 
 var ErrQueueFull = errors.New("queue is full")
 
+// ErrReservationExpired is a queued request whose hold was already taken and
+// has expired since.
+var ErrReservationExpired = errors.New("reservation expired")
+
+// ReadinessGate reports whether reservations for an event may be served.
+type ReadinessGate interface {
+	Ready(eventID string) bool
+}
+
 type ReservationUsecase struct {
 	categories      repository.TicketCategoryRepository
 	reservations    repository.ReservationRepository
 	bookings        repository.BookingRepository
 	stock           service.StockService
-	producer        service.EventProducer
 	now             func() time.Time
 	newID           func() string
 	ttl             time.Duration
@@ -28,9 +38,13 @@ type ReservationUsecase struct {
 	waitingRequests atomic.Int64
 	gate            chan struct{}
 	persistSync     bool
+	readiness       ReadinessGate
+	soldOut         service.SoldOutCache
+	encoding        ticketevent.Encoding
+	writeTimeout    time.Duration
 }
 
-func NewReservationUsecase(categories repository.TicketCategoryRepository, reservations repository.ReservationRepository, bookings repository.BookingRepository, stock service.StockService, producer service.EventProducer, now func() time.Time, newID func() string, ttl time.Duration, queueThreshold, workerPoolSize int, persistSync bool) *ReservationUsecase {
+func NewReservationUsecase(categories repository.TicketCategoryRepository, reservations repository.ReservationRepository, bookings repository.BookingRepository, stock service.StockService, now func() time.Time, newID func() string, ttl time.Duration, queueThreshold, workerPoolSize int, persistSync bool) *ReservationUsecase {
 	if workerPoolSize <= 0 {
 		workerPoolSize = 1
 	}
@@ -39,19 +53,43 @@ func NewReservationUsecase(categories repository.TicketCategoryRepository, reser
 		reservations:   reservations,
 		bookings:       bookings,
 		stock:          stock,
-		producer:       producer,
 		now:            now,
 		newID:          newID,
 		ttl:            ttl,
 		queueThreshold: int64(queueThreshold),
 		gate:           make(chan struct{}, workerPoolSize),
 		persistSync:    persistSync,
+		encoding:       ticketevent.EncodingJSON,
+		writeTimeout:   3 * time.Second,
 	}
 }
 
+func (u *ReservationUsecase) SetReadinessGate(g ReadinessGate) {
+	u.readiness = g
+}
+
+// SetEventEncoding selects the wire format of the events Reserve, Confirm
+// and expiry publish.
+func (u *ReservationUsecase) SetEventEncoding(enc ticketevent.Encoding) {
+	u.encoding = enc
+}
+
+// SetWriteTimeout bounds the writes Confirm finishes after the stock store
+// confirmed the hold, which no longer follow the request's cancellation.
+func (u *ReservationUsecase) SetWriteTimeout(d time.Duration) {
+	u.writeTimeout = d
+}
+
+// SetSoldOutCache lets Reserve reject requests for empty categories before
+// taking a gate slot.
+func (u *ReservationUsecase) SetSoldOutCache(c service.SoldOutCache) {
+	u.soldOut = c
+}
+
 func (u *ReservationUsecase) Reserve(ctx context.Context, userID, eventID, category string, qty int) (entity.Reservation, error) {
-	if strings.TrimSpace(userID) == "" || strings.TrimSpace(eventID) == "" || strings.TrimSpace(category) == "" || qty <= 0 {
-		return entity.Reservation{}, ErrInvalidInput
+	category, err := u.admit(userID, eventID, category, qty)
+	if err != nil {
+		return entity.Reservation{}, err
 	}
 	if u.waitingRequests.Add(1) > u.queueThreshold {
 		u.waitingRequests.Add(-1)
@@ -65,11 +103,64 @@ func (u *ReservationUsecase) Reserve(ctx context.Context, userID, eventID, categ
 	case <-ctx.Done():
 		return entity.Reservation{}, ctx.Err()
 	}
+	return u.reserve(ctx, u.newID(), userID, eventID, category, qty)
+}
 
+// admit runs the checks that need no stock round trip and returns the
+// normalized category.
+func (u *ReservationUsecase) admit(userID, eventID, category string, qty int) (string, error) {
+	if strings.TrimSpace(userID) == "" || strings.TrimSpace(eventID) == "" || strings.TrimSpace(category) == "" || qty <= 0 {
+		return "", ErrInvalidInput
+	}
+	if u.readiness != nil && !u.readiness.Ready(eventID) {
+		return "", ErrEventNotReady
+	}
 	category = strings.ToUpper(strings.TrimSpace(category))
+	if u.soldOut != nil && u.soldOut.SoldOut(eventID, category) {
+		metrics.IncSoldOutShortCircuit()
+		return "", service.ErrOutOfStock
+	}
+	return category, nil
+}
 
+// allocate holds stock for a queued request. It skips the gate because the
+// allocator is the only writer for the request's partition. The request ID
+// doubles as the reservation ID, so a redelivered request finds what the
+// first delivery took instead of taking a second hold: the reservation row,
+// or, while the row is still being projected, the stock store's record of
+// the hold, which is written in the same step as the hold and outlives it.
+// A hold found expired returns ErrReservationExpired.
+func (u *ReservationUsecase) allocate(ctx context.Context, req entity.ReservationRequest) (entity.Reservation, error) {
+	res, err := u.reservations.FindByID(ctx, req.ID)
+	if err == nil {
+		if res.Status == entity.ReservationStatusExpired {
+			return res, ErrReservationExpired
+		}
+		return res, nil
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return entity.Reservation{}, err
+	}
+	meta, err := u.stock.GetReservation(ctx, req.ID)
+	if err == nil {
+		return entity.Reservation{ID: meta.ReservationID, UserID: meta.UserID, EventID: meta.EventID, Category: meta.Category, Qty: meta.Qty, Status: meta.Status, ExpiredAt: meta.ExpiredAt}, nil
+	}
+	if errors.Is(err, service.ErrReservationReleased) {
+		return entity.Reservation{ID: req.ID}, ErrReservationExpired
+	}
+	if !errors.Is(err, service.ErrReservationNotFound) {
+		return entity.Reservation{}, err
+	}
+	category, err := u.admit(req.UserID, req.EventID, req.Category, req.Qty)
+	if err != nil {
+		return entity.Reservation{}, err
+	}
+	return u.reserve(ctx, req.ID, req.UserID, req.EventID, category, req.Qty)
+}
+
+func (u *ReservationUsecase) reserve(ctx context.Context, id, userID, eventID, category string, qty int) (entity.Reservation, error) {
 	res := entity.Reservation{
-		ID:        u.newID(),
+		ID:        id,
 		UserID:    userID,
 		EventID:   eventID,
 		Category:  category,
@@ -88,7 +179,11 @@ func (u *ReservationUsecase) Reserve(ctx context.Context, userID, eventID, categ
 		Status:        entity.ReservationStatusReserved,
 		ExpiredAt:     res.ExpiredAt,
 	}
-	if err := u.stock.Reserve(ctx, meta, u.ttl); err != nil {
+	reserved, err := u.newOutboxEvent(ctx, ticketevent.TopicReserved, res.ID, eventID, ticketevent.ReservedFrom(res))
+	if err != nil {
+		return entity.Reservation{}, err
+	}
+	if err := u.stock.Reserve(ctx, meta, u.ttl, reserved); err != nil {
 		if errors.Is(err, service.ErrOutOfStock) {
 			return entity.Reservation{}, service.ErrOutOfStock
 		}
@@ -96,17 +191,10 @@ func (u *ReservationUsecase) Reserve(ctx context.Context, userID, eventID, categ
 	}
 
 	if u.persistSync {
-		if err := u.reservations.Upsert(res); err != nil {
+		if err := u.reservations.Upsert(ctx, res); err != nil {
 			return entity.Reservation{}, err
 		}
 	}
-
-	payload, _ := json.Marshal(res)
-	pubCtx, cancel := context.WithTimeout(context.Background(), 80*time.Millisecond)
-	defer cancel()
-	if err := u.producer.Publish(pubCtx, "ticket.reserved", eventID, payload); err != nil {
-		return entity.Reservation{}, err
-	}
 	return res, nil
 }
 
@@ -130,66 +218,139 @@ func (u *ReservationUsecase) Confirm(ctx context.Context, reservationID string,
 	resMeta, err := u.stock.GetReservation(ctx, reservationID)
 	if err != nil {
 		if errors.Is(err, service.ErrReservationNotFound) {
-			if existing, ferr := u.bookings.FindByReservationID(reservationID); ferr == nil {
+			existing, ferr := u.bookings.FindByReservationID(ctx, reservationID)
+			if ferr == nil {
 				return existing, nil
 			}
+			if !errors.Is(ferr, repository.ErrNotFound) {
+				return entity.Booking{}, ferr
+			}
 			return entity.Booking{}, ErrNotFound
 		}
 		return entity.Booking{}, err
 	}
 
 	if !paymentOK {
-		_, _ = u.stock.ReleaseReservation(ctx, reservationID)
-		_ = u.reservations.UpdateStatus(reservationID, entity.ReservationStatusExpired)
-		payload, _ := json.Marshal(map[string]string{"reservation_id": reservationID, "status": "expired"})
-		_ = u.producer.Publish(ctx, "ticket.expired", resMeta.EventID, payload)
-		return entity.Booking{}, errors.New("payment failed")
-	}
-
-	if err := u.stock.ConfirmReservation(ctx, reservationID); err != nil {
-		if errors.Is(err, service.ErrReservationFinalized) {
-			if existing, ferr := u.bookings.FindByReservationID(reservationID); ferr == nil {
-				return existing, nil
+		expired, err := u.expiredEvent(ctx, resMeta)
+		if err != nil {
+			return entity.Booking{}, err
+		}
+		// Only a release that flipped the hold moves the row; a hold confirmed
+		// or released in the meantime keeps its status.
+		if _, err := u.stock.ReleaseReservation(ctx, reservationID, expired); err != nil {
+			if errors.Is(err, service.ErrReservationNotFound) {
+				return entity.Booking{}, ErrNotFound
 			}
+			return entity.Booking{}, err
 		}
-		return entity.Booking{}, err
+		u.expired(ctx, resMeta)
+		return entity.Booking{}, errors.New("payment failed")
 	}
-	_ = u.reservations.UpdateStatus(reservationID, entity.ReservationStatusConfirmed)
 
+	// The confirmed event carries the booking and is recorded by the stock
+	// store together with the confirm, so the booking row below reuses its
+	// ID. A retry after that step finds the reservation finalized.
 	booking := entity.Booking{
 		ID:            u.newID(),
 		ReservationID: reservationID,
 		PaymentStatus: "paid",
 		CreatedAt:     u.now(),
 	}
-	created, err := u.bookings.CreateIfNotExists(booking)
+	confirmed, err := u.newOutboxEvent(ctx, ticketevent.TopicConfirmed, reservationID, resMeta.EventID, ticketevent.ConfirmedFrom(booking, resMeta.EventID))
 	if err != nil {
 		return entity.Booking{}, err
 	}
-	if !created {
-		existing, err := u.bookings.FindByReservationID(reservationID)
-		if err != nil {
-			return entity.Booking{}, err
+	if err := u.stock.ConfirmReservation(ctx, reservationID, confirmed); err != nil {
+		if errors.Is(err, service.ErrReservationFinalized) {
+			if existing, ferr := u.bookings.FindByReservationID(ctx, reservationID); ferr == nil {
+				return existing, nil
+			}
 		}
-		return existing, nil
+		return entity.Booking{}, err
 	}
+	// The hold is confirmed from here on, so the rest runs to completion even
+	// if the client disconnects; otherwise the hold could be left without a
+	// booking row.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), u.writeTimeout)
+	defer cancel()
+	_, _ = u.reservations.Transition(ctx, reservationID, entity.ReservationStatusConfirmed)
 
-	payload, _ := json.Marshal(booking)
-	if err := u.producer.Publish(ctx, "ticket.confirmed", resMeta.EventID, payload); err != nil {
+	created, err := u.bookings.CreateIfNotExists(ctx, booking)
+	if err != nil {
 		return entity.Booking{}, err
 	}
+	if !created {
+		existing, err := u.bookings.FindByReservationID(ctx, reservationID)
+		if err != nil {
+			return entity.Booking{}, err
+		}
+		booking = existing
+	}
 	return booking, nil
 }
 
 func (u *ReservationUsecase) ReleaseExpired(ctx context.Context, now time.Time, batch int) error {
-	items, err := u.stock.ReleaseExpired(ctx, now, batch)
-	if err != nil {
-		return err
+	_, err := u.releaseExpired(ctx, now, batch)
+	return err
+}
+
+// ReleaseAllExpired sweeps in batches until one comes back short, e.g. to
+// release everything that expired while the process was down.
+func (u *ReservationUsecase) ReleaseAllExpired(ctx context.Context, now time.Time, batch int) error {
+	for {
+		n, err := u.releaseExpired(ctx, now, batch)
+		if err != nil || n < batch {
+			return err
+		}
 	}
+}
+
+func (u *ReservationUsecase) releaseExpired(ctx context.Context, now time.Time, batch int) (int, error) {
+	started := time.Now()
+	items, err := u.stock.ReleaseExpired(ctx, now, batch, func(item service.ReservationMeta) (service.OutboxEvent, error) {
+		return u.expiredEvent(ctx, item)
+	})
+	metrics.ObserveReaperRun(len(items), time.Since(started))
+	// A failed sweep may still have released some holds before the error.
 	for _, item := range items {
-		_ = u.reservations.UpdateStatus(item.ReservationID, entity.ReservationStatusExpired)
-		payload, _ := json.Marshal(map[string]string{"reservation_id": item.ReservationID, "status": "expired"})
-		_ = u.producer.Publish(ctx, "ticket.expired", item.EventID, payload)
+		u.expired(ctx, item)
+	}
+	return len(items), err
+}
+
+// WatchExpired records holds the stock store releases as their TTL runs out.
+// The reaper keeps running as a safety net for missed notifications.
+func (u *ReservationUsecase) WatchExpired(ctx context.Context, watcher service.ExpiryWatcher) {
+	event := func(item service.ReservationMeta) (service.OutboxEvent, error) { return u.expiredEvent(ctx, item) }
+	for {
+		if err := watcher.WatchExpired(ctx, event, func(item service.ReservationMeta) { u.expired(ctx, item) }); err != nil {
+			log.Printf("expiry watcher stopped: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// expired moves the reservation row of a released hold. Its expired event
+// was already recorded by the stock store with the release.
+func (u *ReservationUsecase) expired(ctx context.Context, item service.ReservationMeta) {
+	_, _ = u.reservations.Transition(ctx, item.ReservationID, entity.ReservationStatusExpired)
+}
+
+func (u *ReservationUsecase) expiredEvent(ctx context.Context, item service.ReservationMeta) (service.OutboxEvent, error) {
+	return u.newOutboxEvent(ctx, ticketevent.TopicExpired, item.ReservationID, item.EventID, ticketevent.Expired{ReservationID: item.ReservationID, EventID: item.EventID, Status: entity.ReservationStatusExpired})
+}
+
+// newOutboxEvent wraps data in the event envelope. The ID is derived from
+// the topic and reservation, so a retried call produces the same event.
+func (u *ReservationUsecase) newOutboxEvent(ctx context.Context, topic, reservationID, key string, data any) (service.OutboxEvent, error) {
+	id := topic + ":" + reservationID
+	payload, err := u.encoding.New(ctx, id, topic, reservationID, data, u.now())
+	if err != nil {
+		return service.OutboxEvent{}, err
 	}
-	return nil
+	return service.OutboxEvent{ID: id, Topic: topic, Key: key, Payload: payload}, nil
 }
@@ -3,6 +3,7 @@ package usecase
 import (
 	"context"
 	"errors"
+	"strconv"
 	"testing"
 	"time"
 
@@ -16,14 +17,13 @@ func TestReserveAndConfirm(t *testing.T) {
 	stock := memory.NewStockService()
 	reservations := memory.NewReservationRepository()
 	bookings := memory.NewBookingRepository()
-	producer := memory.NewEventProducer()
 
 	eventID := "event-1"
-	_ = categories.Create(entity.TicketCategory{ID: "cat-1", EventID: eventID, Name: "VIP", TotalStock: 3, Price: 1000})
+	_ = categories.Create(context.Background(), entity.TicketCategory{ID: "cat-1", EventID: eventID, Name: "VIP", TotalStock: 3, Price: 1000})
 	_ = stock.InitStock(context.Background(), eventID, "VIP", 3)
 
 	idSeq := 0
-	u := NewReservationUsecase(categories, reservations, bookings, stock, producer, time.Now, func() string {
+	u := NewReservationUsecase(categories, reservations, bookings, stock, time.Now, func() string {
 		idSeq++
 		if idSeq == 1 {
 			return "res-1"
@@ -53,15 +53,135 @@ func TestReserveOutOfStock(t *testing.T) {
 	stock := memory.NewStockService()
 	reservations := memory.NewReservationRepository()
 	bookings := memory.NewBookingRepository()
-	producer := memory.NewEventProducer()
 
 	eventID := "event-1"
-	_ = categories.Create(entity.TicketCategory{ID: "cat-1", EventID: eventID, Name: "REGULAR", TotalStock: 1, Price: 1000})
+	_ = categories.Create(context.Background(), entity.TicketCategory{ID: "cat-1", EventID: eventID, Name: "REGULAR", TotalStock: 1, Price: 1000})
 	_ = stock.InitStock(context.Background(), eventID, "REGULAR", 1)
 
-	u := NewReservationUsecase(categories, reservations, bookings, stock, producer, time.Now, func() string { return "res-1" }, 5*time.Minute, 100, 10, true)
+	u := NewReservationUsecase(categories, reservations, bookings, stock, time.Now, func() string { return "res-1" }, 5*time.Minute, 100, 10, true)
 	_, err := u.Reserve(context.Background(), "user-1", eventID, "REGULAR", 2)
 	if !errors.Is(err, service.ErrOutOfStock) {
 		t.Fatalf("expected out of stock, got %v", err)
 	}
 }
+
+type soldOutStub map[string]bool
+
+func (s soldOutStub) SoldOut(eventID, category string) bool { return s[eventID+"/"+category] }
+
+func TestReserveShortCircuitsSoldOut(t *testing.T) {
+	categories := memory.NewTicketCategoryRepository()
+	stock := memory.NewStockService()
+	_ = stock.InitStock(context.Background(), "event-1", "VIP", 5)
+
+	u := NewReservationUsecase(categories, memory.NewReservationRepository(), memory.NewBookingRepository(), stock, time.Now, func() string { return "res-1" }, 5*time.Minute, 100, 10, true)
+	u.SetSoldOutCache(soldOutStub{"event-1/VIP": true})
+
+	if _, err := u.Reserve(context.Background(), "user-1", "event-1", "vip", 1); !errors.Is(err, service.ErrOutOfStock) {
+		t.Fatalf("expected out of stock from the cache, got %v", err)
+	}
+	if stocks, _ := stock.GetStocks(context.Background(), "event-1", []string{"VIP"}); stocks["VIP"] != 5 {
+		t.Fatalf("expected stock untouched, got %d", stocks["VIP"])
+	}
+}
+
+// ctxBookings fails like a database would once the request context ends.
+type ctxBookings struct{ *memory.BookingRepository }
+
+func (b ctxBookings) CreateIfNotExists(ctx context.Context, booking entity.Booking) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return b.BookingRepository.CreateIfNotExists(ctx, booking)
+}
+
+func (b ctxBookings) FindByReservationID(ctx context.Context, reservationID string) (entity.Booking, error) {
+	if err := ctx.Err(); err != nil {
+		return entity.Booking{}, err
+	}
+	return b.BookingRepository.FindByReservationID(ctx, reservationID)
+}
+
+// cancelOnConfirm ends the request context right after the hold is confirmed.
+type cancelOnConfirm struct {
+	*memory.StockService
+	cancel context.CancelFunc
+}
+
+func (s cancelOnConfirm) ConfirmReservation(ctx context.Context, reservationID string, events ...service.OutboxEvent) error {
+	defer s.cancel()
+	return s.StockService.ConfirmReservation(ctx, reservationID, events...)
+}
+
+func TestConfirmOutlivesClientCancel(t *testing.T) {
+	stock := memory.NewStockService()
+	_ = stock.InitStock(context.Background(), "event-1", "VIP", 5)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	bookings := ctxBookings{memory.NewBookingRepository()}
+
+	ids := []string{"res-1", "book-1"}
+	u := NewReservationUsecase(memory.NewTicketCategoryRepository(), memory.NewReservationRepository(), bookings, cancelOnConfirm{stock, cancel}, time.Now, func() string {
+		id := ids[0]
+		ids = ids[1:]
+		return id
+	}, 5*time.Minute, 100, 10, true)
+	if _, err := u.Reserve(ctx, "user-1", "event-1", "VIP", 1); err != nil {
+		t.Fatalf("reserve: %v", err)
+	}
+	if _, err := u.Confirm(ctx, "res-1", true); err != nil {
+		t.Fatalf("confirm: %v", err)
+	}
+	if _, err := bookings.FindByReservationID(context.Background(), "res-1"); err != nil {
+		t.Fatalf("expected booking row after cancel, got %v", err)
+	}
+
+	// A lookup cut short by the client is not reported as a missing booking.
+	if _, err := u.Confirm(ctx, "res-2", true); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context canceled, got %v", err)
+	}
+}
+
+// confirmedMeanwhile reports the hold as confirmed by a concurrent request.
+type confirmedMeanwhile struct{ *memory.StockService }
+
+func (s confirmedMeanwhile) ReleaseReservation(context.Context, string, ...service.OutboxEvent) (service.ReservationMeta, error) {
+	return service.ReservationMeta{}, service.ErrReservationFinalized
+}
+
+func TestFailedPaymentKeepsFinalizedHold(t *testing.T) {
+	stock := memory.NewStockService()
+	_ = stock.InitStock(context.Background(), "event-1", "VIP", 5)
+	reservations := memory.NewReservationRepository()
+	u := NewReservationUsecase(memory.NewTicketCategoryRepository(), reservations, memory.NewBookingRepository(), confirmedMeanwhile{stock}, time.Now, func() string { return "res-1" }, 5*time.Minute, 100, 10, true)
+	if _, err := u.Reserve(context.Background(), "user-1", "event-1", "VIP", 1); err != nil {
+		t.Fatalf("reserve: %v", err)
+	}
+	if _, err := u.Confirm(context.Background(), "res-1", false); !errors.Is(err, service.ErrReservationFinalized) {
+		t.Fatalf("expected finalized, got %v", err)
+	}
+	if res, _ := reservations.FindByID(context.Background(), "res-1"); res.Status != entity.ReservationStatusReserved {
+		t.Fatalf("expected row left alone, got %s", res.Status)
+	}
+}
+
+func TestReleaseAllExpiredSweepsInBatches(t *testing.T) {
+	stock := memory.NewStockService()
+	_ = stock.InitStock(context.Background(), "event-1", "VIP", 5)
+	seq := 0
+	u := NewReservationUsecase(memory.NewTicketCategoryRepository(), memory.NewReservationRepository(), memory.NewBookingRepository(), stock, time.Now, func() string {
+		seq++
+		return "res-" + strconv.Itoa(seq)
+	}, 5*time.Minute, 100, 10, true)
+	for i := 0; i < 5; i++ {
+		if _, err := u.Reserve(context.Background(), "user-1", "event-1", "VIP", 1); err != nil {
+			t.Fatalf("reserve: %v", err)
+		}
+	}
+	if err := u.ReleaseAllExpired(context.Background(), time.Now().Add(time.Hour), 2); err != nil {
+		t.Fatalf("release all: %v", err)
+	}
+	if stocks, _ := stock.GetStocks(context.Background(), "event-1", []string{"VIP"}); stocks["VIP"] != 5 {
+		t.Fatalf("expected every hold released, got stock %d", stocks["VIP"])
+	}
+}
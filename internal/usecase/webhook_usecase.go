@@ -0,0 +1,360 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"concert-booking/internal/domain/entity"
+	"concert-booking/internal/domain/repository"
+	"concert-booking/internal/domain/service"
+	"concert-booking/internal/domain/ticketevent"
+	"concert-booking/internal/observability/metrics"
+)
+
+// ErrWebhookDisabled rejects redelivery to a webhook that is switched off.
+var ErrWebhookDisabled = errors.New("webhook is disabled")
+
+// WebhookEventTypes are the event types a webhook can subscribe to.
+var WebhookEventTypes = []string{ticketevent.TypeReserved, ticketevent.TypeConfirmed, ticketevent.TypeExpired}
+
+type WebhookOptions struct {
+	// MaxAttempts bounds the attempts of one delivery before it is failed.
+	MaxAttempts int
+	// Backoff is the delay after the first failed attempt, doubled per
+	// attempt up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// DisableAfter consecutive failed attempts switch the webhook off.
+	DisableAfter int
+	// Lease hides a claimed delivery from other dispatchers; it must be
+	// longer than a send can take.
+	Lease time.Duration
+}
+
+// WebhookUsecase manages partner webhooks, queues ticket events for them and
+// delivers the queue.
+type WebhookUsecase struct {
+	webhooks   repository.WebhookRepository
+	deliveries repository.WebhookDeliveryRepository
+	sender     service.WebhookSender
+	now        func() time.Time
+	newID      func() string
+	opts       WebhookOptions
+}
+
+func NewWebhookUsecase(webhooks repository.WebhookRepository, deliveries repository.WebhookDeliveryRepository, sender service.WebhookSender, now func() time.Time, newID func() string, opts WebhookOptions) *WebhookUsecase {
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 1
+	}
+	if opts.DisableAfter <= 0 {
+		opts.DisableAfter = 1
+	}
+	if opts.Lease <= 0 {
+		opts.Lease = time.Minute
+	}
+	return &WebhookUsecase{webhooks: webhooks, deliveries: deliveries, sender: sender, now: now, newID: newID, opts: opts}
+}
+
+// WebhookPatch lists the fields Update changes; nil fields are kept.
+type WebhookPatch struct {
+	URL        *string
+	EventTypes *[]string
+	Active     *bool
+}
+
+// Create registers an endpoint. A secret is generated when none is given;
+// it is only ever returned here.
+func (u *WebhookUsecase) Create(ctx context.Context, endpoint string, eventTypes []string, secret string) (entity.Webhook, error) {
+	if err := validateWebhook(endpoint, eventTypes); err != nil {
+		return entity.Webhook{}, err
+	}
+	if secret == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return entity.Webhook{}, err
+		}
+		secret = hex.EncodeToString(b)
+	}
+	now := u.now()
+	w := entity.Webhook{ID: u.newID(), URL: endpoint, Secret: secret, EventTypes: eventTypes, Active: true, CreatedAt: now, UpdatedAt: now}
+	if err := u.webhooks.Create(ctx, w); err != nil {
+		return entity.Webhook{}, err
+	}
+	return w, nil
+}
+
+func (u *WebhookUsecase) List(ctx context.Context) ([]entity.Webhook, error) {
+	return u.webhooks.List(ctx)
+}
+
+func (u *WebhookUsecase) Get(ctx context.Context, id string) (entity.Webhook, error) {
+	w, err := u.webhooks.FindByID(ctx, id)
+	if err != nil {
+		return entity.Webhook{}, notFoundUnlessCanceled(err)
+	}
+	return w, nil
+}
+
+// Update changes the URL, filter or active flag. Enabling a webhook that was
+// switched off clears its failure streak.
+func (u *WebhookUsecase) Update(ctx context.Context, id string, patch WebhookPatch) (entity.Webhook, error) {
+	w, err := u.Get(ctx, id)
+	if err != nil {
+		return entity.Webhook{}, err
+	}
+	if patch.URL != nil {
+		w.URL = *patch.URL
+	}
+	if patch.EventTypes != nil {
+		w.EventTypes = *patch.EventTypes
+	}
+	if patch.Active != nil {
+		w.Active = *patch.Active
+	}
+	if err := validateWebhook(w.URL, w.EventTypes); err != nil {
+		return entity.Webhook{}, err
+	}
+	w.UpdatedAt = u.now()
+	if err := u.webhooks.Update(ctx, w); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return entity.Webhook{}, ErrNotFound
+		}
+		return entity.Webhook{}, err
+	}
+	return u.Get(ctx, id)
+}
+
+func (u *WebhookUsecase) Delete(ctx context.Context, id string) error {
+	if err := u.webhooks.Delete(ctx, id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Deliveries returns the delivery log of a webhook, newest first.
+func (u *WebhookUsecase) Deliveries(ctx context.Context, id string, limit int) ([]entity.WebhookDelivery, error) {
+	if _, err := u.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	return u.deliveries.ListByWebhook(ctx, id, limit)
+}
+
+// Redeliver queues a delivery again with a fresh attempt budget, whatever
+// its current status.
+func (u *WebhookUsecase) Redeliver(ctx context.Context, webhookID, deliveryID string) (entity.WebhookDelivery, error) {
+	w, err := u.Get(ctx, webhookID)
+	if err != nil {
+		return entity.WebhookDelivery{}, err
+	}
+	d, err := u.deliveries.FindByID(ctx, deliveryID)
+	if err != nil || d.WebhookID != webhookID {
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return entity.WebhookDelivery{}, notFoundUnlessCanceled(err)
+		}
+		return entity.WebhookDelivery{}, ErrNotFound
+	}
+	if !w.Active {
+		return entity.WebhookDelivery{}, ErrWebhookDisabled
+	}
+	now := u.now()
+	d.Status, d.Attempts, d.LastError, d.ResponseStatus = entity.DeliveryPending, 0, "", 0
+	d.NextAttemptAt, d.DeliveredAt, d.UpdatedAt = now, nil, now
+	if err := u.deliveries.Update(ctx, d); err != nil {
+		return entity.WebhookDelivery{}, err
+	}
+	return d, nil
+}
+
+func validateWebhook(endpoint string, eventTypes []string) error {
+	parsed, err := url.Parse(endpoint)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http(s) URL", ErrInvalidInput)
+	}
+	for _, t := range eventTypes {
+		if !slices.Contains(WebhookEventTypes, t) {
+			return fmt.Errorf("%w: unknown event type %q", ErrInvalidInput, t)
+		}
+	}
+	return nil
+}
+
+// Handlers returns the fan-out handlers keyed by topic. Each queues the
+// event for every active webhook subscribed to its type; a redelivered
+// message is queued only once per webhook.
+func (u *WebhookUsecase) Handlers() map[string]EventHandler {
+	out := map[string]EventHandler{}
+	for _, topic := range []string{ticketevent.TopicReserved, ticketevent.TopicConfirmed, ticketevent.TopicExpired} {
+		out[topic] = func(ctx context.Context, payload []byte) error { return u.enqueue(ctx, topic, payload) }
+	}
+	return out
+}
+
+func (u *WebhookUsecase) enqueue(ctx context.Context, topic string, payload []byte) error {
+	env, err := ticketevent.Decode(topic, payload)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+	if env.ID == "" {
+		// Pre-envelope messages have no ID to deduplicate on.
+		return nil
+	}
+	// Partners always get the JSON envelope, whatever the topic encoding.
+	env.DataContentType = ticketevent.ContentTypeJSON
+	body, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	webhooks, err := u.webhooks.List(ctx)
+	if err != nil {
+		return err
+	}
+	now := u.now()
+	for _, w := range webhooks {
+		if !w.Accepts(env.Type) {
+			continue
+		}
+		_, err := u.deliveries.Create(ctx, entity.WebhookDelivery{
+			ID:            u.newID(),
+			WebhookID:     w.ID,
+			EventID:       env.ID,
+			EventType:     env.Type,
+			Payload:       body,
+			Status:        entity.DeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StartDispatcher delivers due webhooks every interval until ctx ends.
+// Several dispatchers may run at once; claims keep them from sending the
+// same delivery concurrently.
+func (u *WebhookUsecase) StartDispatcher(ctx context.Context, interval time.Duration, batch int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for {
+				n, err := u.DeliverDue(ctx, batch)
+				if err != nil {
+					log.Printf("webhook dispatcher: %v", err)
+				}
+				if err != nil || n < batch {
+					break
+				}
+			}
+		}
+	}
+}
+
+// DeliverDue sends one batch of due deliveries concurrently and returns how
+// many it claimed.
+func (u *WebhookUsecase) DeliverDue(ctx context.Context, batch int) (int, error) {
+	due, err := u.deliveries.ClaimDue(ctx, u.now(), u.opts.Lease, batch)
+	if err != nil {
+		return 0, err
+	}
+	var wg sync.WaitGroup
+	for _, d := range due {
+		wg.Add(1)
+		go func(d entity.WebhookDelivery) {
+			defer wg.Done()
+			if err := u.deliver(ctx, d); err != nil {
+				log.Printf("webhook delivery %s: %v", d.ID, err)
+			}
+		}(d)
+	}
+	wg.Wait()
+	return len(due), nil
+}
+
+func (u *WebhookUsecase) deliver(ctx context.Context, d entity.WebhookDelivery) error {
+	w, err := u.webhooks.FindByID(ctx, d.WebhookID)
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return u.finish(ctx, d, entity.DeliveryFailed, "webhook deleted")
+	case err != nil:
+		return err
+	case !w.Active:
+		return u.finish(ctx, d, entity.DeliveryFailed, "webhook disabled: "+w.DisabledReason)
+	}
+
+	status, sendErr := u.sender.Send(ctx, service.WebhookRequest{URL: w.URL, Secret: w.Secret, DeliveryID: d.ID, EventType: d.EventType, Body: d.Payload})
+	if ctx.Err() != nil {
+		// Shutting down: leave the claim to expire and retry later.
+		return nil
+	}
+	now := u.now()
+	d.Attempts++
+	d.ResponseStatus = status
+	d.UpdatedAt = now
+	if sendErr == nil {
+		d.Status, d.LastError, d.DeliveredAt = entity.DeliverySucceeded, "", &now
+		metrics.IncWebhookDelivery("succeeded")
+	} else {
+		d.LastError = sendErr.Error()
+		if d.Attempts >= u.opts.MaxAttempts {
+			d.Status = entity.DeliveryFailed
+			metrics.IncWebhookDelivery("failed")
+		} else {
+			d.NextAttemptAt = now.Add(u.backoff(d.Attempts))
+			metrics.IncWebhookDelivery("retried")
+		}
+	}
+	if err := u.deliveries.Update(ctx, d); err != nil {
+		return err
+	}
+	reason := ""
+	if sendErr != nil {
+		reason = fmt.Sprintf("%d consecutive failed deliveries, last: %v", u.opts.DisableAfter, sendErr)
+	}
+	updated, err := u.webhooks.RecordAttempt(ctx, w.ID, sendErr == nil, u.opts.DisableAfter, reason, now)
+	if err != nil {
+		return err
+	}
+	if w.Active && !updated.Active {
+		metrics.IncWebhookDisabled()
+		log.Printf("webhook %s disabled: %s", w.ID, updated.DisabledReason)
+	}
+	return nil
+}
+
+func (u *WebhookUsecase) finish(ctx context.Context, d entity.WebhookDelivery, status, reason string) error {
+	d.Status, d.LastError, d.UpdatedAt = status, reason, u.now()
+	metrics.IncWebhookDelivery(status)
+	return u.deliveries.Update(ctx, d)
+}
+
+func (u *WebhookUsecase) backoff(attempts int) time.Duration {
+	d := u.opts.Backoff
+	for i := 1; i < attempts && d < u.opts.MaxBackoff; i++ {
+		d *= 2
+	}
+	if u.opts.MaxBackoff > 0 {
+		d = min(d, u.opts.MaxBackoff)
+	}
+	return d
+}
@@ -0,0 +1,186 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"concert-booking/internal/domain/entity"
+	"concert-booking/internal/domain/service"
+	"concert-booking/internal/domain/ticketevent"
+	"concert-booking/internal/infrastructure/memory"
+)
+
+type stubWebhookSender struct {
+	mu   sync.Mutex
+	fail bool
+	sent []service.WebhookRequest
+}
+
+func (s *stubWebhookSender) Send(_ context.Context, req service.WebhookRequest) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, req)
+	if s.fail {
+		return 503, errors.New("unexpected status 503")
+	}
+	return 200, nil
+}
+
+// seqIDs returns an ID generator yielding "<prefix>-1", "<prefix>-2", ...
+func seqIDs(prefix string) func() string {
+	n := 0
+	return func() string {
+		n++
+		return fmt.Sprintf("%s-%d", prefix, n)
+	}
+}
+
+func reservedEvent(t *testing.T, reservationID string) []byte {
+	t.Helper()
+	return replayEvent(t, ticketevent.TopicReserved, reservationID, ticketevent.Reserved{ReservationID: reservationID, EventID: "event-1", Category: "VIP", Qty: 1})
+}
+
+func TestWebhookFanOutFiltersAndDeduplicates(t *testing.T) {
+	ctx := context.Background()
+	sender := &stubWebhookSender{}
+	u := NewWebhookUsecase(memory.NewWebhookRepository(), memory.NewWebhookDeliveryRepository(), sender, time.Now, seqIDs("id"), WebhookOptions{MaxAttempts: 3, DisableAfter: 5})
+	all, _ := u.Create(ctx, "https://crm.example/hook", nil, "")
+	confirmedOnly, _ := u.Create(ctx, "https://acct.example/hook", []string{ticketevent.TypeConfirmed}, "s3cret")
+	off, _ := u.Create(ctx, "https://old.example/hook", nil, "")
+	inactive := false
+	if _, err := u.Update(ctx, off.ID, WebhookPatch{Active: &inactive}); err != nil {
+		t.Fatalf("disable: %v", err)
+	}
+	if all.Secret == "" || confirmedOnly.Secret != "s3cret" {
+		t.Fatalf("unexpected secrets %q %q", all.Secret, confirmedOnly.Secret)
+	}
+
+	handler := u.Handlers()[ticketevent.TopicReserved]
+	for range 2 {
+		if err := handler(ctx, reservedEvent(t, "res-1")); err != nil {
+			t.Fatalf("handle: %v", err)
+		}
+	}
+
+	for _, hook := range []entity.Webhook{all, confirmedOnly, off} {
+		deliveries, _ := u.Deliveries(ctx, hook.ID, 0)
+		want := 0
+		if hook.ID == all.ID {
+			want = 1
+		}
+		if len(deliveries) != want {
+			t.Fatalf("webhook %s: expected %d deliveries, got %+v", hook.URL, want, deliveries)
+		}
+	}
+
+	if n, err := u.DeliverDue(ctx, 10); err != nil || n != 1 {
+		t.Fatalf("deliver: n=%d err=%v", n, err)
+	}
+	if len(sender.sent) != 1 || sender.sent[0].URL != all.URL || sender.sent[0].EventType != ticketevent.TypeReserved {
+		t.Fatalf("unexpected sends %+v", sender.sent)
+	}
+	deliveries, _ := u.Deliveries(ctx, all.ID, 0)
+	if deliveries[0].Status != entity.DeliverySucceeded || deliveries[0].DeliveredAt == nil {
+		t.Fatalf("expected delivered, got %+v", deliveries[0])
+	}
+}
+
+func TestWebhookCreateRejectsInvalidInput(t *testing.T) {
+	u := NewWebhookUsecase(memory.NewWebhookRepository(), memory.NewWebhookDeliveryRepository(), &stubWebhookSender{}, time.Now, seqIDs("id"), WebhookOptions{})
+	if _, err := u.Create(context.Background(), "ftp://crm.example", nil, ""); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected invalid url, got %v", err)
+	}
+	if _, err := u.Create(context.Background(), "https://crm.example", []string{"concert.ticket.sold"}, ""); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("expected unknown type, got %v", err)
+	}
+}
+
+func TestWebhookRetriesWithBackoffThenFails(t *testing.T) {
+	ctx := context.Background()
+	sender := &stubWebhookSender{}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	u := NewWebhookUsecase(memory.NewWebhookRepository(), memory.NewWebhookDeliveryRepository(), sender, func() time.Time { return now }, seqIDs("id"), WebhookOptions{MaxAttempts: 3, Backoff: time.Second, MaxBackoff: 90 * time.Second, DisableAfter: 10})
+	hook, _ := u.Create(ctx, "https://crm.example/hook", nil, "")
+	_ = u.Handlers()[ticketevent.TopicReserved](ctx, reservedEvent(t, "res-1"))
+	sender.fail = true
+
+	start := now
+	if n, _ := u.DeliverDue(ctx, 10); n != 1 {
+		t.Fatalf("expected first attempt, got %d", n)
+	}
+	d, _ := u.Deliveries(ctx, hook.ID, 1)
+	if d[0].Status != entity.DeliveryPending || d[0].Attempts != 1 || !d[0].NextAttemptAt.Equal(start.Add(time.Second)) {
+		t.Fatalf("expected retry in 1s, got %+v", d[0])
+	}
+	if n, _ := u.DeliverDue(ctx, 10); n != 0 {
+		t.Fatal("delivery must wait for its backoff")
+	}
+
+	now = d[0].NextAttemptAt
+	_, _ = u.DeliverDue(ctx, 10)
+	d, _ = u.Deliveries(ctx, hook.ID, 1)
+	if d[0].Attempts != 2 || !d[0].NextAttemptAt.Equal(now.Add(2*time.Second)) {
+		t.Fatalf("expected backoff to double, got %+v", d[0])
+	}
+
+	now = d[0].NextAttemptAt
+	_, _ = u.DeliverDue(ctx, 10)
+	d, _ = u.Deliveries(ctx, hook.ID, 1)
+	if d[0].Status != entity.DeliveryFailed || d[0].Attempts != 3 || d[0].ResponseStatus != 503 || d[0].LastError == "" {
+		t.Fatalf("expected failed after max attempts, got %+v", d[0])
+	}
+	w, _ := u.Get(ctx, hook.ID)
+	if !w.Active || w.ConsecutiveFailures != 3 {
+		t.Fatalf("webhook must stay active below the disable threshold, got %+v", w)
+	}
+}
+
+func TestWebhookAutoDisableAndRedeliver(t *testing.T) {
+	ctx := context.Background()
+	sender := &stubWebhookSender{}
+	u := NewWebhookUsecase(memory.NewWebhookRepository(), memory.NewWebhookDeliveryRepository(), sender, time.Now, seqIDs("id"), WebhookOptions{MaxAttempts: 1, Backoff: time.Second, DisableAfter: 2})
+	hook, _ := u.Create(ctx, "https://crm.example/hook", nil, "")
+	handler := u.Handlers()[ticketevent.TopicReserved]
+	_ = handler(ctx, reservedEvent(t, "res-1"))
+	_ = handler(ctx, reservedEvent(t, "res-2"))
+	sender.fail = true
+	_, _ = u.DeliverDue(ctx, 10)
+
+	w, _ := u.Get(ctx, hook.ID)
+	if w.Active || w.DisabledReason == "" {
+		t.Fatalf("expected webhook disabled, got %+v", w)
+	}
+	_ = handler(ctx, reservedEvent(t, "res-3"))
+	deliveries, _ := u.Deliveries(ctx, hook.ID, 0)
+	if len(deliveries) != 2 {
+		t.Fatalf("disabled webhook must not queue new events, got %d deliveries", len(deliveries))
+	}
+	if _, err := u.Redeliver(ctx, hook.ID, deliveries[0].ID); !errors.Is(err, ErrWebhookDisabled) {
+		t.Fatalf("expected ErrWebhookDisabled, got %v", err)
+	}
+	if _, err := u.Redeliver(ctx, hook.ID, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	active := true
+	w, err := u.Update(ctx, hook.ID, WebhookPatch{Active: &active})
+	if err != nil || !w.Active || w.ConsecutiveFailures != 0 || w.DisabledReason != "" {
+		t.Fatalf("expected re-enabled webhook with a clean streak, got %+v err=%v", w, err)
+	}
+	sender.fail = false
+	d, err := u.Redeliver(ctx, hook.ID, deliveries[0].ID)
+	if err != nil || d.Status != entity.DeliveryPending || d.Attempts != 0 {
+		t.Fatalf("redeliver: %+v err=%v", d, err)
+	}
+	if n, _ := u.DeliverDue(ctx, 10); n != 1 {
+		t.Fatalf("expected the redelivery to be sent, got %d", n)
+	}
+	d, _ = u.deliveries.FindByID(ctx, d.ID)
+	if d.Status != entity.DeliverySucceeded || d.Attempts != 1 {
+		t.Fatalf("expected redelivery to succeed, got %+v", d)
+	}
+}
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS bookings;
+DROP TABLE IF EXISTS reservations;
+DROP TABLE IF EXISTS ticket_categories;
+DROP TABLE IF EXISTS events;
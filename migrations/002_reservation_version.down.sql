@@ -0,0 +1,2 @@
+ALTER TABLE reservations DROP CONSTRAINT IF EXISTS reservations_status_check;
+ALTER TABLE reservations DROP COLUMN IF EXISTS version;
@@ -0,0 +1,10 @@
+ALTER TABLE reservations ADD COLUMN IF NOT EXISTS version INT NOT NULL DEFAULT 1;
+
+-- Placeholder rows written by the old UpdateStatus before the reservation was
+-- known. Only rows that never moved past reserved carry nothing worth keeping;
+-- the others hold a real confirmation or expiry and are completed by the
+-- reservation's ticket.reserved when it is redelivered or replayed.
+DELETE FROM reservations WHERE user_id = '' AND event_id = '' AND qty = 0 AND status = 'reserved';
+
+ALTER TABLE reservations ADD CONSTRAINT reservations_status_check
+    CHECK (status IN ('reserved', 'confirmed', 'expired', 'refunded'));
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS stock_ledger_no_update ON stock_ledger;
+DROP FUNCTION IF EXISTS stock_ledger_immutable();
+DROP TABLE IF EXISTS stock_ledger;
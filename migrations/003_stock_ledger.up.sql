@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS stock_ledger (
+    id TEXT PRIMARY KEY,
+    stream_ms BIGINT NOT NULL,
+    stream_seq BIGINT NOT NULL,
+    event_id TEXT NOT NULL,
+    category TEXT NOT NULL,
+    movement TEXT NOT NULL,
+    reservation_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    delta INT NOT NULL,
+    balance INT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS stock_ledger_category_idx ON stock_ledger(event_id, category, stream_ms, stream_seq);
+
+CREATE OR REPLACE FUNCTION stock_ledger_immutable() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'stock_ledger is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER stock_ledger_no_update
+    BEFORE UPDATE OR DELETE ON stock_ledger
+    FOR EACH ROW EXECUTE FUNCTION stock_ledger_immutable();
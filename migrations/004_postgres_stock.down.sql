@@ -0,0 +1,5 @@
+DROP SEQUENCE IF EXISTS stock_ledger_seq;
+DROP TABLE IF EXISTS stock_leases;
+DROP TABLE IF EXISTS stock_outbox;
+DROP TABLE IF EXISTS stock_reservations;
+DROP TABLE IF EXISTS stock_counters;
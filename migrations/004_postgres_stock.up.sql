@@ -0,0 +1,38 @@
+CREATE TABLE IF NOT EXISTS stock_counters (
+    event_id TEXT NOT NULL,
+    category TEXT NOT NULL,
+    remaining INT NOT NULL CHECK (remaining >= 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (event_id, category)
+);
+
+CREATE TABLE IF NOT EXISTS stock_reservations (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    event_id TEXT NOT NULL,
+    category TEXT NOT NULL,
+    qty INT NOT NULL CHECK (qty > 0),
+    status TEXT NOT NULL CHECK (status IN ('reserved', 'confirmed', 'expired')),
+    expired_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Only live holds are ever scanned for expiry, so the index stays small.
+CREATE INDEX IF NOT EXISTS stock_reservations_due_idx ON stock_reservations(expired_at) WHERE status = 'reserved';
+
+CREATE TABLE IF NOT EXISTS stock_outbox (
+    seq BIGSERIAL PRIMARY KEY,
+    id TEXT NOT NULL,
+    topic TEXT NOT NULL,
+    key TEXT NOT NULL,
+    payload BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS stock_leases (
+    name TEXT PRIMARY KEY,
+    owner TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE SEQUENCE IF NOT EXISTS stock_ledger_seq;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS reservation_requests (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    event_id TEXT NOT NULL,
+    category TEXT NOT NULL,
+    qty INT NOT NULL,
+    status TEXT NOT NULL,
+    reservation_id TEXT NOT NULL DEFAULT '',
+    reason TEXT NOT NULL DEFAULT '',
+    expired_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+);
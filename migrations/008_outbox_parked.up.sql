@@ -0,0 +1,11 @@
+-- Outbox events the relay gave up on after repeated publish failures. They
+-- are kept for inspection and replayed by hand.
+CREATE TABLE IF NOT EXISTS stock_outbox_parked (
+    seq BIGINT PRIMARY KEY,
+    id TEXT NOT NULL,
+    topic TEXT NOT NULL,
+    key TEXT NOT NULL,
+    payload BYTEA NOT NULL,
+    error TEXT NOT NULL,
+    parked_at TIMESTAMPTZ NOT NULL
+);